@@ -18,6 +18,20 @@ type AudioConfig struct {
 	HandleEventsPerSecond    int
 	MidiEventInputBufferSize int
 	Debug                    bool
+
+	// The number of times per second the Synth should publish
+	// the channel and master levels. Zero disables metering.
+	// Default: 20.
+	MetersPerSecond int
+
+	// Also publish a frequency spectrum of the master output.
+	// Default: false.
+	Spectrum bool
+
+	// The time in seconds it takes mixer and filter parameters
+	// (volume, panning, cutoff frequencies, etc.) to move to a
+	// new value. Zero disables smoothing. Default: 0.02.
+	SmoothingTime float64
 }
 
 func NewAudioConfig() *AudioConfig {
@@ -30,6 +44,9 @@ func NewAudioConfig() *AudioConfig {
 		HandleEventsPerSecond:    100,
 		MidiEventInputBufferSize: 128,
 		Debug:                    false,
+		MetersPerSecond:          20,
+		Spectrum:                 false,
+		SmoothingTime:            0.02,
 	}
 }
 
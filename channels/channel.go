@@ -1,8 +1,11 @@
 package channels
 
 import (
+	"math"
+
 	"github.com/bspaans/bleep/audio"
 	"github.com/bspaans/bleep/generators"
+	"github.com/bspaans/bleep/tuning"
 )
 
 type Channel interface {
@@ -12,5 +15,29 @@ type Channel interface {
 	SetFX(fx FX, value float64)
 	SetGrainOption(opt GrainOption, value interface{})
 	SetInstrument(func() generators.Generator)
+	SetTuning(t *tuning.Tuning)
 	GetSamples(cfg *audio.AudioConfig, n int) []float64
 }
+
+// ExpressiveChannel is implemented by channels that support per note
+// expression (e.g. from MPE controllers).
+type ExpressiveChannel interface {
+	SetNotePitchbend(note int, pitchbendFactor float64)
+	SetNotePressure(note int, pressure float64)
+	SetNoteTimbre(note int, timbre float64)
+}
+
+// PerformanceChannel is implemented by channels that respond to the sustain
+// pedal, the mod wheel and aftertouch. Values are between 0.0 and 1.0.
+type PerformanceChannel interface {
+	SetSustain(on bool)
+	SetModWheel(value float64)
+	SetChannelPressure(value float64)
+	SetAftertouch(note int, value float64)
+}
+
+// TimbreToCutoff maps a timbre value between 0.0 and 1.0 exponentially
+// onto a low pass filter cutoff between 100Hz and 12.8kHz.
+func TimbreToCutoff(timbre float64) float64 {
+	return 100.0 * math.Pow(2, timbre*7)
+}
@@ -25,6 +25,19 @@ type ChannelDef struct {
 	HPF_Cutoff     int                           `json:"hpf_cutoff,omitempty" yaml:"hpf_cutoff,omitempty"`
 	Grain          *instruments.GrainsOptionsDef `json:"grain,omitempty" yaml:"grain,omitempty"`
 	Generator      *instruments.GeneratorDef     `json:"generator,omitempty" yaml:"generator,omitempty"`
+	Quantize       *QuantizeDef                  `json:"quantize,omitempty" yaml:"quantize,omitempty"`
+	ChordMemory    *ChordMemoryDef               `json:"chord_memory,omitempty" yaml:"chord_memory,omitempty"`
+	Arpeggiator    *ArpeggiatorDef               `json:"arpeggiator,omitempty" yaml:"arpeggiator,omitempty"`
+	Tuning         *TuningDef                    `json:"tuning,omitempty" yaml:"tuning,omitempty"`
+	PitchbendRange float64                       `json:"pitchbend_range,omitempty" yaml:"pitchbend_range,omitempty"`
+	Output         string                        `json:"output,omitempty" yaml:"output,omitempty"`
+	Mute           bool                          `json:"mute,omitempty" yaml:"mute,omitempty"`
+	Solo           bool                          `json:"solo,omitempty" yaml:"solo,omitempty"`
+	SoloSafe       bool                          `json:"solo_safe,omitempty" yaml:"solo_safe,omitempty"`
+	// The dynamics are applied in this order, before the fader.
+	Gate       *instruments.DynamicsOptionsDef `json:"gate,omitempty" yaml:"gate,omitempty"`
+	Expander   *instruments.DynamicsOptionsDef `json:"expander,omitempty" yaml:"expander,omitempty"`
+	Compressor *instruments.DynamicsOptionsDef `json:"compressor,omitempty" yaml:"compressor,omitempty"`
 }
 
 func ParseDuration(d interface{}, bpm float64) (float64, error) {
@@ -1,10 +1,6 @@
 package channels
 
-import (
-	"fmt"
-
-	"github.com/bspaans/bleep/filters"
-)
+import "github.com/bspaans/bleep/filters"
 
 type FX int
 
@@ -66,7 +62,6 @@ func (f *ChannelFX) Set(fx FX, value float64) {
 	if fx == Reverb {
 		f.Reverb = value
 		if f.reverb == nil {
-			fmt.Println("Setting factor", value)
 			time := f.ReverbTime
 			if time == 0.0 {
 				time = 0.2
@@ -78,7 +73,6 @@ func (f *ChannelFX) Set(fx FX, value float64) {
 		}
 		f.CachedFilter = nil
 	} else if fx == ReverbTime {
-		fmt.Println("Setting time", value)
 		f.ReverbTime = value
 		if f.reverb == nil {
 			f.reverb = filters.NewDelayFilter(f.ReverbTime, f.Reverb, f.ReverbFeedback)
@@ -91,7 +85,6 @@ func (f *ChannelFX) Set(fx FX, value float64) {
 		}
 		f.CachedFilter = nil
 	} else if fx == ReverbFeedback {
-		fmt.Println("Setting feedback", value)
 		f.ReverbFeedback = value
 		if f.reverb == nil {
 			f.reverb = filters.NewDelayFilter(f.ReverbTime, f.Reverb, value)
@@ -110,7 +103,6 @@ func (f *ChannelFX) Set(fx FX, value float64) {
 		f.CachedFilter = nil
 	} else if fx == LPF_Cutoff {
 		f.LPF_Cutoff = value
-		fmt.Println("set", value)
 		if f.lpf == nil {
 			f.lpf = filters.NewLowPassConvolutionFilter(f.LPF_Cutoff, 25)
 		} else {
@@ -3,11 +3,12 @@ package channels
 import (
 	"github.com/bspaans/bleep/audio"
 	"github.com/bspaans/bleep/generators"
-	"github.com/bspaans/bleep/midi/notes"
+	"github.com/bspaans/bleep/tuning"
 )
 
 type MonophonicChannel struct {
 	Instrument generators.Generator
+	Tuning     *tuning.Tuning
 }
 
 func NewMonophonicChannel(g generators.Generator) *MonophonicChannel {
@@ -21,8 +22,9 @@ func (c *MonophonicChannel) SetInstrument(g func() generators.Generator) {
 }
 
 func (c *MonophonicChannel) NoteOn(note int, velocity float64) {
-	if c.Instrument != nil {
-		c.Instrument.SetPitch(notes.NoteToPitch[note])
+	pitch := c.Tuning.Pitch(note)
+	if c.Instrument != nil && pitch != 0.0 {
+		c.Instrument.SetPitch(pitch)
 		c.Instrument.SetGain(velocity)
 	}
 }
@@ -43,5 +45,9 @@ func (c *MonophonicChannel) SetPitchbend(pitchbendFactor float64) {
 	}
 }
 
+func (c *MonophonicChannel) SetTuning(t *tuning.Tuning) {
+	c.Tuning = t
+}
+
 func (c *MonophonicChannel) SetFX(fx FX, value float64)                        {}
 func (c *MonophonicChannel) SetGrainOption(opt GrainOption, value interface{}) {}
@@ -6,13 +6,14 @@ import (
 	"github.com/bspaans/bleep/audio"
 	"github.com/bspaans/bleep/generators"
 	"github.com/bspaans/bleep/instruments"
-	"github.com/bspaans/bleep/midi/notes"
+	"github.com/bspaans/bleep/tuning"
 )
 
 type PercussionChannel struct {
 	On          *sync.Map
 	Instruments []generators.Generator
 	FX          ChannelFX
+	Tuning      *tuning.Tuning
 }
 
 func NewPercussionChannel() *PercussionChannel {
@@ -44,8 +45,9 @@ func (c *PercussionChannel) SetInstrument(g func() generators.Generator) {
 
 func (c *PercussionChannel) NoteOn(note int, velocity float64) {
 	instr := c.getInstrument(note)
-	if instr != nil {
-		instr.SetPitch(notes.NoteToPitch[note])
+	pitch := c.Tuning.Pitch(note)
+	if instr != nil && pitch != 0.0 {
+		instr.SetPitch(pitch)
 		instr.SetGain(velocity)
 		c.On.Store(note, true)
 	}
@@ -62,6 +64,10 @@ func (c *PercussionChannel) NoteOff(note int) {
 func (c *PercussionChannel) SetPitchbend(f float64) {
 }
 
+func (c *PercussionChannel) SetTuning(t *tuning.Tuning) {
+	c.Tuning = t
+}
+
 func (c *PercussionChannel) GetSamples(cfg *audio.AudioConfig, n int) []float64 {
 	result := generators.GetEmptySampleArray(cfg, n)
 	c.On.Range(func(on, value interface{}) bool {
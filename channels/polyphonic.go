@@ -5,8 +5,9 @@ import (
 	"sync"
 
 	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/filters"
 	"github.com/bspaans/bleep/generators"
-	"github.com/bspaans/bleep/midi/notes"
+	"github.com/bspaans/bleep/tuning"
 )
 
 type PolyphonicChannel struct {
@@ -14,14 +15,53 @@ type PolyphonicChannel struct {
 	On          *sync.Map
 	FX          ChannelFX
 	Grain       *ChannelGrain
+	Tuning      *tuning.Tuning
+	Pitchbend   float64
+	Expression  []*NoteExpression
+
+	// While the sustain pedal is down, released notes keep playing until
+	// the pedal is released.
+	Sustain   bool
+	Sustained []bool
+	ModWheel  float64
+	Pressure  float64
+}
+
+// NoteExpression holds the per note pitch bend, pressure and timbre of a
+// voice, as sent by MPE controllers.
+type NoteExpression struct {
+	Velocity   float64
+	Pitchbend  float64
+	Pressure   float64
+	Aftertouch float64
+	Timbre     *filters.LowPassFilter
+}
+
+func NewNoteExpression(velocity float64) *NoteExpression {
+	return &NoteExpression{
+		Velocity:  velocity,
+		Pitchbend: 1.0,
+	}
+}
+
+// Pressure raises the gain from the note's velocity up to full gain.
+func (e *NoteExpression) Gain() float64 {
+	return e.Velocity + (1.0-e.Velocity)*e.Pressure
 }
 
 func NewPolyphonicChannel() *PolyphonicChannel {
 	instr := make([]generators.Generator, 128)
+	expr := make([]*NoteExpression, 128)
+	for i := range expr {
+		expr[i] = NewNoteExpression(0.0)
+	}
 	return &PolyphonicChannel{
 		Instruments: instr,
 		On:          &sync.Map{},
 		Grain:       NewChannelGrain(),
+		Pitchbend:   1.0,
+		Expression:  expr,
+		Sustained:   make([]bool, 128),
 	}
 }
 
@@ -33,12 +73,21 @@ func (c *PolyphonicChannel) SetInstrument(g func() generators.Generator) {
 
 func (c *PolyphonicChannel) NoteOn(note int, velocity float64) {
 	if note >= 0 && note < 128 && c.Instruments[note] != nil {
+		pitch := c.Tuning.Pitch(note)
+		if pitch == 0.0 {
+			return
+		}
 		if _, alreadyOn := c.On.Load(note); alreadyOn {
 			// turn note off briefly to reset phase
 			c.Instruments[note].SetPitch(0.0)
 		}
-		c.Instruments[note].SetPitch(notes.NoteToPitch[note])
+		c.Expression[note] = NewNoteExpression(velocity)
+		c.Instruments[note].SetPitchbend(c.Pitchbend)
+		c.Instruments[note].SetPitch(pitch)
 		c.Instruments[note].SetGain(velocity)
+		generators.SetModulation(c.Instruments[note], generators.ModWheel, c.ModWheel)
+		generators.SetModulation(c.Instruments[note], generators.Aftertouch, c.Pressure)
+		c.Sustained[note] = false
 		c.On.Store(note, true)
 	}
 	if note == 128 && c.Grain != nil {
@@ -49,6 +98,12 @@ func (c *PolyphonicChannel) NoteOn(note int, velocity float64) {
 }
 
 func (c *PolyphonicChannel) NoteOff(note int) {
+	if c.Sustain && note >= 0 && note < 128 {
+		if _, on := c.On.Load(note); on {
+			c.Sustained[note] = true
+		}
+		return
+	}
 	if note >= 0 && note < 128 && c.Instruments[note] != nil {
 		c.Instruments[note].SetPitch(0.0)
 		c.On.Delete(note)
@@ -64,7 +119,11 @@ func (c *PolyphonicChannel) GetSamples(cfg *audio.AudioConfig, n int) []float64
 	result := generators.GetEmptySampleArray(cfg, n)
 	c.On.Range(func(on, value interface{}) bool {
 		if on.(int) != 128 {
-			for i, s := range c.Instruments[on.(int)].GetSamples(cfg, n) {
+			samples := c.Instruments[on.(int)].GetSamples(cfg, n)
+			if timbre := c.Expression[on.(int)].Timbre; timbre != nil {
+				samples = timbre.Filter(cfg, samples)
+			}
+			for i, s := range samples {
 				result[i] += s
 			}
 		} else if c.Grain != nil {
@@ -88,9 +147,108 @@ func (c *PolyphonicChannel) GetSamples(cfg *audio.AudioConfig, n int) []float64
 }
 
 func (c *PolyphonicChannel) SetPitchbend(pitchbendFactor float64) {
+	c.Pitchbend = pitchbendFactor
+	for note, i := range c.Instruments {
+		if i != nil {
+			i.SetPitchbend(pitchbendFactor * c.Expression[note].Pitchbend)
+		}
+	}
+}
+
+// Set the pitch bend for a single note. It is applied on top of the channel's
+// pitch bend.
+func (c *PolyphonicChannel) SetNotePitchbend(note int, pitchbendFactor float64) {
+	if note >= 0 && note < 128 && c.Instruments[note] != nil {
+		c.Expression[note].Pitchbend = pitchbendFactor
+		c.Instruments[note].SetPitchbend(c.Pitchbend * pitchbendFactor)
+	}
+}
+
+// Set the pressure (0.0-1.0) for a single note.
+func (c *PolyphonicChannel) SetNotePressure(note int, pressure float64) {
+	if note >= 0 && note < 128 && c.Instruments[note] != nil {
+		c.Expression[note].Pressure = pressure
+		c.Expression[note].Aftertouch = pressure
+		c.Instruments[note].SetGain(c.Expression[note].Gain())
+		c.updateAftertouch(note)
+	}
+}
+
+// Set the sustain pedal. Releasing the pedal stops the notes that were
+// released while it was down.
+func (c *PolyphonicChannel) SetSustain(on bool) {
+	c.Sustain = on
+	if on {
+		return
+	}
+	for note, sustained := range c.Sustained {
+		if sustained {
+			c.Sustained[note] = false
+			c.NoteOff(note)
+		}
+	}
+}
+
+// Set the mod wheel (0.0-1.0) modulation source for all the voices.
+func (c *PolyphonicChannel) SetModWheel(value float64) {
+	c.ModWheel = value
 	for _, i := range c.Instruments {
-		i.SetPitchbend(pitchbendFactor)
+		if i != nil {
+			generators.SetModulation(i, generators.ModWheel, value)
+		}
+	}
+}
+
+// Set the channel pressure (0.0-1.0). The aftertouch modulation source of a
+// voice is the channel pressure or the note's own aftertouch, whichever is
+// higher.
+func (c *PolyphonicChannel) SetChannelPressure(value float64) {
+	c.Pressure = value
+	for note := range c.Instruments {
+		c.updateAftertouch(note)
+	}
+}
+
+// Set the polyphonic aftertouch (0.0-1.0) for a single note.
+func (c *PolyphonicChannel) SetAftertouch(note int, value float64) {
+	if note >= 0 && note < 128 {
+		c.Expression[note].Aftertouch = value
+		c.updateAftertouch(note)
+	}
+}
+
+func (c *PolyphonicChannel) updateAftertouch(note int) {
+	if c.Instruments[note] == nil {
+		return
+	}
+	value := c.Pressure
+	if c.Expression[note].Aftertouch > value {
+		value = c.Expression[note].Aftertouch
+	}
+	generators.SetModulation(c.Instruments[note], generators.Aftertouch, value)
+}
+
+// Set the timbre (0.0-1.0) for a single note. The timbre opens up a low pass
+// filter on the voice; at 1.0 the voice is unfiltered.
+func (c *PolyphonicChannel) SetNoteTimbre(note int, timbre float64) {
+	if note < 0 || note >= 128 {
+		return
+	}
+	expr := c.Expression[note]
+	if timbre >= 1.0 {
+		expr.Timbre = nil
+		return
 	}
+	cutoff := TimbreToCutoff(timbre)
+	if expr.Timbre == nil {
+		expr.Timbre = filters.NewLowPassFilter(cutoff)
+	} else {
+		expr.Timbre.Cutoff = cutoff
+	}
+}
+
+func (c *PolyphonicChannel) SetTuning(t *tuning.Tuning) {
+	c.Tuning = t
 }
 
 func (c *PolyphonicChannel) SetFX(fx FX, value float64) {
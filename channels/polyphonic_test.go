@@ -0,0 +1,68 @@
+package channels
+
+import (
+	"testing"
+
+	"github.com/bspaans/bleep/generators"
+	"github.com/bspaans/bleep/generators/derived"
+)
+
+func newTestChannel() *PolyphonicChannel {
+	c := NewPolyphonicChannel()
+	c.SetInstrument(func() generators.Generator {
+		return generators.NewBaseGenerator()
+	})
+	return c
+}
+
+func isOn(c *PolyphonicChannel, note int) bool {
+	_, on := c.On.Load(note)
+	return on
+}
+
+func Test_PolyphonicChannel_sustain(t *testing.T) {
+	c := newTestChannel()
+	c.NoteOn(60, 1.0)
+	c.SetSustain(true)
+	c.NoteOff(60)
+	c.NoteOn(64, 1.0)
+	c.NoteOff(64)
+	c.NoteOff(67)
+	if !isOn(c, 60) || !isOn(c, 64) {
+		t.Errorf("Expecting released notes to be sustained")
+	}
+	c.NoteOn(60, 1.0)
+	c.SetSustain(false)
+	if !isOn(c, 60) {
+		t.Errorf("Expecting retriggered note to keep playing")
+	}
+	if isOn(c, 64) || isOn(c, 67) {
+		t.Errorf("Expecting sustained notes to be released")
+	}
+}
+
+func Test_PolyphonicChannel_aftertouch(t *testing.T) {
+	c := NewPolyphonicChannel()
+	voices := []*generators.BaseGenerator{}
+	c.SetInstrument(func() generators.Generator {
+		g := generators.NewBaseGenerator()
+		voices = append(voices, g)
+		return derived.NewModulatedGenerator(g, generators.Aftertouch, derived.ModulateGain, 1.0, 0.0)
+	})
+	c.NoteOn(60, 0.5)
+	c.NoteOn(64, 0.5)
+	c.NoteOn(67, 0.5)
+	c.SetChannelPressure(0.25)
+	c.SetAftertouch(60, 0.5)
+	c.SetAftertouch(64, 0.1)
+	cases := map[int]float64{
+		60: 0.75,
+		64: 0.625,
+		67: 0.625,
+	}
+	for note, expected := range cases {
+		if voices[note].Gain != expected {
+			t.Errorf("Expecting gain %f for note %d, got %f", expected, note, voices[note].Gain)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package channels
+
+// ArpeggiatorDef configures a live arpeggiator for the notes played on a
+// channel. Rate is a duration (e.g. "Sixteenth") and Gate the fraction of the
+// rate that each note is held for.
+type ArpeggiatorDef struct {
+	Mode    string      `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Octaves int         `json:"octaves,omitempty" yaml:"octaves,omitempty"`
+	Rate    interface{} `json:"rate,omitempty" yaml:"rate,omitempty"`
+	Gate    float64     `json:"gate,omitempty" yaml:"gate,omitempty"`
+	Latch   bool        `json:"latch,omitempty" yaml:"latch,omitempty"`
+}
+
+// ChordMemoryDef stores a chord that is triggered by a single key. Either a
+// chord quality (e.g. "m7", see theory.ParseChordQuality) or a list of
+// intervals in semitones can be given.
+type ChordMemoryDef struct {
+	Chord     string `json:"chord,omitempty" yaml:"chord,omitempty"`
+	Intervals []int  `json:"intervals,omitempty" yaml:"intervals,omitempty"`
+}
+
+// QuantizeDef snaps the notes played on a channel to a scale from
+// theory.Scales on the given root note.
+type QuantizeDef struct {
+	Scale string `json:"scale" yaml:"scale"`
+	Root  int    `json:"root,omitempty" yaml:"root,omitempty"`
+}
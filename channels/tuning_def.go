@@ -0,0 +1,26 @@
+package channels
+
+import (
+	"fmt"
+
+	"github.com/bspaans/bleep/instruments"
+	"github.com/bspaans/bleep/tuning"
+)
+
+// TuningDef loads a tuning from a Scala scale (.scl) file and an optional
+// keyboard mapping (.kbm) file. Paths are relative to the sequencer file.
+type TuningDef struct {
+	Scale           string `json:"scl" yaml:"scl"`
+	KeyboardMapping string `json:"kbm,omitempty" yaml:"kbm,omitempty"`
+}
+
+func (t *TuningDef) GetTuning(ctx *instruments.Context) (*tuning.Tuning, error) {
+	if t.Scale == "" {
+		return nil, fmt.Errorf("missing scl file")
+	}
+	kbm := ""
+	if t.KeyboardMapping != "" {
+		kbm = ctx.GetPathFor(t.KeyboardMapping)
+	}
+	return tuning.LoadTuning(ctx.GetPathFor(t.Scale), kbm)
+}
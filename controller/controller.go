@@ -4,9 +4,14 @@ import (
 	"fmt"
 
 	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/instruments"
+	"github.com/bspaans/bleep/midi"
+	"github.com/bspaans/bleep/midi/mapping"
 	"github.com/bspaans/bleep/sequencer"
+	"github.com/bspaans/bleep/sequencer/definitions"
 	"github.com/bspaans/bleep/synth"
 	"github.com/bspaans/bleep/ui"
+	"gitlab.com/gomidi/midi/mid"
 )
 
 // The Controller is a high level object that can be used to setup
@@ -17,7 +22,17 @@ type Controller struct {
 	Sequencer          *sequencer.Sequencer
 	InstrumentBankFile string
 	PercussionBankFile string
+	PercussionBank     *instruments.BankDef
 	UI                 ui.UI
+	MIDIInput          *midi.MIDIInput
+	MIDIOutput         *midi.Output
+	ClockInput         *midi.MIDIInput
+	ClockOutput        *midi.ClockOutput
+	ClockSlave         bool
+	MIDIMapper         *mapping.Mapper
+	MIDIDriver         mid.Driver
+	// The master bus used when the sequencer doesn't configure one.
+	Master *definitions.MasterDef
 }
 
 func NewController(cfg *audio.AudioConfig) *Controller {
@@ -38,6 +53,219 @@ func (c *Controller) EnableWavSink(file string) error {
 	return c.Synth.EnableWavSink(file)
 }
 
+// Listen for notes and controller changes on a MIDI input port. If a
+// sequencer is loaded the events are passed through its input processors
+// (arpeggiator, chord memory, quantizer), otherwise they go straight to the
+// synthesizer.
+func (c *Controller) EnableMIDIInput(in mid.In) error {
+	input := midi.NewMIDIInput(in)
+	input.OnControlChange = c.handleControlChange
+	if err := input.Start(c.HandleLiveEvent); err != nil {
+		return err
+	}
+	c.MIDIInput = input
+	return nil
+}
+
+// HandleLiveEvent plays an event from a live input, passing it through the
+// sequencer's input processors when a sequencer is loaded.
+func (c *Controller) HandleLiveEvent(ev *synth.Event) {
+	if c.Sequencer != nil {
+		c.Sequencer.HandleLiveEvent(ev)
+	} else {
+		c.Synth.Inputs <- ev
+	}
+}
+
+// Load the MIDI controller mappings from a YAML file. Mappings that are
+// learned later on are saved to the same file.
+func (c *Controller) LoadMIDIMappings(file string) error {
+	mapper, err := mapping.NewMapperFromFile(file)
+	if err != nil {
+		return err
+	}
+	mapper.OnLearn = c.onMIDILearn
+	c.MIDIMapper = mapper
+	return nil
+}
+
+// Map the next control change that comes in on the MIDI input to the
+// parameter described by def.
+func (c *Controller) StartMIDILearn(def *mapping.MappingDef) error {
+	if c.MIDIMapper == nil {
+		c.MIDIMapper = mapping.NewMapper()
+		c.MIDIMapper.OnLearn = c.onMIDILearn
+	}
+	fmt.Println("Learning MIDI mapping for", def.Target)
+	return c.MIDIMapper.Learn(def)
+}
+
+func (c *Controller) CancelMIDILearn() {
+	if c.MIDIMapper != nil {
+		c.MIDIMapper.CancelLearn()
+	}
+}
+
+func (c *Controller) onMIDILearn(m *mapping.Mapping) {
+	fmt.Println("Learned MIDI mapping", m.String())
+	if c.UI != nil {
+		ev := ui.NewUIEvent(ui.MIDILearnEvent)
+		ev.Value = m.String()
+		c.UI.HandleEvent(ev)
+	}
+}
+
+func (c *Controller) handleControlChange(ch, controller, value int) bool {
+	if c.MIDIMapper == nil {
+		return false
+	}
+	return c.MIDIMapper.HandleControlChange(c, ch, controller, value)
+}
+
+// SendSynthEvent, SetBPM, SetRegister and SetFloatRegister implement
+// mapping.Receiver.
+func (c *Controller) SendSynthEvent(ev *synth.Event) {
+	c.Synth.Inputs <- ev
+}
+func (c *Controller) SetBPM(bpm float64) {
+	if c.Sequencer != nil {
+		c.Sequencer.SetBPM(bpm)
+	}
+}
+func (c *Controller) SetRegister(register, value int) {
+	if c.Sequencer != nil {
+		c.Sequencer.SetRegister(register, value)
+	}
+}
+func (c *Controller) SetFloatRegister(register int, value float64) {
+	if c.Sequencer != nil {
+		c.Sequencer.SetFloatRegister(register, value)
+	}
+}
+
+// MIDIPorts names the ports for EnableMIDIPorts. Ports can be given by
+// number or (part of their) name; empty ports aren't used.
+type MIDIPorts struct {
+	In       string
+	Out      string
+	ClockIn  string
+	ClockOut string
+}
+
+// EnableMIDIPorts finds the ports on the driver and enables MIDI input,
+// output, clock output and clock slaving on them. The same port can be used
+// for the notes and the clock.
+func (c *Controller) EnableMIDIPorts(driver mid.Driver, ports MIDIPorts) error {
+	c.MIDIDriver = driver
+	ins := map[string]mid.In{}
+	findIn := func(port string) (mid.In, error) {
+		if in, ok := ins[port]; ok {
+			return in, nil
+		}
+		in, err := midi.FindIn(driver, port)
+		ins[port] = in
+		return in, err
+	}
+	outs := map[string]mid.Out{}
+	findOut := func(port string) (mid.Out, error) {
+		if out, ok := outs[port]; ok {
+			return out, nil
+		}
+		out, err := midi.FindOut(driver, port)
+		outs[port] = out
+		return out, err
+	}
+	if ports.In != "" {
+		in, err := findIn(ports.In)
+		if err != nil {
+			return err
+		}
+		if err := c.EnableMIDIInput(in); err != nil {
+			return err
+		}
+	}
+	if ports.ClockIn != "" {
+		in, err := findIn(ports.ClockIn)
+		if err != nil {
+			return err
+		}
+		if err := c.EnableMIDIClockSlave(in); err != nil {
+			return err
+		}
+	}
+	if ports.Out != "" {
+		out, err := findOut(ports.Out)
+		if err != nil {
+			return err
+		}
+		if err := c.EnableMIDIOutput(out); err != nil {
+			return err
+		}
+	}
+	if ports.ClockOut != "" {
+		out, err := findOut(ports.ClockOut)
+		if err != nil {
+			return err
+		}
+		if err := c.EnableMIDIClockOutput(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Send the events on channels with 'output: midi' or 'output: both' to a
+// MIDI output port.
+func (c *Controller) EnableMIDIOutput(out mid.Out) error {
+	output := midi.NewOutput(out)
+	if err := output.Open(); err != nil {
+		return err
+	}
+	c.MIDIOutput = output
+	c.Synth.EventOutput = output
+	return nil
+}
+
+// Send MIDI clock and transport messages to an output port, so that drum
+// machines and DAWs can follow the sequencer.
+func (c *Controller) EnableMIDIClockOutput(out mid.Out) error {
+	clock := midi.NewClockOutput(out)
+	if err := clock.Open(); err != nil {
+		return err
+	}
+	c.ClockOutput = clock
+	if c.Sequencer != nil {
+		c.Sequencer.SetClockOutput(clock)
+	}
+	return nil
+}
+
+// Make the sequencer follow the MIDI clock and transport messages on an
+// input port. Notes on the port are handled as live input.
+func (c *Controller) EnableMIDIClockSlave(in mid.In) error {
+	handleClock := func(msg midi.ClockMessage, songPosition int) {
+		if c.Sequencer != nil {
+			c.Sequencer.HandleClock(msg, songPosition)
+		}
+	}
+	if c.MIDIInput != nil && c.MIDIInput.In == in {
+		c.MIDIInput.OnClock = handleClock
+	} else {
+		input := midi.NewMIDIInput(in)
+		input.OnClock = handleClock
+		input.OnControlChange = c.handleControlChange
+		if err := input.Start(c.HandleLiveEvent); err != nil {
+			return err
+		}
+		c.ClockInput = input
+	}
+	c.ClockSlave = true
+	if c.Sequencer != nil {
+		c.Sequencer.SetClockSlave(true)
+	}
+	return nil
+}
+
 // Load an instrument bank definition from a file.
 func (c *Controller) LoadInstrumentBank(file string) error {
 	c.InstrumentBankFile = file
@@ -63,11 +291,34 @@ func (c *Controller) LoadPercussionBank(file string) error {
 func (c *Controller) ReloadPercussionBank() error {
 	if c.PercussionBankFile != "" {
 		fmt.Println("Loading", c.PercussionBankFile)
-		return c.Synth.LoadPercussionBank(c.PercussionBankFile)
+		bankDef, err := instruments.NewBankFromYamlFile(c.PercussionBankFile)
+		if err != nil {
+			return err
+		}
+		return c.LoadPercussionBankDef(bankDef)
 	}
 	return nil
 }
 
+// Load instrument bank definitions that have already been parsed, e.g. from
+// the REST API.
+func (c *Controller) LoadInstrumentBankDef(def *instruments.BankDef) error {
+	if err := c.Synth.LoadInstrumentBankDef(def); err != nil {
+		return err
+	}
+	c.InstrumentBankFile = def.FromFile
+	return nil
+}
+
+func (c *Controller) LoadPercussionBankDef(def *instruments.BankDef) error {
+	if err := c.Synth.LoadPercussionBankDef(def); err != nil {
+		return err
+	}
+	c.PercussionBankFile = def.FromFile
+	c.PercussionBank = def
+	return nil
+}
+
 func (c *Controller) LoadEmptySequencer() {
 	c.Sequencer = sequencer.NewSequencer(120, 64)
 	c.StartSequencer()
@@ -112,6 +363,24 @@ func (c *Controller) DecreaseSequencerBPM() {
 	}
 }
 
+// Toggle between playing and pausing the sequencer.
+func (c *Controller) ToggleSequencerPlaying() {
+	if c.Sequencer == nil {
+		return
+	}
+	if c.Sequencer.Status.Playing {
+		c.Sequencer.PausePlaying()
+	} else {
+		c.Sequencer.StartPlaying()
+	}
+}
+
+func (c *Controller) RewindSequencer() {
+	if c.Sequencer != nil {
+		c.Sequencer.Rewind()
+	}
+}
+
 // Start Synthesizer. Note that this synthesizer isn't run in a go-routine by
 // default.
 func (c *Controller) StartSynth() {
@@ -130,11 +399,37 @@ func (c *Controller) StartSynth() {
 
 // Start Sequencer. The sequencer is started in its own go-routine.
 func (c *Controller) StartSequencer() {
+	c.Sequencer.DefaultMaster = c.Master
 	c.Sequencer.Start(c.Synth.Inputs)
+	if c.ClockOutput != nil {
+		c.Sequencer.SetClockOutput(c.ClockOutput)
+	}
+	if c.ClockSlave {
+		c.Sequencer.SetClockSlave(true)
+	}
 }
 
 // Close the Synthesizer and its sinks.
 func (c *Controller) Quit() {
+	if c.MIDIInput != nil {
+		c.MIDIInput.Close()
+	}
+	if c.ClockInput != nil {
+		c.ClockInput.Close()
+	}
+	if c.MIDIOutput != nil {
+		for ch := 0; ch < 16; ch++ {
+			c.MIDIOutput.SendEvent(synth.NewEvent(synth.SilenceChannel, ch, nil))
+		}
+		c.MIDIOutput.Close()
+	}
+	if c.ClockOutput != nil {
+		c.ClockOutput.Stop()
+		c.ClockOutput.Close()
+	}
+	if c.MIDIDriver != nil {
+		c.MIDIDriver.Close()
+	}
 	c.Synth.Close()
 	c.Sequencer.Quit()
 }
@@ -142,3 +437,27 @@ func (c *Controller) Quit() {
 func (c *Controller) ToggleSoloChannel(ch int) {
 	c.Synth.Inputs <- synth.NewEvent(synth.ToggleSoloChannel, ch, nil)
 }
+
+func (c *Controller) ToggleMuteChannel(ch int) {
+	c.Synth.Inputs <- synth.NewEvent(synth.ToggleMuteChannel, ch, nil)
+}
+
+func (c *Controller) SetSoloSafe(ch int, safe bool) {
+	value := 0
+	if safe {
+		value = 1
+	}
+	c.Synth.Inputs <- synth.NewEvent(synth.SetSoloSafe, ch, []int{value})
+}
+
+// SetMaster configures the master bus. A sequencer with a master section
+// overrides it while it's loaded. Call it before the sequencer is started.
+func (c *Controller) SetMaster(def *definitions.MasterDef) error {
+	bus, err := def.GetMasterBus()
+	if err != nil {
+		return err
+	}
+	c.Master = def
+	c.Synth.Inputs <- synth.NewMasterBusEvent(bus)
+	return nil
+}
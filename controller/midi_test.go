@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/midi"
+	"github.com/bspaans/bleep/midi/mapping"
+	"github.com/bspaans/bleep/synth"
+)
+
+func Test_Controller_EnableMIDIPorts(t *testing.T) {
+	driver := midi.NewLoopbackDriver(2)
+	c := NewController(audio.NewAudioConfig())
+	err := c.EnableMIDIPorts(driver, MIDIPorts{
+		In:       "0",
+		ClockIn:  "loopback in 0",
+		Out:      "1",
+		ClockOut: "1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ClockInput != nil || c.MIDIInput.OnClock == nil {
+		t.Errorf("Expecting the clock to be read from the MIDI input port")
+	}
+
+	// Notes sent to loopback out 0 arrive on the input port
+	outs, _ := driver.Outs()
+	outs[0].Open()
+	outs[0].Send([]byte{0x93, 60, 100})
+	select {
+	case ev := <-c.Synth.Inputs:
+		if ev.Type != synth.NoteOn || ev.Channel != 3 || ev.Values[0] != 60 {
+			t.Errorf("Expecting a note on, got %v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expecting the note on the MIDI input to be played")
+	}
+
+	// Events on MIDI output channels are sent to loopback in 1
+	received := make(chan []byte, 10)
+	ins, _ := driver.Ins()
+	ins[1].Open()
+	ins[1].SetListener(func(data []byte, deltaMicroseconds int64) {
+		received <- data
+	})
+	c.Synth.EventOutput.SendEvent(synth.NewEvent(synth.NoteOn, 2, []int{64, 90}))
+	select {
+	case data := <-received:
+		if len(data) != 3 || data[0] != 0x92 || data[1] != 64 || data[2] != 90 {
+			t.Errorf("Expecting a note on message, got %v", data)
+		}
+	default:
+		t.Fatal("Expecting the note to be sent to the MIDI output")
+	}
+	if c.ClockOutput == nil || c.ClockOutput.Out != outs[1] {
+		t.Errorf("Expecting the clock output on port 1")
+	}
+}
+
+func Test_Controller_EnableMIDIPorts_unknown_port(t *testing.T) {
+	c := NewController(audio.NewAudioConfig())
+	if err := c.EnableMIDIPorts(midi.NewLoopbackDriver(1), MIDIPorts{In: "2"}); err == nil {
+		t.Errorf("Expecting an error for an unknown port")
+	}
+}
+
+func Test_Controller_MIDI_learn(t *testing.T) {
+	driver := midi.NewLoopbackDriver(1)
+	c := NewController(audio.NewAudioConfig())
+	if err := c.EnableMIDIPorts(driver, MIDIPorts{In: "0"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.StartMIDILearn(&mapping.MappingDef{Target: "lpf_cutoff", Channel: 1}); err != nil {
+		t.Fatal(err)
+	}
+	outs, _ := driver.Outs()
+	outs[0].Open()
+	outs[0].Send([]byte{0xB0, 21, 127})
+	if c.MIDIMapper.IsLearning() || len(c.MIDIMapper.Mappings) != 1 {
+		t.Fatalf("Expecting the control change on the MIDI input to be learned")
+	}
+	select {
+	case ev := <-c.Synth.Inputs:
+		if ev.Type != synth.SetLPFCutoff || ev.Channel != 1 {
+			t.Errorf("Expecting the learned mapping to set the cutoff, got %v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expecting the learned mapping to be applied")
+	}
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bspaans/bleep/sequencer"
+	"github.com/bspaans/bleep/sequencer/definitions"
+	"github.com/bspaans/bleep/sinks"
+	"github.com/bspaans/bleep/synth"
+)
+
+// Render a sequencer definition to a .wav file without real-time playback.
+// The rendering uses its own synthesizer and sequencer, so it can run while
+// the controller is playing. The loaded instrument banks are used.
+func (c *Controller) Render(def *definitions.SequencerDef, beats float64, file string) error {
+	if err := def.Validate(); err != nil {
+		return err
+	}
+	if beats <= 0 {
+		return fmt.Errorf("expecting a positive number of beats")
+	}
+	s := synth.NewSynth(c.Config)
+	// Nobody is listening to the meters of an offline render
+	s.Mixer.Meter = nil
+	if c.PercussionBank != nil {
+		if err := s.LoadPercussionBankDef(c.PercussionBank); err != nil {
+			return err
+		}
+	}
+	sink, err := sinks.NewWavSink(c.Config, file)
+	if err != nil {
+		return err
+	}
+	seq := sequencer.NewSequencerFromDefinition(def)
+	seq.DefaultMaster = c.Master
+	seq.Status.Playing = true
+
+	ticks := int(math.Ceil(beats * float64(seq.Granularity)))
+	seconds, samples := 0.0, 0
+	for i := 0; i < ticks; i++ {
+		seq.Step(s.DispatchEvent)
+		// The tempo can change during the sequence, so the length of each
+		// tick is calculated separately.
+		seconds += 60.0 / seq.BPM / float64(seq.Granularity)
+		n := int(seconds*float64(c.Config.SampleRate)) - samples
+		samples += n
+		if err := sink.Write(c.Config, s.Mixer.GetSamples(c.Config, n)); err != nil {
+			return err
+		}
+	}
+	return sink.Close(c.Config)
+}
+
+// LoadSequencerDef replaces the definition of the running sequencer, or
+// starts a new sequencer if there isn't one yet.
+func (c *Controller) LoadSequencerDef(def *definitions.SequencerDef) {
+	if c.Sequencer == nil {
+		c.Sequencer = sequencer.NewSequencerFromDefinition(def)
+		c.StartSequencer()
+	} else {
+		c.Sequencer.SetSequencerDef(def)
+	}
+}
@@ -0,0 +1,37 @@
+package filters
+
+import (
+	"github.com/bspaans/bleep/audio"
+)
+
+// The CompressorFilter reduces the level of everything above the threshold
+// by the ratio; with a ratio of 4, 8dB over the threshold becomes 2dB.
+type CompressorFilter struct {
+	Dynamics
+}
+
+func NewCompressorFilter(threshold, ratio, knee, attack, release, makeup float64) *CompressorFilter {
+	return &CompressorFilter{
+		Dynamics: Dynamics{
+			Threshold: threshold,
+			Ratio:     ratio,
+			Knee:      knee,
+			Attack:    attack,
+			Release:   release,
+			Makeup:    makeup,
+		},
+	}
+}
+
+func (f *CompressorFilter) Filter(cfg *audio.AudioConfig, samples []float64) []float64 {
+	return f.filter(cfg, samples, f.GainReduction, false)
+}
+
+// GainReduction returns the reduction in dB for a signal at the given level
+// in dB, ignoring the attack and release.
+func (f *CompressorFilter) GainReduction(level float64) float64 {
+	if f.Ratio <= 1 {
+		return 0
+	}
+	return softKnee(level-f.Threshold, f.Knee) * (1 - 1/f.Ratio)
+}
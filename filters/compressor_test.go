@@ -0,0 +1,70 @@
+package filters
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+)
+
+func Test_Compressor_gain_reduction(t *testing.T) {
+	f := NewCompressorFilter(-20, 4, 0, 0.01, 0.1, 0)
+	if f.GainReduction(-30) != 0 {
+		t.Errorf("Expecting no reduction below the threshold")
+	}
+	if f.GainReduction(-4) != 12 {
+		t.Errorf("Expecting 16dB over the threshold to be reduced to 4dB, got %f", f.GainReduction(-4))
+	}
+}
+
+func Test_Compressor_settles_on_the_ratio(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.Stereo = false
+	f := NewCompressorFilter(-20, 4, 0, 0.001, 0.1, 6)
+	samples := make([]float64, 4410)
+	for i := range samples {
+		samples[i] = DecibelsToGain(-4)
+	}
+	samples = f.Filter(cfg, samples)
+	// -4dB is reduced by 12dB and made up by 6dB
+	if !almostEqual(GainToDecibels(samples[len(samples)-1]), -10, 0.01) {
+		t.Errorf("Expecting the output to settle at -10dB, got %f", GainToDecibels(samples[len(samples)-1]))
+	}
+	if GainToDecibels(samples[0]) < -4.01 {
+		t.Errorf("Expecting the attack to take some time, got %f", GainToDecibels(samples[0]))
+	}
+}
+
+func Test_Compressor_soft_knee(t *testing.T) {
+	f := NewCompressorFilter(-20, 4, 10, 0.01, 0.1, 0)
+	if f.GainReduction(-25) != 0 || f.GainReduction(-15) != 3.75 {
+		t.Errorf("Expecting the knee to start at -25dB and end at -15dB, got %f and %f", f.GainReduction(-25), f.GainReduction(-15))
+	}
+	previous := 0.0
+	for level := -25.0; level <= -15; level += 0.5 {
+		reduction := f.GainReduction(level)
+		if reduction < previous || reduction < math.Max(0, (level+20)*0.75) {
+			t.Errorf("Expecting a smooth knee, got %f at %fdB", reduction, level)
+		}
+		previous = reduction
+	}
+}
+
+func Test_Compressor_follows_the_sidechain(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.Stereo = false
+	f := NewCompressorFilter(-20, 10, 0, 0.0001, 0.1, 0)
+	sidechain := &Sidechain{}
+	f.SetSidechain(sidechain)
+	samples := make([]float64, 100)
+	sidechain.Samples = make([]float64, 100)
+	for i := range samples {
+		samples[i] = 0.05
+		sidechain.Samples[i] = 1.0
+	}
+	samples = f.Filter(cfg, samples)
+	// 20dB over the threshold is reduced by 18dB
+	if !almostEqual(GainToDecibels(samples[99]), GainToDecibels(0.05)-18, 0.1) {
+		t.Errorf("Expecting the quiet input to be compressed by the sidechain, got %fdB", GainToDecibels(samples[99]))
+	}
+}
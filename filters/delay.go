@@ -16,6 +16,10 @@ type DelayFilter struct {
 	RightFactor   float64
 	RightFeedback float64
 	RightDelayed  *ring.Ring
+
+	// Changes to the factors are smoothed.
+	leftFactor  SmoothedValue
+	rightFactor SmoothedValue
 }
 
 func NewDelayFilter(time, factor, feedback float64) *DelayFilter {
@@ -29,6 +33,8 @@ func NewDelayFilter(time, factor, feedback float64) *DelayFilter {
 		RightFactor:   factor,
 		RightDelayed:  nil,
 		RightFeedback: feedback,
+		leftFactor:    NewSmoothedValue(factor),
+		rightFactor:   NewSmoothedValue(factor),
 	}
 }
 
@@ -48,6 +54,8 @@ func (f *DelayFilter) Filter(cfg *audio.AudioConfig, samples []float64) []float6
 	if cfg.Stereo {
 		n = n / 2
 	}
+	f.leftFactor.Set(f.LeftFactor)
+	f.rightFactor.Set(f.RightFactor)
 	for i := 0; i < n; i++ {
 
 		ix := i
@@ -55,12 +63,12 @@ func (f *DelayFilter) Filter(cfg *audio.AudioConfig, samples []float64) []float6
 			ix *= 2
 		}
 
-		s := Delay(samples[ix], f.LeftFactor, f.LeftFeedback, f.LeftDelayed)
+		s := Delay(samples[ix], f.leftFactor.Next(cfg), f.LeftFeedback, f.LeftDelayed)
 		f.LeftDelayed = f.LeftDelayed.Next()
 		samples[ix] = s
 
 		if cfg.Stereo {
-			s := Delay(samples[ix+1], f.RightFactor, f.RightFeedback, f.RightDelayed)
+			s := Delay(samples[ix+1], f.rightFactor.Next(cfg), f.RightFeedback, f.RightDelayed)
 			f.RightDelayed = f.RightDelayed.Next()
 			samples[ix+1] = s
 		}
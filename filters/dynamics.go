@@ -0,0 +1,130 @@
+package filters
+
+import (
+	"math"
+
+	"github.com/bspaans/bleep/audio"
+)
+
+// The most the expanders and gates turn the gain down in dB, which is about as
+// good as silent.
+const MaxGainReduction = 100.0
+
+// A Sidechain passes the samples of another signal to a dynamics filter,
+// which then follows the level of that signal instead of its input; e.g. a
+// compressor on a pad that follows the kick drum. Samples needs to be set
+// before every call to Filter.
+type Sidechain struct {
+	Samples []float64
+}
+
+// SidechainFilter is implemented by the dynamics filters.
+type SidechainFilter interface {
+	Filter
+	SetSidechain(sidechain *Sidechain)
+}
+
+// Dynamics is what the compressor, expander and gate have in common: they
+// follow the level of their input (or of a sidechain) and turn the gain
+// down depending on how far that level is from the threshold. In stereo
+// both sides get the same gain, so that the stereo image doesn't shift.
+type Dynamics struct {
+	// The threshold, knee and makeup gain in dB. The gain changes gradually
+	// over the width of the knee around the threshold.
+	Threshold float64
+	Knee      float64
+	Makeup    float64
+	Ratio     float64
+	// The time in seconds it takes to turn the gain down (attack) and back
+	// up (release) for a compressor, and the other way round for expanders
+	// and gates, which open in the attack time.
+	Attack  float64
+	Release float64
+
+	Sidechain *Sidechain
+
+	// The current gain reduction in dB
+	reduction float64
+}
+
+func (d *Dynamics) SetSidechain(sidechain *Sidechain) {
+	d.Sidechain = sidechain
+}
+
+// filter applies the gain reduction; expanding says whether the reduction
+// increases when the level goes down instead of up.
+func (d *Dynamics) filter(cfg *audio.AudioConfig, samples []float64, gainReduction func(level float64) float64, expanding bool) []float64 {
+	channels := cfg.GetNumberOfChannels()
+	down := envelopeCoefficient(cfg, d.Attack)
+	up := envelopeCoefficient(cfg, d.Release)
+	if expanding {
+		down, up = up, down
+	}
+	key := samples
+	if d.Sidechain != nil && d.Sidechain.Samples != nil {
+		key = d.Sidechain.Samples
+	}
+	makeup := DecibelsToGain(d.Makeup)
+	for i := 0; i+channels <= len(samples); i += channels {
+		level := silence
+		if i+channels <= len(key) {
+			level = GainToDecibels(framePeak(key[i : i+channels]))
+		}
+		target := gainReduction(level)
+		if target > d.reduction {
+			d.reduction += (target - d.reduction) * down
+		} else {
+			d.reduction += (target - d.reduction) * up
+		}
+		gain := DecibelsToGain(-d.reduction) * makeup
+		for c := 0; c < channels; c++ {
+			samples[i+c] *= gain
+		}
+	}
+	return samples
+}
+
+// softKnee returns the distance over the threshold, or 0 below it. In the
+// knee it goes from 0 to the distance along a curve, so that the gain
+// reduction doesn't kick in suddenly.
+func softKnee(over, knee float64) float64 {
+	if 2*over <= -knee {
+		return 0
+	} else if 2*over < knee {
+		return (over + knee/2) * (over + knee/2) / (2 * knee)
+	}
+	return over
+}
+
+// envelopeCoefficient returns the factor by which an envelope moves towards
+// its target every sample, so that it gets about two thirds of the way
+// there in the given time.
+func envelopeCoefficient(cfg *audio.AudioConfig, time float64) float64 {
+	if time <= 0 {
+		return 1
+	}
+	return 1 - math.Exp(-1/(time*float64(cfg.SampleRate)))
+}
+
+// framePeak returns the highest absolute value in a frame.
+func framePeak(frame []float64) float64 {
+	peak := 0.0
+	for _, v := range frame {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	return peak
+}
+
+// The level of silence in dB.
+const silence = -200.0
+
+func GainToDecibels(gain float64) float64 {
+	if gain <= 0 {
+		return silence
+	}
+	return math.Max(silence, 20*math.Log10(gain))
+}
+
+func DecibelsToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
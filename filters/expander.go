@@ -0,0 +1,74 @@
+package filters
+
+import (
+	"math"
+
+	"github.com/bspaans/bleep/audio"
+)
+
+// The ExpanderFilter reduces the level of everything below the threshold
+// by the ratio; with a ratio of 2, 4dB below the threshold becomes 8dB.
+// This turns down noise and bleed between notes.
+type ExpanderFilter struct {
+	Dynamics
+}
+
+func NewExpanderFilter(threshold, ratio, knee, attack, release, makeup float64) *ExpanderFilter {
+	return &ExpanderFilter{
+		Dynamics: Dynamics{
+			Threshold: threshold,
+			Ratio:     ratio,
+			Knee:      knee,
+			Attack:    attack,
+			Release:   release,
+			Makeup:    makeup,
+		},
+	}
+}
+
+func (f *ExpanderFilter) Filter(cfg *audio.AudioConfig, samples []float64) []float64 {
+	return f.filter(cfg, samples, f.GainReduction, true)
+}
+
+// GainReduction returns the reduction in dB for a signal at the given level
+// in dB, ignoring the attack and release.
+func (f *ExpanderFilter) GainReduction(level float64) float64 {
+	if f.Ratio <= 1 {
+		return 0
+	}
+	return math.Min(MaxGainReduction, softKnee(f.Threshold-level, f.Knee)*(f.Ratio-1))
+}
+
+// The GateFilter silences everything below the threshold. The ratio isn't
+// used: the gate is either open or closed, except in the knee where it
+// opens gradually.
+type GateFilter struct {
+	Dynamics
+}
+
+func NewGateFilter(threshold, knee, attack, release, makeup float64) *GateFilter {
+	return &GateFilter{
+		Dynamics: Dynamics{
+			Threshold: threshold,
+			Knee:      knee,
+			Attack:    attack,
+			Release:   release,
+			Makeup:    makeup,
+		},
+	}
+}
+
+func (f *GateFilter) Filter(cfg *audio.AudioConfig, samples []float64) []float64 {
+	return f.filter(cfg, samples, f.GainReduction, true)
+}
+
+// GainReduction returns the reduction in dB for a signal at the given level
+// in dB, ignoring the attack and release.
+func (f *GateFilter) GainReduction(level float64) float64 {
+	if level >= f.Threshold+f.Knee/2 {
+		return 0
+	} else if level <= f.Threshold-f.Knee/2 {
+		return MaxGainReduction
+	}
+	return MaxGainReduction * (f.Threshold + f.Knee/2 - level) / f.Knee
+}
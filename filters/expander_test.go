@@ -0,0 +1,46 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+)
+
+func Test_Expander_gain_reduction(t *testing.T) {
+	f := NewExpanderFilter(-40, 2, 0, 0.001, 0.1, 0)
+	if f.GainReduction(-30) != 0 {
+		t.Errorf("Expecting no reduction above the threshold")
+	}
+	if f.GainReduction(-44) != 4 {
+		t.Errorf("Expecting 4dB below the threshold to become 8dB below, got %f", f.GainReduction(-44))
+	}
+	f.Ratio = 1000
+	if f.GainReduction(-60) != MaxGainReduction {
+		t.Errorf("Expecting the reduction to be limited, got %f", f.GainReduction(-60))
+	}
+}
+
+func Test_Gate_opens_and_closes(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.Stereo = true
+	f := NewGateFilter(-40, 0, 0.0001, 0.001, 0)
+	samples := make([]float64, 4410*2)
+	for i := range samples {
+		if i < 2205*2 {
+			samples[i] = 0.5
+		} else {
+			samples[i] = 0.001
+		}
+	}
+	samples = f.Filter(cfg, samples)
+	if !almostEqual(samples[2000*2], 0.5, 1e-6) || !almostEqual(samples[2000*2+1], 0.5, 1e-6) {
+		t.Errorf("Expecting the gate to be open, got %f", samples[2000*2])
+	}
+	if GainToDecibels(samples[len(samples)-1]) > -100 {
+		t.Errorf("Expecting the gate to be closed, got %fdB", GainToDecibels(samples[len(samples)-1]))
+	}
+	f.Knee = 10
+	if f.GainReduction(-40) != MaxGainReduction/2 {
+		t.Errorf("Expecting the gate to be half open in the middle of the knee, got %f", f.GainReduction(-40))
+	}
+}
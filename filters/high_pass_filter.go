@@ -8,8 +8,9 @@ import (
 
 // A FIR high pass filter.
 type HighPassConvolutionFilter struct {
-	// The cutoff frequency, in Hz.
+	// The cutoff frequency, in Hz. Changes are smoothed.
 	Cutoff float64
+	cutoff SmoothedValue
 
 	// The order of the filter. Should be odd.
 	// The order can't be changed once set without resetting the convolution
@@ -23,18 +24,16 @@ type HighPassConvolutionFilter struct {
 func NewHighPassConvolutionFilter(cutoff float64, order int) *HighPassConvolutionFilter {
 	return &HighPassConvolutionFilter{
 		Cutoff: cutoff,
+		cutoff: NewSmoothedValue(cutoff),
 		Order:  order,
 	}
 }
 
 func (f *HighPassConvolutionFilter) Filter(cfg *audio.AudioConfig, samples []float64) []float64 {
-	coefficients := HighPassConvolution(float64(cfg.SampleRate), f.Cutoff, f.Order)
-	if f.ConvolutionFilter == nil {
-		f.ConvolutionFilter = NewSimpleConvolutionFilter(coefficients)
-	} else {
-		f.ConvolutionFilter.Coefficients = coefficients
-	}
-	return f.ConvolutionFilter.Filter(cfg, samples)
+	f.cutoff.Set(f.Cutoff)
+	return filterWithSmoothedCoefficients(cfg, samples, &f.cutoff, &f.ConvolutionFilter, func(cutoff float64) []float64 {
+		return HighPassConvolution(float64(cfg.SampleRate), cutoff, f.Order)
+	})
 }
 
 // Order should be odd.
@@ -0,0 +1,124 @@
+package filters
+
+import (
+	"math"
+
+	"github.com/bspaans/bleep/audio"
+)
+
+// The LimiterFilter makes sure the output never goes over the ceiling. The
+// output is delayed by the look ahead time, so that the gain can be brought
+// down smoothly before a peak arrives, instead of clipping it.
+//
+// The gain needed for every frame is held for the look ahead time and then
+// averaged over the look ahead time, so that every frame in the average
+// already includes the peak by the time the peak comes out.
+type LimiterFilter struct {
+	// The ceiling in dB; e.g. -0.3
+	Ceiling float64
+	// The look ahead and release times in seconds.
+	LookAhead float64
+	Release   float64
+
+	// The delayed samples
+	delayed []float64
+	// The look ahead in frames
+	length int
+	// A running minimum of the gains over the look ahead window; a queue of
+	// frames with increasing gains.
+	holdFrames []int
+	holdGains  []float64
+	frame      int
+	// The released gain and a running average over the look ahead window
+	released float64
+	average  []float64
+	sum      float64
+}
+
+func NewLimiterFilter(ceiling, lookAhead, release float64) *LimiterFilter {
+	return &LimiterFilter{
+		Ceiling:   ceiling,
+		LookAhead: lookAhead,
+		Release:   release,
+		released:  1.0,
+	}
+}
+
+// Latency returns the delay in frames that the limiter adds.
+func (f *LimiterFilter) Latency(cfg *audio.AudioConfig) int {
+	return int(math.Max(1, math.Round(f.LookAhead*float64(cfg.SampleRate))))
+}
+
+func (f *LimiterFilter) reset(cfg *audio.AudioConfig) {
+	f.length = f.Latency(cfg)
+	f.delayed = make([]float64, f.length*cfg.GetNumberOfChannels())
+	f.average = make([]float64, f.length)
+	for i := range f.average {
+		f.average[i] = 1.0
+	}
+	f.sum = float64(f.length)
+	f.frame = 0
+	f.holdFrames = nil
+	f.holdGains = nil
+	f.released = 1.0
+}
+
+func (f *LimiterFilter) Filter(cfg *audio.AudioConfig, samples []float64) []float64 {
+	channels := cfg.GetNumberOfChannels()
+	if len(f.delayed) != f.Latency(cfg)*channels {
+		f.reset(cfg)
+	}
+	ceiling := DecibelsToGain(f.Ceiling)
+	release := envelopeCoefficient(cfg, f.Release)
+	for i := 0; i+channels <= len(samples); i += channels {
+		frame := samples[i : i+channels]
+		gain := 1.0
+		if peak := framePeak(frame); peak > ceiling {
+			gain = ceiling / peak
+		}
+		held := f.hold(gain)
+		if held < f.released {
+			f.released = held
+		} else {
+			f.released += (held - f.released) * release
+		}
+		slot := f.frame % f.length
+		f.sum += f.released - f.average[slot]
+		f.average[slot] = f.released
+		if slot == f.length-1 {
+			// Start over every now and then, so that rounding errors
+			// don't add up.
+			f.sum = 0
+			for _, v := range f.average {
+				f.sum += v
+			}
+		}
+		smoothed := f.sum / float64(f.length)
+
+		offset := slot * channels
+		for c := 0; c < channels; c++ {
+			delayed := f.delayed[offset+c]
+			f.delayed[offset+c] = frame[c]
+			// Only rounding errors are clipped here.
+			frame[c] = math.Max(-ceiling, math.Min(ceiling, delayed*smoothed))
+		}
+		f.frame++
+	}
+	return samples
+}
+
+// hold adds the gain for the next frame and returns the lowest gain of the
+// last length+1 frames.
+func (f *LimiterFilter) hold(gain float64) float64 {
+	for len(f.holdGains) > 0 && f.holdGains[len(f.holdGains)-1] >= gain {
+		f.holdGains = f.holdGains[:len(f.holdGains)-1]
+		f.holdFrames = f.holdFrames[:len(f.holdFrames)-1]
+	}
+	f.holdGains = append(f.holdGains, gain)
+	f.holdFrames = append(f.holdFrames, f.frame)
+	if f.holdFrames[0] < f.frame-f.length {
+		f.holdGains = f.holdGains[1:]
+		f.holdFrames = f.holdFrames[1:]
+	}
+	return f.holdGains[0]
+}
@@ -0,0 +1,59 @@
+package filters
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+)
+
+func Test_Limiter_keeps_the_output_below_the_ceiling(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.Stereo = true
+	f := NewLimiterFilter(-1.0, 0.005, 0.05)
+	ceiling := DecibelsToGain(-1.0)
+
+	// A quiet sine with a loud burst in the left channel halfway through
+	samples := make([]float64, 44100*2)
+	for i := 0; i < len(samples)/2; i++ {
+		v := 0.5 * math.Sin(2*math.Pi*440*float64(i)/44100)
+		if i > 20000 && i < 22000 {
+			samples[i*2] = v * 4
+		} else {
+			samples[i*2] = v
+		}
+		samples[i*2+1] = v
+	}
+	input := append([]float64{}, samples...)
+	latency := f.Latency(cfg)
+	if latency != 221 {
+		t.Fatalf("Expecting a latency of 221 frames, got %d", latency)
+	}
+	output := []float64{}
+	for i := 0; i < len(samples); i += 512 {
+		end := i + 512
+		if end > len(samples) {
+			end = len(samples)
+		}
+		output = append(output, f.Filter(cfg, samples[i:end])...)
+	}
+	for i, v := range output {
+		if math.Abs(v) > ceiling+1e-12 {
+			t.Fatalf("Expecting the output to stay below %f, got %f at %d", ceiling, v, i)
+		}
+	}
+	// Before the burst the quiet sine is just delayed
+	for i := latency * 2; i < 19000*2; i++ {
+		if !almostEqual(output[i], input[i-latency*2], 1e-9) {
+			t.Fatalf("Expecting the input to pass through delayed, got %f instead of %f at %d", output[i], input[i-latency*2], i)
+		}
+	}
+	// The right channel gets turned down along with the left
+	peak := 0.0
+	for i := (21000 + latency) * 2; i < (21500+latency)*2; i += 2 {
+		peak = math.Max(peak, math.Abs(output[i+1]))
+	}
+	if peak > 0.5*ceiling/2+0.01 {
+		t.Errorf("Expecting the right channel to be limited too, got a peak of %f", peak)
+	}
+}
@@ -45,7 +45,9 @@ func (f *LowPassFilter) Filter(cfg *audio.AudioConfig, samples []float64) []floa
 }
 
 type LowPassConvolutionFilter struct {
+	// The cutoff frequency, in Hz. Changes are smoothed.
 	Cutoff float64
+	cutoff SmoothedValue
 
 	// The order of the filter. Should be odd.
 	// The order can't be changed once set without resetting the convolution
@@ -59,18 +61,16 @@ type LowPassConvolutionFilter struct {
 func NewLowPassConvolutionFilter(cutoff float64, order int) *LowPassConvolutionFilter {
 	return &LowPassConvolutionFilter{
 		Cutoff: cutoff,
+		cutoff: NewSmoothedValue(cutoff),
 		Order:  order,
 	}
 }
 
 func (f *LowPassConvolutionFilter) Filter(cfg *audio.AudioConfig, samples []float64) []float64 {
-	coefficients := LowPassConvolution(float64(cfg.SampleRate), f.Cutoff, f.Order)
-	if f.ConvolutionFilter == nil {
-		f.ConvolutionFilter = NewSimpleConvolutionFilter(coefficients)
-	} else {
-		f.ConvolutionFilter.Coefficients = coefficients
-	}
-	return f.ConvolutionFilter.Filter(cfg, samples)
+	f.cutoff.Set(f.Cutoff)
+	return filterWithSmoothedCoefficients(cfg, samples, &f.cutoff, &f.ConvolutionFilter, func(cutoff float64) []float64 {
+		return LowPassConvolution(float64(cfg.SampleRate), cutoff, f.Order)
+	})
 }
 
 // Order should be odd.
@@ -0,0 +1,96 @@
+package filters
+
+import "github.com/bspaans/bleep/audio"
+
+// A SmoothedValue ramps linearly to a new target over the smoothing time in
+// the audio config, so that parameter changes don't click or zipper. The
+// zero value starts at 0.0; use Jump to start somewhere else.
+type SmoothedValue struct {
+	Current float64
+	Target  float64
+
+	step      float64
+	remaining int
+	pending   bool
+}
+
+func NewSmoothedValue(value float64) SmoothedValue {
+	return SmoothedValue{
+		Current: value,
+		Target:  value,
+	}
+}
+
+// Set the target. The ramp starts on the next call to Next.
+func (s *SmoothedValue) Set(target float64) {
+	if target == s.Target && !s.pending {
+		return
+	}
+	s.Target = target
+	s.pending = true
+}
+
+// Jump to the value without smoothing.
+func (s *SmoothedValue) Jump(value float64) {
+	s.Current = value
+	s.Target = value
+	s.remaining = 0
+	s.pending = false
+}
+
+// Next returns the value for the next sample.
+func (s *SmoothedValue) Next(cfg *audio.AudioConfig) float64 {
+	return s.Advance(cfg, 1)
+}
+
+// Advance moves n samples ahead and returns the value.
+func (s *SmoothedValue) Advance(cfg *audio.AudioConfig, n int) float64 {
+	if s.pending {
+		s.pending = false
+		s.remaining = int(cfg.SmoothingTime * float64(cfg.SampleRate))
+		if s.remaining < 1 {
+			s.Jump(s.Target)
+			return s.Current
+		}
+		s.step = (s.Target - s.Current) / float64(s.remaining)
+	}
+	if s.remaining <= n {
+		s.Current = s.Target
+		s.remaining = 0
+	} else if n > 0 {
+		s.Current += s.step * float64(n)
+		s.remaining -= n
+	}
+	return s.Current
+}
+
+// IsSmoothing returns whether the value hasn't reached its target yet.
+func (s *SmoothedValue) IsSmoothing() bool {
+	return s.pending || s.remaining > 0
+}
+
+// While a cutoff frequency is changing, the coefficients of the convolution
+// filters are recalculated every smoothingBlockSize samples.
+const smoothingBlockSize = 32
+
+// filterWithSmoothedCoefficients runs the convolution filter over the
+// samples, in blocks while the value is smoothing.
+func filterWithSmoothedCoefficients(cfg *audio.AudioConfig, samples []float64, value *SmoothedValue, filter **SimpleConvolutionFilter, coefficients func(value float64) []float64) []float64 {
+	channels := cfg.GetNumberOfChannels()
+	n := len(samples) / channels
+	for start := 0; start < n; {
+		size := n - start
+		if value.IsSmoothing() && size > smoothingBlockSize {
+			size = smoothingBlockSize
+		}
+		c := coefficients(value.Advance(cfg, size))
+		if *filter == nil {
+			*filter = NewSimpleConvolutionFilter(c)
+		} else {
+			(*filter).Coefficients = c
+		}
+		(*filter).Filter(cfg, samples[start*channels:(start+size)*channels])
+		start += size
+	}
+	return samples
+}
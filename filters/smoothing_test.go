@@ -0,0 +1,55 @@
+package filters
+
+import (
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+)
+
+func Test_SmoothedValue_ramps_linearly(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.SampleRate = 1000
+	cfg.SmoothingTime = 0.01
+
+	v := NewSmoothedValue(1.0)
+	v.Set(2.0)
+	for i := 1; i <= 10; i++ {
+		if value := v.Next(cfg); !almostEqual(value, 1.0+float64(i)/10, 1e-9) {
+			t.Errorf("Expecting %f after %d samples, got %f", 1.0+float64(i)/10, i, value)
+		}
+	}
+	if v.IsSmoothing() || v.Next(cfg) != 2.0 {
+		t.Errorf("Expecting the target to be reached")
+	}
+
+	v.Set(0.0)
+	if value := v.Advance(cfg, 5); !almostEqual(value, 1.0, 1e-9) {
+		t.Errorf("Expecting to be halfway after 5 samples, got %f", value)
+	}
+	if value := v.Advance(cfg, 100); value != 0.0 {
+		t.Errorf("Expecting the target after the smoothing time, got %f", value)
+	}
+
+	cfg.SmoothingTime = 0.0
+	v.Set(3.0)
+	if value := v.Next(cfg); value != 3.0 {
+		t.Errorf("Expecting no smoothing, got %f", value)
+	}
+}
+
+func Test_LowPassConvolutionFilter_smooths_the_cutoff(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.Stereo = false
+	f := NewLowPassConvolutionFilter(1000, 25)
+	f.Filter(cfg, make([]float64, 64))
+
+	f.Cutoff = 5000
+	f.Filter(cfg, make([]float64, 64))
+	if f.cutoff.Current <= 1000 || f.cutoff.Current >= 5000 {
+		t.Errorf("Expecting the cutoff to be on its way to 5000Hz, got %f", f.cutoff.Current)
+	}
+	f.Filter(cfg, make([]float64, cfg.SampleRate))
+	if f.cutoff.Current != 5000 {
+		t.Errorf("Expecting the cutoff to reach 5000Hz, got %f", f.cutoff.Current)
+	}
+}
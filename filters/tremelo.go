@@ -8,15 +8,19 @@ import (
 )
 
 type TremeloFilter struct {
-	Rate   float64
+	Rate float64
+	// Changes to the factor are smoothed.
 	Factor float64
 	Phase  float64
+
+	factor SmoothedValue
 }
 
 func NewTremeloFilter(rate, factor float64) *TremeloFilter {
 	return &TremeloFilter{
 		Factor: factor,
 		Rate:   rate,
+		factor: NewSmoothedValue(factor),
 	}
 }
 
@@ -29,9 +33,10 @@ func (f *TremeloFilter) Filter(cfg *audio.AudioConfig, samples []float64) []floa
 	}
 	result := make([]float64, len(samples))
 	stepSize := 2 * math.Pi * (f.Rate / float64(cfg.SampleRate))
+	f.factor.Set(f.Factor)
 	for i := 0; i < n; i++ {
 
-		tremelo := 1 + f.Factor*math.Sin(f.Phase)
+		tremelo := 1 + f.factor.Next(cfg)*math.Sin(f.Phase)
 
 		if cfg.Stereo {
 			result[i*2] = samples[i*2] * tremelo
@@ -89,3 +89,7 @@ func (e *EnvelopeGenerator) SetGain(f float64) {
 func (e *EnvelopeGenerator) SetPitchbend(f float64) {
 	e.Generator.SetPitchbend(f)
 }
+
+func (e *EnvelopeGenerator) SetModulation(source generators.ModulationSource, value float64) {
+	generators.SetModulation(e.Generator, source, value)
+}
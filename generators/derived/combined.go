@@ -31,5 +31,10 @@ func NewCombinedGenerators(g ...generators.Generator) generators.Generator {
 			generator.SetGain(f)
 		}
 	}
+	result.SetModulationFunc = func(source generators.ModulationSource, value float64) {
+		for _, generator := range g {
+			generators.SetModulation(generator, source, value)
+		}
+	}
 	return result
 }
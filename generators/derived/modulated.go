@@ -0,0 +1,92 @@
+package derived
+
+import (
+	"math"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/generators"
+)
+
+// ModulationTarget is the parameter that a modulation source controls.
+type ModulationTarget int
+
+const (
+	ModulateGain    ModulationTarget = iota
+	ModulatePitch   ModulationTarget = iota
+	ModulateVibrato ModulationTarget = iota
+)
+
+// The vibrato is updated every vibratoBlockSize samples.
+const vibratoBlockSize = 64
+
+// NewModulatedGenerator routes a modulation source (0.0-1.0) to a parameter
+// of g. For ModulateGain the amount is added to the gain factor; for
+// ModulatePitch and ModulateVibrato it's in semitones. The rate is the
+// frequency of the vibrato in Hz.
+func NewModulatedGenerator(g generators.Generator, source generators.ModulationSource, target ModulationTarget, amount, rate float64) generators.Generator {
+	result := NewWrappedGenerator(g)
+	gain, pitchbend, modulation, phase := 1.0, 1.0, 0.0, 0.0
+
+	applyGain := func() {
+		factor := 1.0 + amount*modulation
+		if factor < 0.0 {
+			factor = 0.0
+		}
+		g.SetGain(gain * factor)
+	}
+	applyPitchbend := func(semitones float64) {
+		g.SetPitchbend(pitchbend * math.Pow(2, semitones/12))
+	}
+
+	result.SetGainFunc = func(f float64) {
+		gain = f
+		if target == ModulateGain {
+			applyGain()
+		} else {
+			g.SetGain(f)
+		}
+	}
+	result.SetPitchbendFunc = func(f float64) {
+		pitchbend = f
+		if f == 0.0 {
+			pitchbend = 1.0
+		}
+		if target == ModulatePitch {
+			applyPitchbend(amount * modulation)
+		} else {
+			g.SetPitchbend(f)
+		}
+	}
+	result.SetModulationFunc = func(s generators.ModulationSource, value float64) {
+		if s == source {
+			modulation = value
+			if target == ModulateGain {
+				applyGain()
+			} else if target == ModulatePitch {
+				applyPitchbend(amount * modulation)
+			} else if target == ModulateVibrato && modulation == 0.0 {
+				applyPitchbend(0.0)
+			}
+		}
+		generators.SetModulation(g, s, value)
+	}
+	if target == ModulateVibrato {
+		result.GetSamplesFunc = func(cfg *audio.AudioConfig, n int) []float64 {
+			if modulation == 0.0 {
+				return g.GetSamples(cfg, n)
+			}
+			samples := make([]float64, 0, len(generators.GetEmptySampleArray(cfg, n)))
+			for i := 0; i < n; i += vibratoBlockSize {
+				size := vibratoBlockSize
+				if n-i < size {
+					size = n - i
+				}
+				applyPitchbend(amount * modulation * math.Sin(2*math.Pi*phase))
+				samples = append(samples, g.GetSamples(cfg, size)...)
+				phase = math.Mod(phase+float64(size)*rate/float64(cfg.SampleRate), 1.0)
+			}
+			return samples
+		}
+	}
+	return result
+}
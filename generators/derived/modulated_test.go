@@ -0,0 +1,61 @@
+package derived
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/generators"
+)
+
+func Test_ModulatedGenerator_gain(t *testing.T) {
+	g := generators.NewBaseGenerator()
+	m := NewModulatedGenerator(g, generators.Aftertouch, ModulateGain, -0.5, 0.0)
+	m.SetGain(0.8)
+	if g.Gain != 0.8 {
+		t.Errorf("Expecting gain 0.8, got %f", g.Gain)
+	}
+	generators.SetModulation(m, generators.Aftertouch, 1.0)
+	if math.Abs(g.Gain-0.4) > 0.0001 {
+		t.Errorf("Expecting gain 0.4, got %f", g.Gain)
+	}
+	generators.SetModulation(m, generators.ModWheel, 0.0)
+	if math.Abs(g.Gain-0.4) > 0.0001 {
+		t.Errorf("Expecting other sources to be ignored, got %f", g.Gain)
+	}
+}
+
+func Test_ModulatedGenerator_pitch(t *testing.T) {
+	g := generators.NewBaseGenerator()
+	m := NewModulatedGenerator(g, generators.ModWheel, ModulatePitch, 12.0, 0.0)
+	m.SetPitchbend(1.5)
+	generators.SetModulation(m, generators.ModWheel, 0.5)
+	expected := 1.5 * math.Pow(2, 0.5)
+	if math.Abs(g.PitchbendFactor-expected) > 0.0001 {
+		t.Errorf("Expecting pitch bend %f, got %f", expected, g.PitchbendFactor)
+	}
+}
+
+func Test_ModulatedGenerator_vibrato(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.SampleRate = 1000
+	cfg.Stereo = true
+	g := generators.NewSineWaveOscillator()
+	m := NewModulatedGenerator(g, generators.ModWheel, ModulateVibrato, 1.0, 5.0)
+	m.SetPitch(440.0)
+	if samples := m.GetSamples(cfg, 100); len(samples) != 200 {
+		t.Errorf("Expecting 200 samples, got %d", len(samples))
+	}
+	generators.SetModulation(m, generators.ModWheel, 1.0)
+	if samples := m.GetSamples(cfg, 100); len(samples) != 200 {
+		t.Errorf("Expecting 200 samples, got %d", len(samples))
+	}
+	base := g.(*generators.BaseGenerator)
+	if base.PitchbendFactor == 1.0 || base.PitchbendFactor == 0.0 {
+		t.Errorf("Expecting the vibrato to bend the pitch")
+	}
+	generators.SetModulation(m, generators.ModWheel, 0.0)
+	if base.PitchbendFactor != 1.0 {
+		t.Errorf("Expecting the pitch bend to be reset, got %f", base.PitchbendFactor)
+	}
+}
@@ -6,10 +6,11 @@ import (
 )
 
 type WrappedGenerator struct {
-	GetSamplesFunc   func(cfg *audio.AudioConfig, n int) []float64
-	SetPitchFunc     func(float64)
-	SetPitchbendFunc func(float64)
-	SetGainFunc      func(float64)
+	GetSamplesFunc    func(cfg *audio.AudioConfig, n int) []float64
+	SetPitchFunc      func(float64)
+	SetPitchbendFunc  func(float64)
+	SetGainFunc       func(float64)
+	SetModulationFunc func(generators.ModulationSource, float64)
 }
 
 func NewWrappedGenerator(g generators.Generator) *WrappedGenerator {
@@ -18,6 +19,9 @@ func NewWrappedGenerator(g generators.Generator) *WrappedGenerator {
 		SetPitchFunc:     g.SetPitch,
 		SetPitchbendFunc: g.SetPitchbend,
 		SetGainFunc:      g.SetGain,
+		SetModulationFunc: func(source generators.ModulationSource, value float64) {
+			generators.SetModulation(g, source, value)
+		},
 	}
 }
 
@@ -44,3 +48,9 @@ func (b *WrappedGenerator) SetGain(f float64) {
 		b.SetGainFunc(f)
 	}
 }
+
+func (b *WrappedGenerator) SetModulation(source generators.ModulationSource, value float64) {
+	if b.SetModulationFunc != nil {
+		b.SetModulationFunc(source, value)
+	}
+}
@@ -0,0 +1,23 @@
+package generators
+
+// ModulationSource is a performance control that can be routed to a
+// generator parameter (see derived.NewModulatedGenerator).
+type ModulationSource int
+
+const (
+	ModWheel   ModulationSource = iota
+	Aftertouch ModulationSource = iota
+)
+
+// Modulated is implemented by generators that respond to modulation
+// sources. Values are between 0.0 and 1.0.
+type Modulated interface {
+	SetModulation(source ModulationSource, value float64)
+}
+
+// SetModulation passes the value on to the generator if it's Modulated.
+func SetModulation(g Generator, source ModulationSource, value float64) {
+	if m, ok := g.(Modulated); ok {
+		m.SetModulation(source, value)
+	}
+}
@@ -10,7 +10,8 @@ require (
 	github.com/gorilla/websocket v1.4.0
 	github.com/mattn/go-runewidth v0.0.4 // indirect
 	github.com/veandco/go-sdl2 v0.4.0
-	gitlab.com/gomidi/midi v1.13.0
+	gitlab.com/gomidi/midi v1.14.1
+	gitlab.com/gomidi/rtmididrv v0.6.0
 	gonum.org/v1/plot v0.8.0
 	gopkg.in/yaml.v2 v2.2.2
 )
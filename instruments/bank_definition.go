@@ -52,14 +52,20 @@ func NewBankFromYamlFile(file string) (*BankDef, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewBankFromYaml(contents, file)
+}
+
+// NewBankFromYaml parses a bank definition. Files referenced by the bank are
+// resolved relative to fromFile, or to the working directory if it's empty.
+func NewBankFromYaml(contents []byte, fromFile string) (*BankDef, error) {
 	result := BankDef{}
 	if err := yaml.Unmarshal(contents, &result); err != nil {
 		return nil, err
 	}
 	if len(result.Instruments) == 0 {
-		return nil, fmt.Errorf("No instruments in bank def %s", file)
+		return nil, fmt.Errorf("No instruments in bank def %s", fromFile)
 	}
-	result.FromFile = file
+	result.FromFile = fromFile
 	return &result, nil
 }
 
@@ -36,6 +36,9 @@ type FilterOptionsDef struct {
 	LPF         *LPFOptionsDef         `json:"lpf,omitempty" yaml:"lpf"`
 	HPF         *LPFOptionsDef         `json:"hpf,omitempty" yaml:"hpf"`
 	BPF         *BandOptionsDef        `json:"bpf,omitempty" yaml:"bpf"`
+	Compressor  *DynamicsOptionsDef    `json:"compressor,omitempty" yaml:"compressor"`
+	Expander    *DynamicsOptionsDef    `json:"expander,omitempty" yaml:"expander"`
+	Gate        *DynamicsOptionsDef    `json:"gate,omitempty" yaml:"gate"`
 	Sum         []*FilterOptionsDef    `json:"sum,omitempty" yaml:"sum"`
 	Average     []*FilterOptionsDef    `json:"average,omitempty" yaml:"average"`
 }
@@ -59,6 +62,12 @@ func (f *FilterOptionsDef) Filter() filters.Filter {
 		return filters.NewTremeloFilter(f.Tremelo.Rate, f.Tremelo.Factor)
 	} else if f.Convolution != nil {
 		return filters.MustNewSimpleConvolutionFilterFromWav(f.Convolution.File)
+	} else if f.Compressor != nil {
+		return f.Compressor.CompressorFilter()
+	} else if f.Expander != nil {
+		return f.Expander.ExpanderFilter()
+	} else if f.Gate != nil {
+		return f.Gate.GateFilter()
 	} else if f.Sum != nil {
 		gs := []filters.Filter{}
 		for _, filter := range f.Sum {
@@ -95,6 +104,21 @@ func (f *FilterOptionsDef) Validate() error {
 		return f.Tremelo.Validate()
 	} else if f.Convolution != nil {
 		return f.Convolution.Validate()
+	} else if f.Compressor != nil {
+		if err := f.Compressor.ValidateWithoutSidechain(); err != nil {
+			return WrapError("compressor", err)
+		}
+		return nil
+	} else if f.Expander != nil {
+		if err := f.Expander.ValidateWithoutSidechain(); err != nil {
+			return WrapError("expander", err)
+		}
+		return nil
+	} else if f.Gate != nil {
+		if err := f.Gate.ValidateWithoutSidechain(); err != nil {
+			return WrapError("gate", err)
+		}
+		return nil
 	} else if f.Sum != nil {
 		for _, filter := range f.Sum {
 			err := filter.Validate()
@@ -224,3 +248,103 @@ func (f *BandOptionsDef) Validate() error {
 	}
 	return nil
 }
+
+// DynamicsOptionsDef configures a compressor, expander or gate. The
+// threshold, knee and makeup are in dB; attack and release in seconds. The
+// sidechain is a mixer channel, and only works on channels.
+type DynamicsOptionsDef struct {
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+	Ratio     float64 `json:"ratio,omitempty" yaml:"ratio,omitempty"`
+	Knee      float64 `json:"knee,omitempty" yaml:"knee,omitempty"`
+	Attack    float64 `json:"attack,omitempty" yaml:"attack,omitempty"`
+	Release   float64 `json:"release,omitempty" yaml:"release,omitempty"`
+	Makeup    float64 `json:"makeup,omitempty" yaml:"makeup,omitempty"`
+	Sidechain *int    `json:"sidechain,omitempty" yaml:"sidechain,omitempty"`
+}
+
+func (f *DynamicsOptionsDef) Validate() error {
+	if f.Ratio != 0.0 && f.Ratio < 1.0 {
+		return fmt.Errorf("Expecting a 'ratio' of at least 1 [e.g. 4]")
+	}
+	if f.Threshold > 0.0 {
+		return fmt.Errorf("Expecting a 'threshold' in dB of 0 or below")
+	}
+	if f.Knee < 0.0 || f.Attack < 0.0 || f.Release < 0.0 {
+		return fmt.Errorf("Expecting positive 'knee', 'attack' and 'release' values")
+	}
+	return nil
+}
+
+// ValidateWithoutSidechain is used where there are no other channels to
+// follow.
+func (f *DynamicsOptionsDef) ValidateWithoutSidechain() error {
+	if f.Sidechain != nil {
+		return fmt.Errorf("A 'sidechain' can only be used on channels")
+	}
+	return f.Validate()
+}
+
+// The ratio defaults to 4, the attack to 10ms and the release to 100ms.
+func (f *DynamicsOptionsDef) CompressorFilter() *filters.CompressorFilter {
+	return filters.NewCompressorFilter(f.Threshold, f.ratio(4), f.Knee, f.attack(0.01), f.release(0.1), f.Makeup)
+}
+
+// The ratio defaults to 2, the attack to 1ms and the release to 100ms.
+func (f *DynamicsOptionsDef) ExpanderFilter() *filters.ExpanderFilter {
+	return filters.NewExpanderFilter(f.Threshold, f.ratio(2), f.Knee, f.attack(0.001), f.release(0.1), f.Makeup)
+}
+
+// The attack defaults to 1ms and the release to 100ms.
+func (f *DynamicsOptionsDef) GateFilter() *filters.GateFilter {
+	return filters.NewGateFilter(f.Threshold, f.Knee, f.attack(0.001), f.release(0.1), f.Makeup)
+}
+
+func (f *DynamicsOptionsDef) ratio(def float64) float64 {
+	if f.Ratio == 0.0 {
+		return def
+	}
+	return f.Ratio
+}
+
+func (f *DynamicsOptionsDef) attack(def float64) float64 {
+	if f.Attack == 0.0 {
+		return def
+	}
+	return f.Attack
+}
+
+func (f *DynamicsOptionsDef) release(def float64) float64 {
+	if f.Release == 0.0 {
+		return def
+	}
+	return f.Release
+}
+
+// The ceiling is in dB; look_ahead and release in seconds.
+type LimiterOptionsDef struct {
+	Ceiling   float64 `json:"ceiling" yaml:"ceiling"`
+	LookAhead float64 `json:"look_ahead,omitempty" yaml:"look_ahead,omitempty"`
+	Release   float64 `json:"release,omitempty" yaml:"release,omitempty"`
+}
+
+func (f *LimiterOptionsDef) Validate() error {
+	if f.Ceiling > 0.0 {
+		return fmt.Errorf("Expecting a 'ceiling' in dB of 0 or below in limiter options [recommended -0.3]")
+	}
+	if f.LookAhead < 0.0 || f.Release < 0.0 {
+		return fmt.Errorf("Expecting positive 'look_ahead' and 'release' times in limiter options")
+	}
+	return nil
+}
+
+// The look ahead defaults to 5ms and the release to 50ms.
+func (f *LimiterOptionsDef) Filter() *filters.LimiterFilter {
+	lookAhead, release := f.LookAhead, f.Release
+	if lookAhead == 0.0 {
+		lookAhead = 0.005
+	}
+	if release == 0.0 {
+		release = 0.05
+	}
+	return filters.NewLimiterFilter(f.Ceiling, lookAhead, release)
+}
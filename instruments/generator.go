@@ -23,6 +23,7 @@ type GeneratorDef struct {
 	Combined      []*GeneratorDef      `json:"combined,omitempty" yaml:"combined,omitempty"`
 	Vocoder       *VocoderDef          `json:"vocoder,omitempty" yaml:"vocoder,omitempty"`
 	Panning       *PitchedPanningDef   `json:"panning,omitempty" yaml:"panning,omitempty"`
+	Modulate      *ModulateDef         `json:"modulate,omitempty" yaml:"modulate,omitempty"`
 }
 
 func (d *GeneratorDef) Generator(ctx *Context) generators.Generator {
@@ -53,6 +54,8 @@ func (d *GeneratorDef) Generator(ctx *Context) generators.Generator {
 		g = d.Vocoder.Generator(ctx)
 	} else if d.Panning != nil {
 		g = d.Panning.Generator(ctx)
+	} else if d.Modulate != nil {
+		g = d.Modulate.Generator(ctx)
 	} else if len(d.Combined) > 0 {
 		gs := []generators.Generator{}
 		for _, gen := range d.Combined {
@@ -92,6 +95,8 @@ func (d *GeneratorDef) Validate(ctx *Context) error {
 		return d.Vocoder.Validate(ctx)
 	} else if d.Panning != nil {
 		return d.Panning.Validate(ctx)
+	} else if d.Modulate != nil {
+		return d.Modulate.Validate(ctx)
 	} else if len(d.Combined) > 0 {
 		gs := []string{}
 		for _, gen := range d.Combined {
@@ -0,0 +1,62 @@
+package instruments
+
+import (
+	"fmt"
+
+	"github.com/bspaans/bleep/generators"
+	"github.com/bspaans/bleep/generators/derived"
+)
+
+var modulationSources = map[string]generators.ModulationSource{
+	"mod_wheel":  generators.ModWheel,
+	"aftertouch": generators.Aftertouch,
+}
+
+var modulationTargets = map[string]derived.ModulationTarget{
+	"gain":    derived.ModulateGain,
+	"pitch":   derived.ModulatePitch,
+	"vibrato": derived.ModulateVibrato,
+}
+
+// ModulateDef routes the mod wheel or aftertouch to the gain, pitch or
+// vibrato of a generator.
+type ModulateDef struct {
+	Source       string  `json:"source" yaml:"source"`
+	Target       string  `json:"target" yaml:"target"`
+	Amount       float64 `json:"amount" yaml:"amount"`
+	Rate         float64 `json:"rate,omitempty" yaml:"rate,omitempty"`
+	GeneratorDef `json:",inline" yaml:",inline"`
+}
+
+func (m *ModulateDef) Generator(ctx *Context) generators.Generator {
+	rate := m.Rate
+	if rate == 0.0 {
+		rate = 5.0
+	}
+	return derived.NewModulatedGenerator(
+		m.GeneratorDef.Generator(ctx),
+		modulationSources[m.Source],
+		modulationTargets[m.Target],
+		m.Amount,
+		rate,
+	)
+}
+
+func (m *ModulateDef) Validate(ctx *Context) error {
+	if _, ok := modulationSources[m.Source]; !ok {
+		return fmt.Errorf("Unknown modulation source '%s' [expecting mod_wheel or aftertouch]", m.Source)
+	}
+	if _, ok := modulationTargets[m.Target]; !ok {
+		return fmt.Errorf("Unknown modulation target '%s' [expecting gain, pitch or vibrato]", m.Target)
+	}
+	if m.Amount == 0.0 {
+		return fmt.Errorf("Missing 'amount' in modulate options")
+	}
+	if m.Rate < 0.0 {
+		return fmt.Errorf("Invalid vibrato 'rate' in modulate options")
+	}
+	if err := m.GeneratorDef.Validate(ctx); err != nil {
+		return WrapError("modulate", err)
+	}
+	return nil
+}
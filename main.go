@@ -9,7 +9,11 @@ import (
 
 	"github.com/bspaans/bleep/audio"
 	"github.com/bspaans/bleep/controller"
+	filterdefs "github.com/bspaans/bleep/instruments"
+	"github.com/bspaans/bleep/midi"
+	"github.com/bspaans/bleep/sequencer/definitions"
 	"github.com/bspaans/bleep/termbox"
+	"github.com/bspaans/bleep/ui/osc"
 	"github.com/bspaans/bleep/ui/server"
 )
 
@@ -20,17 +24,67 @@ var enableSequencer = flag.Bool("enable-sequencer", false, "Enable sequencer")
 var record = flag.String("record", "", "Record .wav output")
 var instruments = flag.String("instruments", "", "The instruments bank to load")
 var percussion = flag.String("percussion", "", "The instruments bank to load for the percussion channel.")
+var mappings = flag.String("mappings", "", "Load MIDI controller mappings from file; learned mappings are saved here as well")
+var midiIn = flag.String("midi-in", "", "Play the notes and control changes from this MIDI input port (number or name)")
+var midiOut = flag.String("midi-out", "", "Send the events on channels with 'output: midi' to this MIDI output port (number or name)")
+var clockIn = flag.String("clock-in", "", "Follow the MIDI clock on this input port (number or name)")
+var clockOut = flag.String("clock-out", "", "Send MIDI clock to this output port (number or name)")
+var listMIDIPorts = flag.Bool("midi-ports", false, "List the MIDI ports and exit")
 var enableUI = flag.Bool("ui", false, "Enable terminal UI (experimental)")
 var enableWS = flag.Bool("ws", false, "Enable web socket endpoint (experimental)")
+var oscAddr = flag.String("osc", "", "Listen for OSC messages on this UDP address (e.g. :9000)")
+var smoothing = flag.Float64("smoothing", 0.02, "The time in seconds it takes volume, panning and filter changes to take effect; 0 to disable")
+var enableLimiter = flag.Bool("limiter", false, "Enable the look-ahead brickwall limiter on the master output")
+var limiterCeiling = flag.Float64("limiter-ceiling", -0.3, "The ceiling of the master limiter in dBFS")
+var enableCompressor = flag.Bool("compressor", false, "Enable the bus compressor on the master output")
+var compressorThreshold = flag.Float64("compressor-threshold", -18, "The threshold of the bus compressor in dBFS")
+var compressorRatio = flag.Float64("compressor-ratio", 2, "The ratio of the bus compressor")
+var enableDither = flag.Bool("dither", false, "Apply TPDF dither when converting to the output bit depth")
+var noiseShaping = flag.Bool("noise-shaping", true, "Shape the dither noise towards the high frequencies")
+var enableSpectrum = flag.Bool("spectrum", false, "Publish a frequency spectrum of the output to the UI and web socket")
 
 func QuitWithError(err error) {
 	fmt.Println("Oh no:", err.Error())
 	os.Exit(1)
 }
 
+func masterDef() *definitions.MasterDef {
+	def := &definitions.MasterDef{}
+	if *enableCompressor {
+		def.Compressor = &filterdefs.DynamicsOptionsDef{
+			Threshold: *compressorThreshold,
+			Ratio:     *compressorRatio,
+		}
+	}
+	if *enableLimiter {
+		def.Limiter = &filterdefs.LimiterOptionsDef{
+			Ceiling: *limiterCeiling,
+		}
+	}
+	if *enableDither {
+		def.Dither = &definitions.DitherDef{
+			NoiseShaping: *noiseShaping,
+		}
+	}
+	return def
+}
+
 func main() {
 
 	flag.Parse()
+	if *listMIDIPorts {
+		driver, err := midi.NewDriver()
+		if err != nil {
+			QuitWithError(err)
+		}
+		ports, err := midi.ListPorts(driver)
+		if err != nil {
+			QuitWithError(err)
+		}
+		fmt.Print(ports)
+		driver.Close()
+		return
+	}
 	fmt.Println("This is not a test.")
 	fmt.Println("You are now running bleep.")
 
@@ -42,8 +96,18 @@ func main() {
 	if *enableMono {
 		cfg.Stereo = false
 	}
+	if *enableSpectrum {
+		cfg.Spectrum = true
+	}
+	cfg.SmoothingTime = *smoothing
 	ctrl := controller.NewController(cfg)
 
+	if *enableLimiter || *enableCompressor || *enableDither {
+		if err := ctrl.SetMaster(masterDef()); err != nil {
+			QuitWithError(err)
+		}
+	}
+
 	if *record != "" {
 		if err := ctrl.EnableWavSink(*record); err != nil {
 			QuitWithError(err)
@@ -62,6 +126,26 @@ func main() {
 			QuitWithError(err)
 		}
 	}
+	if *mappings != "" {
+		if err := ctrl.LoadMIDIMappings(*mappings); err != nil {
+			QuitWithError(err)
+		}
+	}
+	if *midiIn != "" || *midiOut != "" || *clockIn != "" || *clockOut != "" {
+		driver, err := midi.NewDriver()
+		if err != nil {
+			QuitWithError(err)
+		}
+		err = ctrl.EnableMIDIPorts(driver, controller.MIDIPorts{
+			In:       *midiIn,
+			Out:      *midiOut,
+			ClockIn:  *clockIn,
+			ClockOut: *clockOut,
+		})
+		if err != nil {
+			QuitWithError(err)
+		}
+	}
 	defer ctrl.Quit()
 
 	c := make(chan os.Signal)
@@ -89,5 +173,10 @@ func main() {
 	if *enableWS {
 		ctrl.UI = server.NewServer().Start(ctrl)
 	}
+	if *oscAddr != "" {
+		if _, err := osc.NewServer(*oscAddr).Start(ctrl); err != nil {
+			QuitWithError(err)
+		}
+	}
 	ctrl.StartSynth()
 }
@@ -0,0 +1,93 @@
+package midi
+
+import (
+	"gitlab.com/gomidi/midi/mid"
+)
+
+// MIDI clock runs at 24 pulses per quarter note. Song positions are counted
+// in "MIDI beats" (sixteenth notes), which are 6 pulses long.
+const (
+	ClocksPerQuarterNote = 24
+	ClocksPerMIDIBeat    = 6
+	MaxSongPosition      = 0x3FFF
+)
+
+type ClockMessage int
+
+const (
+	ClockPulse        ClockMessage = iota
+	ClockStart        ClockMessage = iota
+	ClockContinue     ClockMessage = iota
+	ClockStop         ClockMessage = iota
+	ClockSongPosition ClockMessage = iota
+)
+
+// ClockMessageFromMIDI parses MIDI clock and transport messages. The song
+// position is only set for ClockSongPosition messages.
+func ClockMessageFromMIDI(data []byte) (msg ClockMessage, songPosition int, ok bool) {
+	if len(data) == 0 {
+		return 0, 0, false
+	}
+	switch data[0] {
+	case 0xF8:
+		return ClockPulse, 0, true
+	case 0xFA:
+		return ClockStart, 0, true
+	case 0xFB:
+		return ClockContinue, 0, true
+	case 0xFC:
+		return ClockStop, 0, true
+	case 0xF2:
+		if len(data) < 3 {
+			return 0, 0, false
+		}
+		return ClockSongPosition, int(data[2])<<7 | int(data[1]), true
+	}
+	return 0, 0, false
+}
+
+// ClockOutput sends MIDI clock and transport messages to an output port.
+type ClockOutput struct {
+	Out mid.Out
+}
+
+func NewClockOutput(out mid.Out) *ClockOutput {
+	return &ClockOutput{
+		Out: out,
+	}
+}
+
+func (c *ClockOutput) Open() error {
+	return c.Out.Open()
+}
+
+func (c *ClockOutput) Close() error {
+	return c.Out.Close()
+}
+
+func (c *ClockOutput) Pulse() error {
+	return c.Out.Send([]byte{0xF8})
+}
+
+func (c *ClockOutput) Start() error {
+	return c.Out.Send([]byte{0xFA})
+}
+
+func (c *ClockOutput) Continue() error {
+	return c.Out.Send([]byte{0xFB})
+}
+
+func (c *ClockOutput) Stop() error {
+	return c.Out.Send([]byte{0xFC})
+}
+
+// SongPosition sends a Song Position Pointer. The position is in sixteenth
+// notes and is capped at MaxSongPosition.
+func (c *ClockOutput) SongPosition(sixteenths int) error {
+	if sixteenths < 0 {
+		sixteenths = 0
+	} else if sixteenths > MaxSongPosition {
+		sixteenths = MaxSongPosition
+	}
+	return c.Out.Send([]byte{0xF2, byte(sixteenths & 0x7F), byte(sixteenths >> 7)})
+}
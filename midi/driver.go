@@ -0,0 +1,16 @@
+//go:build !rtmidi
+// +build !rtmidi
+
+package midi
+
+import (
+	"errors"
+
+	"gitlab.com/gomidi/midi/mid"
+)
+
+// NewDriver returns the driver for the system's MIDI ports. This build
+// doesn't include one; see driver_rtmidi.go.
+func NewDriver() (mid.Driver, error) {
+	return nil, errors.New("bleep was built without MIDI support; rebuild it with `-tags rtmidi`")
+}
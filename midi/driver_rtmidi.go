@@ -0,0 +1,17 @@
+//go:build rtmidi
+// +build rtmidi
+
+package midi
+
+import (
+	"gitlab.com/gomidi/midi/mid"
+	"gitlab.com/gomidi/rtmididrv"
+)
+
+// NewDriver returns the driver for the system's MIDI ports, using RtMidi
+// (ALSA on Linux, CoreMIDI on macOS and WinMM on Windows). It needs cgo and
+// the gitlab.com/gomidi/rtmididrv module, so it's only included when
+// building with `-tags rtmidi`.
+func NewDriver() (mid.Driver, error) {
+	return rtmididrv.New()
+}
@@ -2,6 +2,7 @@ package midi
 
 import (
 	"fmt"
+	"sort"
 
 	"gitlab.com/gomidi/midi"
 	"gitlab.com/gomidi/midi/midimessage/channel" // (Channel Messages)
@@ -102,6 +103,12 @@ func (r *MidiReader) callback(rd smf.Reader) {
 			r.add(int(msg.Channel()), m)
 		case channel.NoteOffVelocity:
 			r.add(int(msg.Channel()), m)
+		case channel.ControlChange:
+			r.add(int(msg.Channel()), m)
+		case channel.Aftertouch:
+			r.add(int(msg.Channel()), m)
+		case channel.PolyAftertouch:
+			r.add(int(msg.Channel()), m)
 		case meta.TimeSig:
 			r.addGlobal(m)
 		case meta.Tempo:
@@ -127,3 +134,44 @@ func (r *MidiReader) ReadFile(file string) (*MIDISequences, error) {
 func ReadMidiFile(file string) (*MIDISequences, error) {
 	return NewMidiReader().ReadFile(file)
 }
+
+// Notes returns the notes that are played on the given channels (or on all
+// channels if none are given) in the order in which they start. Notes that
+// start at the same time are grouped together.
+func (m *MIDISequences) Notes(channels []int) [][]int {
+	onsets := map[int][]int{}
+	for ch, events := range m.Channels {
+		if events == nil || !containsChannel(channels, ch) {
+			continue
+		}
+		for _, ev := range events.Events {
+			if msg, ok := ev.Message.(channel.NoteOn); ok && msg.Velocity() > 0 {
+				onsets[ev.Offset] = append(onsets[ev.Offset], int(msg.Key()))
+			}
+		}
+	}
+	offsets := []int{}
+	for offset := range onsets {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+	result := [][]int{}
+	for _, offset := range offsets {
+		notes := onsets[offset]
+		sort.Ints(notes)
+		result = append(result, notes)
+	}
+	return result
+}
+
+func containsChannel(channels []int, ch int) bool {
+	if len(channels) == 0 {
+		return true
+	}
+	for _, c := range channels {
+		if c == ch {
+			return true
+		}
+	}
+	return false
+}
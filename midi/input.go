@@ -0,0 +1,178 @@
+package midi
+
+import (
+	"github.com/bspaans/bleep/synth"
+	"gitlab.com/gomidi/midi/mid"
+)
+
+// MIDIInput reads live MIDI messages from an input port and turns them into
+// synthesizer events.
+type MIDIInput struct {
+	In         mid.In
+	parameters []*registeredParameter
+
+	// OnClock is called for incoming MIDI clock and transport messages.
+	OnClock func(msg ClockMessage, songPosition int)
+
+	// OnControlChange is called for incoming control changes, except the
+	// ones used for registered parameters. When it returns true the control
+	// change has been handled and no event is produced.
+	OnControlChange func(ch, controller, value int) bool
+}
+
+// registeredParameter tracks the RPN that is selected on a channel with CC
+// 101 and 100, so that the data entry controllers (CC 6 and 38) can be
+// interpreted.
+type registeredParameter struct {
+	MSB     int
+	LSB     int
+	DataMSB int
+}
+
+func NewMIDIInput(in mid.In) *MIDIInput {
+	parameters := make([]*registeredParameter, 16)
+	for i := range parameters {
+		parameters[i] = &registeredParameter{MSB: 127, LSB: 127}
+	}
+	return &MIDIInput{
+		In:         in,
+		parameters: parameters,
+	}
+}
+
+// Start listening on the input port. The handler is called from the driver's
+// go-routine.
+func (m *MIDIInput) Start(handler func(ev *synth.Event)) error {
+	if err := m.In.Open(); err != nil {
+		return err
+	}
+	return m.In.SetListener(func(data []byte, deltaMicroseconds int64) {
+		if msg, pos, ok := ClockMessageFromMIDI(data); ok {
+			if m.OnClock != nil {
+				m.OnClock(msg, pos)
+			}
+		} else if ev := m.Event(data); ev != nil {
+			handler(ev)
+		}
+	})
+}
+
+func (m *MIDIInput) Close() error {
+	if err := m.In.StopListening(); err != nil {
+		return err
+	}
+	return m.In.Close()
+}
+
+// Event converts a raw MIDI channel message into a synthesizer event, like
+// EventFromMIDI, but also keeps track of registered parameters. The pitch bend
+// range (RPN 0) and the MPE configuration message (RPN 6) are supported.
+func (m *MIDIInput) Event(data []byte) *synth.Event {
+	if len(data) < 3 || data[0]&0xF0 != 0xB0 {
+		return EventFromMIDI(data)
+	}
+	ch := int(data[0] & 0x0F)
+	param := m.parameters[ch]
+	value := int(data[2])
+	switch data[1] {
+	case 101:
+		param.MSB = value
+		return nil
+	case 100:
+		param.LSB = value
+		return nil
+	case 6:
+		param.DataMSB = value
+		if param.MSB == 0 && param.LSB == 0 {
+			return synth.NewFloatEvent(synth.SetPitchbendRange, ch, []float64{float64(value)})
+		} else if param.MSB == 0 && param.LSB == 6 {
+			return synth.NewEvent(synth.SetMPEZone, ch, []int{value})
+		}
+		return nil
+	case 38:
+		if param.MSB == 0 && param.LSB == 0 {
+			semitones := float64(param.DataMSB) + float64(value)/100
+			return synth.NewFloatEvent(synth.SetPitchbendRange, ch, []float64{semitones})
+		}
+		return nil
+	}
+	if m.OnControlChange != nil && m.OnControlChange(ch, int(data[1]), value) {
+		return nil
+	}
+	return EventFromMIDI(data)
+}
+
+// EventFromMIDI converts a raw MIDI channel message into a synthesizer event.
+// Returns nil if the message isn't supported.
+func EventFromMIDI(data []byte) *synth.Event {
+	if len(data) == 0 {
+		return nil
+	}
+	status := data[0] & 0xF0
+	ch := int(data[0] & 0x0F)
+	switch status {
+	case 0x80:
+		if len(data) < 3 {
+			return nil
+		}
+		return synth.NewEvent(synth.NoteOff, ch, []int{int(data[1])})
+	case 0x90:
+		if len(data) < 3 {
+			return nil
+		}
+		if data[2] == 0 {
+			return synth.NewEvent(synth.NoteOff, ch, []int{int(data[1])})
+		}
+		return synth.NewEvent(synth.NoteOn, ch, []int{int(data[1]), int(data[2])})
+	case 0xA0:
+		if len(data) < 3 {
+			return nil
+		}
+		return synth.NewEvent(synth.Aftertouch, ch, []int{int(data[1]), int(data[2])})
+	case 0xB0:
+		if len(data) < 3 {
+			return nil
+		}
+		return ControlChangeEvent(ch, int(data[1]), int(data[2]))
+	case 0xC0:
+		if len(data) < 2 {
+			return nil
+		}
+		return synth.NewEvent(synth.ProgramChange, ch, []int{int(data[1])})
+	case 0xE0:
+		if len(data) < 3 {
+			return nil
+		}
+		return synth.NewEvent(synth.PitchBend, ch, []int{int(data[2]), int(data[1])})
+	case 0xD0:
+		if len(data) < 2 {
+			return nil
+		}
+		return synth.NewEvent(synth.ChannelPressure, ch, []int{int(data[1])})
+	}
+	return nil
+}
+
+// ControlChangeEvent converts a control change into a synthesizer event.
+// Returns nil if the controller isn't supported.
+func ControlChangeEvent(ch, controller, value int) *synth.Event {
+	switch controller {
+	case 1:
+		return synth.NewEvent(synth.SetModWheel, ch, []int{value})
+	case 7:
+		return synth.NewEvent(synth.SetChannelVolume, ch, []int{value})
+	case 10:
+		return synth.NewEvent(synth.SetChannelPanning, ch, []int{value})
+	case 11:
+		return synth.NewEvent(synth.SetChannelExpressionVolume, ch, []int{value})
+	case 91:
+		return synth.NewEvent(synth.SetReverb, ch, []int{value})
+	case 92:
+		return synth.NewEvent(synth.SetTremelo, ch, []int{value})
+	case 64:
+		return synth.NewEvent(synth.SetSustain, ch, []int{value})
+	case 74:
+		return synth.NewEvent(synth.SetTimbre, ch, []int{value})
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package midi
+
+import (
+	"testing"
+
+	"github.com/bspaans/bleep/synth"
+)
+
+func Test_MIDIInput_Event_registered_parameters(t *testing.T) {
+	m := NewMIDIInput(nil)
+	for _, msg := range [][]byte{{0xB3, 6, 10}, {0xB3, 101, 0}, {0xB3, 100, 0}} {
+		if ev := m.Event(msg); ev != nil {
+			t.Errorf("Expecting no event for %v, got %v", msg, ev)
+		}
+	}
+	ev := m.Event([]byte{0xB3, 6, 24})
+	if ev == nil || ev.Type != synth.SetPitchbendRange || ev.Channel != 3 || ev.FloatValues[0] != 24.0 {
+		t.Errorf("Expecting a pitch bend range of 24 semitones, got %v", ev)
+	}
+	ev = m.Event([]byte{0xB3, 38, 50})
+	if ev == nil || ev.Type != synth.SetPitchbendRange || ev.FloatValues[0] != 24.5 {
+		t.Errorf("Expecting a pitch bend range of 24.5 semitones, got %v", ev)
+	}
+
+	m.Event([]byte{0xB0, 101, 0})
+	m.Event([]byte{0xB0, 100, 6})
+	ev = m.Event([]byte{0xB0, 6, 15})
+	if ev == nil || ev.Type != synth.SetMPEZone || ev.Channel != 0 || ev.Values[0] != 15 {
+		t.Errorf("Expecting an MPE configuration message, got %v", ev)
+	}
+}
+
+func Test_EventFromMIDI_expression(t *testing.T) {
+	ev := EventFromMIDI([]byte{0xE1, 0x7F, 0x7F})
+	if ev == nil || ev.Type != synth.PitchBend || ev.Values[0] != 127 || ev.Values[1] != 127 {
+		t.Errorf("Expecting a 14 bit pitch bend, got %v", ev)
+	}
+	ev = EventFromMIDI([]byte{0xD2, 100})
+	if ev == nil || ev.Type != synth.ChannelPressure || ev.Channel != 2 || ev.Values[0] != 100 {
+		t.Errorf("Expecting channel pressure, got %v", ev)
+	}
+	ev = EventFromMIDI([]byte{0xB2, 74, 30})
+	if ev == nil || ev.Type != synth.SetTimbre || ev.Values[0] != 30 {
+		t.Errorf("Expecting timbre, got %v", ev)
+	}
+}
+
+func Test_EventFromMIDI_performance_controls(t *testing.T) {
+	cases := map[synth.EventType][]byte{
+		synth.SetSustain:  {0xB0, 64, 127},
+		synth.SetModWheel: {0xB0, 1, 127},
+		synth.Aftertouch:  {0xA0, 60, 127},
+	}
+	for ty, msg := range cases {
+		ev := EventFromMIDI(msg)
+		if ev == nil || ev.Type != ty || ev.Values[len(ev.Values)-1] != 127 {
+			t.Errorf("Expecting event type %v for %v, got %v", ty, msg, ev)
+		}
+	}
+}
+
+func Test_ClockMessageFromMIDI(t *testing.T) {
+	msg, pos, ok := ClockMessageFromMIDI([]byte{0xF2, 0x10, 0x01})
+	if !ok || msg != ClockSongPosition || pos != 144 {
+		t.Errorf("Expecting song position 144, got %v %d", msg, pos)
+	}
+	if msg, _, ok := ClockMessageFromMIDI([]byte{0xF8}); !ok || msg != ClockPulse {
+		t.Errorf("Expecting clock pulse")
+	}
+	if _, _, ok := ClockMessageFromMIDI([]byte{0x90, 60, 100}); ok {
+		t.Errorf("Expecting note on not to be a clock message")
+	}
+}
+
+func Test_MIDIInput_Event_OnControlChange(t *testing.T) {
+	m := NewMIDIInput(nil)
+	m.OnControlChange = func(ch, controller, value int) bool {
+		return controller == 21
+	}
+	if ev := m.Event([]byte{0xB0, 21, 64}); ev != nil {
+		t.Errorf("Expecting the mapped controller to be consumed, got %v", ev)
+	}
+	ev := m.Event([]byte{0xB0, 7, 64})
+	if ev == nil || ev.Type != synth.SetChannelVolume {
+		t.Errorf("Expecting unmapped controllers to keep their meaning, got %v", ev)
+	}
+}
@@ -0,0 +1,132 @@
+package mapping
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/bspaans/bleep/util"
+	"gopkg.in/yaml.v2"
+)
+
+// MappingsDef is the contents of a MIDI mappings file, e.g.:
+//
+//	mappings:
+//	- cc: 74
+//	  target: lpf_cutoff
+//	  channel: 1
+//	  min: 200
+//	  max: 8000
+//	  curve: exponential
+//	- cc: 20
+//	  input_channel: 9
+//	  target: bpm
+type MappingsDef struct {
+	Mappings []*MappingDef `json:"mappings" yaml:"mappings"`
+}
+
+// MappingDef maps a controller to a parameter. When the input channel is
+// omitted the controller is mapped on every channel. The range and curve
+// default to sensible values for the target.
+type MappingDef struct {
+	CC           int      `json:"cc" yaml:"cc"`
+	InputChannel *int     `json:"input_channel,omitempty" yaml:"input_channel,omitempty"`
+	Target       string   `json:"target" yaml:"target"`
+	Channel      int      `json:"channel,omitempty" yaml:"channel,omitempty"`
+	Register     int      `json:"register,omitempty" yaml:"register,omitempty"`
+	Min          *float64 `json:"min,omitempty" yaml:"min,omitempty"`
+	Max          *float64 `json:"max,omitempty" yaml:"max,omitempty"`
+	Curve        string   `json:"curve,omitempty" yaml:"curve,omitempty"`
+}
+
+func NewMappingsDefFromFile(file string) (*MappingsDef, error) {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	result := MappingsDef{}
+	if err := yaml.Unmarshal(contents, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (m *MappingsDef) YAML() (string, error) {
+	b, err := yaml.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (m *MappingsDef) SaveToFile(file string) error {
+	output, err := m.YAML()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, []byte(output), 0644)
+}
+
+func (m *MappingsDef) GetMappings() ([]*Mapping, error) {
+	result := []*Mapping{}
+	for i, def := range m.Mappings {
+		mapping, err := def.GetMapping()
+		if err != nil {
+			return nil, util.WrapError(fmt.Sprintf("mappings[%d]", i), err)
+		}
+		result = append(result, mapping)
+	}
+	return result, nil
+}
+
+func (m *MappingDef) Validate() error {
+	if m.CC < 0 || m.CC > 127 {
+		return util.WrapError("cc", fmt.Errorf("expecting a value between 0 and 127"))
+	}
+	if m.InputChannel != nil && (*m.InputChannel < 0 || *m.InputChannel > 15) {
+		return util.WrapError("input_channel", fmt.Errorf("expecting a value between 0 and 15"))
+	}
+	if _, ok := targets[Target(m.Target)]; !ok {
+		return util.WrapError("target", fmt.Errorf("unknown target '%s'", m.Target))
+	}
+	if m.Channel < 0 || m.Channel > 15 {
+		return util.WrapError("channel", fmt.Errorf("expecting a value between 0 and 15"))
+	}
+	if m.Register < 0 || m.Register > 127 {
+		return util.WrapError("register", fmt.Errorf("expecting a value between 0 and 127"))
+	}
+	curve := Curve(m.Curve)
+	if m.Curve != "" && curve != Linear && curve != Exponential && curve != Logarithmic {
+		return util.WrapError("curve", fmt.Errorf("unknown curve '%s'; expecting linear, exponential or logarithmic", m.Curve))
+	}
+	return nil
+}
+
+func (m *MappingDef) GetMapping() (*Mapping, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	info := targets[Target(m.Target)]
+	result := &Mapping{
+		InputChannel: -1,
+		Controller:   m.CC,
+		Target:       Target(m.Target),
+		Channel:      m.Channel,
+		Register:     m.Register,
+		Min:          info.Min,
+		Max:          info.Max,
+		Curve:        info.Curve,
+	}
+	if m.InputChannel != nil {
+		result.InputChannel = *m.InputChannel
+	}
+	if m.Min != nil {
+		result.Min = *m.Min
+	}
+	if m.Max != nil {
+		result.Max = *m.Max
+	}
+	if m.Curve != "" {
+		result.Curve = Curve(m.Curve)
+	}
+	return result, nil
+}
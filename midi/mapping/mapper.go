@@ -0,0 +1,148 @@
+package mapping
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// The Mapper applies the controller mappings to incoming control changes. In
+// learn mode the next control change that comes in gets mapped to the
+// parameter that is being learned.
+type Mapper struct {
+	sync.Mutex
+	// Learned mappings are saved to this file, unless it's empty.
+	File     string
+	Def      *MappingsDef
+	Mappings []*Mapping
+	// OnLearn is called when a new mapping has been learned.
+	OnLearn func(m *Mapping)
+
+	learning *MappingDef
+}
+
+func NewMapper() *Mapper {
+	return &Mapper{
+		Def:      &MappingsDef{},
+		Mappings: []*Mapping{},
+	}
+}
+
+// NewMapperFromFile loads the mappings from a file. The file doesn't have to
+// exist yet; it's created when the first mapping is learned.
+func NewMapperFromFile(file string) (*Mapper, error) {
+	result := NewMapper()
+	result.File = file
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return result, nil
+	}
+	def, err := NewMappingsDefFromFile(file)
+	if err != nil {
+		return nil, err
+	}
+	mappings, err := def.GetMappings()
+	if err != nil {
+		return nil, err
+	}
+	result.Def = def
+	result.Mappings = mappings
+	return result, nil
+}
+
+// HandleControlChange applies the mappings for the controller and returns
+// whether the control change was handled. Unmapped controllers keep their
+// regular MIDI meaning.
+func (m *Mapper) HandleControlChange(r Receiver, ch, controller, value int) bool {
+	m.Lock()
+	if m.learning != nil {
+		mapping, err := m.learn(ch, controller)
+		onLearn := m.OnLearn
+		m.Unlock()
+		if err != nil {
+			return false
+		}
+		if onLearn != nil {
+			onLearn(mapping)
+		}
+		mapping.Apply(r, value)
+		return true
+	}
+	matches := []*Mapping{}
+	for _, mapping := range m.Mappings {
+		if mapping.Matches(ch, controller) {
+			matches = append(matches, mapping)
+		}
+	}
+	m.Unlock()
+	for _, mapping := range matches {
+		mapping.Apply(r, value)
+	}
+	return len(matches) > 0
+}
+
+// Learn maps the next incoming control change to the target in def. The CC
+// and input channel in def are ignored.
+func (m *Mapper) Learn(def *MappingDef) error {
+	if err := def.Validate(); err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.learning = def
+	return nil
+}
+
+func (m *Mapper) CancelLearn() {
+	m.Lock()
+	defer m.Unlock()
+	m.learning = nil
+}
+
+func (m *Mapper) IsLearning() bool {
+	m.Lock()
+	defer m.Unlock()
+	return m.learning != nil
+}
+
+// Add a mapping, replacing the mappings that were using the same controller
+// on the same input channel.
+func (m *Mapper) Add(def *MappingDef) (*Mapping, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.add(def)
+}
+
+func (m *Mapper) add(def *MappingDef) (*Mapping, error) {
+	mapping, err := def.GetMapping()
+	if err != nil {
+		return nil, err
+	}
+	defs := []*MappingDef{}
+	mappings := []*Mapping{}
+	for i, existing := range m.Mappings {
+		if existing.Controller != mapping.Controller || existing.InputChannel != mapping.InputChannel {
+			defs = append(defs, m.Def.Mappings[i])
+			mappings = append(mappings, existing)
+		}
+	}
+	m.Def.Mappings = append(defs, def)
+	m.Mappings = append(mappings, mapping)
+	return mapping, nil
+}
+
+func (m *Mapper) learn(ch, controller int) (*Mapping, error) {
+	def := *m.learning
+	def.CC = controller
+	def.InputChannel = &ch
+	mapping, err := m.add(&def)
+	if err != nil {
+		return nil, err
+	}
+	m.learning = nil
+	if m.File != "" {
+		if err := m.Def.SaveToFile(m.File); err != nil {
+			fmt.Println("Failed to save MIDI mappings:", err.Error())
+		}
+	}
+	return mapping, nil
+}
@@ -0,0 +1,156 @@
+package mapping
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bspaans/bleep/synth"
+)
+
+// Target is the parameter that a MIDI controller is mapped to.
+type Target string
+
+const (
+	Volume         Target = "volume"
+	Panning        Target = "panning"
+	Expression     Target = "expression"
+	Reverb         Target = "reverb"
+	ReverbTime     Target = "reverb_time"
+	ReverbFeedback Target = "reverb_feedback"
+	Tremelo        Target = "tremelo"
+	LPFCutoff      Target = "lpf_cutoff"
+	HPFCutoff      Target = "hpf_cutoff"
+	GrainGain      Target = "grain_gain"
+	GrainSize      Target = "grain_size"
+	GrainBirthRate Target = "grain_birth_rate"
+	GrainDensity   Target = "grain_density"
+	GrainSpread    Target = "grain_spread"
+	GrainSpeed     Target = "grain_speed"
+	MasterGain     Target = "master_gain"
+	BPM            Target = "bpm"
+	Register       Target = "register"
+	FloatRegister  Target = "float_register"
+)
+
+// Curve shapes how the controller value is spread over the range.
+type Curve string
+
+const (
+	Linear      Curve = "linear"
+	Exponential Curve = "exponential"
+	Logarithmic Curve = "logarithmic"
+)
+
+// The steepness of the exponential and logarithmic curves.
+const curveSteepness = 4.0
+
+type targetInfo struct {
+	EventType synth.EventType
+	Float     bool
+	Min       float64
+	Max       float64
+	Curve     Curve
+}
+
+// The synthesizer events and default ranges for every target. BPM and the
+// registers are set on the sequencer and don't have an event type.
+var targets = map[Target]targetInfo{
+	Volume:         {synth.SetChannelVolume, false, 0, 127, Linear},
+	Panning:        {synth.SetChannelPanning, false, 0, 127, Linear},
+	Expression:     {synth.SetChannelExpressionVolume, false, 0, 127, Linear},
+	Reverb:         {synth.SetReverb, false, 0, 127, Linear},
+	ReverbTime:     {synth.SetReverbTime, true, 0.01, 2.0, Linear},
+	ReverbFeedback: {synth.SetReverbFeedback, true, 0.0, 0.95, Linear},
+	Tremelo:        {synth.SetTremelo, false, 0, 127, Linear},
+	LPFCutoff:      {synth.SetLPFCutoff, false, 20, 20000, Exponential},
+	HPFCutoff:      {synth.SetHPFCutoff, false, 20, 20000, Exponential},
+	GrainGain:      {synth.SetGrainGain, true, 0.0, 1.0, Linear},
+	GrainSize:      {synth.SetGrainSize, true, 0.01, 1.0, Exponential},
+	GrainBirthRate: {synth.SetGrainBirthRate, true, 0.01, 1.0, Exponential},
+	GrainDensity:   {synth.SetGrainDensity, false, 1, 16, Linear},
+	GrainSpread:    {synth.SetGrainSpread, true, 0.0, 100.0, Linear},
+	GrainSpeed:     {synth.SetGrainSpeed, true, 0.25, 4.0, Exponential},
+	MasterGain:     {synth.SetMasterGain, true, 0.0, 1.0, Linear},
+	BPM:            {0, true, 40, 240, Linear},
+	Register:       {0, false, 0, 127, Linear},
+	FloatRegister:  {0, true, 0.0, 1.0, Linear},
+}
+
+// Targets returns the names of all the parameters that can be mapped.
+func Targets() []Target {
+	return []Target{
+		Volume, Panning, Expression,
+		Reverb, ReverbTime, ReverbFeedback, Tremelo, LPFCutoff, HPFCutoff,
+		GrainGain, GrainSize, GrainBirthRate, GrainDensity, GrainSpread, GrainSpeed,
+		MasterGain, BPM, Register, FloatRegister,
+	}
+}
+
+// Receiver applies mapped values. It's implemented by the controller.
+type Receiver interface {
+	SendSynthEvent(ev *synth.Event)
+	SetBPM(bpm float64)
+	SetRegister(register, value int)
+	SetFloatRegister(register int, value float64)
+}
+
+// A Mapping maps a MIDI controller on an input channel to a parameter.
+type Mapping struct {
+	InputChannel int
+	Controller   int
+	Target       Target
+	// The synthesizer channel for channel parameters.
+	Channel  int
+	Register int
+	Min      float64
+	Max      float64
+	Curve    Curve
+}
+
+// Matches returns whether the control change on the input channel is handled
+// by this mapping. An input channel of -1 matches all channels.
+func (m *Mapping) Matches(ch, controller int) bool {
+	return m.Controller == controller && (m.InputChannel < 0 || m.InputChannel == ch)
+}
+
+// Value scales a controller value (0-127) to the mapping's range.
+func (m *Mapping) Value(value int) float64 {
+	x := math.Max(0.0, math.Min(1.0, float64(value)/127))
+	if m.Curve == Exponential {
+		x = (math.Exp(curveSteepness*x) - 1) / (math.Exp(curveSteepness) - 1)
+	} else if m.Curve == Logarithmic {
+		x = math.Log(1+(math.Exp(curveSteepness)-1)*x) / curveSteepness
+	}
+	return m.Min + x*(m.Max-m.Min)
+}
+
+// Apply sends the scaled controller value to the target.
+func (m *Mapping) Apply(r Receiver, value int) {
+	v := m.Value(value)
+	info := targets[m.Target]
+	if m.Target == BPM {
+		r.SetBPM(v)
+	} else if m.Target == Register {
+		r.SetRegister(m.Register, int(math.Round(v)))
+	} else if m.Target == FloatRegister {
+		r.SetFloatRegister(m.Register, v)
+	} else if info.Float {
+		r.SendSynthEvent(synth.NewFloatEvent(info.EventType, m.Channel, []float64{v}))
+	} else {
+		r.SendSynthEvent(synth.NewEvent(info.EventType, m.Channel, []int{int(math.Round(v))}))
+	}
+}
+
+func (m *Mapping) String() string {
+	ch := "any channel"
+	if m.InputChannel >= 0 {
+		ch = fmt.Sprintf("channel %d", m.InputChannel)
+	}
+	target := string(m.Target)
+	if m.Target == Register || m.Target == FloatRegister {
+		target = fmt.Sprintf("%s %d", m.Target, m.Register)
+	} else if m.Target != MasterGain && m.Target != BPM {
+		target = fmt.Sprintf("%s on channel %d", m.Target, m.Channel)
+	}
+	return fmt.Sprintf("CC %d (%s) -> %s", m.Controller, ch, target)
+}
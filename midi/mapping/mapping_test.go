@@ -0,0 +1,161 @@
+package mapping
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bspaans/bleep/synth"
+)
+
+type testReceiver struct {
+	Events         []*synth.Event
+	BPM            float64
+	Registers      map[int]int
+	FloatRegisters map[int]float64
+}
+
+func newTestReceiver() *testReceiver {
+	return &testReceiver{
+		Registers:      map[int]int{},
+		FloatRegisters: map[int]float64{},
+	}
+}
+
+func (r *testReceiver) SendSynthEvent(ev *synth.Event) { r.Events = append(r.Events, ev) }
+func (r *testReceiver) SetBPM(bpm float64)             { r.BPM = bpm }
+func (r *testReceiver) SetRegister(register, value int) {
+	r.Registers[register] = value
+}
+func (r *testReceiver) SetFloatRegister(register int, value float64) {
+	r.FloatRegisters[register] = value
+}
+
+func floatPtr(f float64) *float64 { return &f }
+
+func Test_Mapping_Value_curves(t *testing.T) {
+	for _, curve := range []Curve{Linear, Exponential, Logarithmic} {
+		m := &Mapping{Min: 10, Max: 20, Curve: curve}
+		if v := m.Value(0); math.Abs(v-10) > 1e-9 {
+			t.Errorf("Expecting %s curve to start at 10, got %f", curve, v)
+		}
+		if v := m.Value(127); math.Abs(v-20) > 1e-9 {
+			t.Errorf("Expecting %s curve to end at 20, got %f", curve, v)
+		}
+	}
+	linear := (&Mapping{Max: 1, Curve: Linear}).Value(64)
+	exponential := (&Mapping{Max: 1, Curve: Exponential}).Value(64)
+	logarithmic := (&Mapping{Max: 1, Curve: Logarithmic}).Value(64)
+	if !(exponential < linear && linear < logarithmic) {
+		t.Errorf("Expecting exp < linear < log halfway, got %f %f %f", exponential, linear, logarithmic)
+	}
+	inverted := &Mapping{Min: 1, Max: 0, Curve: Linear}
+	if v := inverted.Value(127); v != 0.0 {
+		t.Errorf("Expecting inverted ranges to work, got %f", v)
+	}
+}
+
+func Test_MappingDef_GetMapping(t *testing.T) {
+	m, err := (&MappingDef{CC: 74, Target: "lpf_cutoff", Channel: 2}).GetMapping()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.InputChannel != -1 || m.Min != 20 || m.Max != 20000 || m.Curve != Exponential {
+		t.Errorf("Expecting the defaults for lpf_cutoff, got %v", m)
+	}
+	m, err = (&MappingDef{CC: 1, Target: "grain_gain", Min: floatPtr(0.5), Max: floatPtr(0.0), Curve: "logarithmic"}).GetMapping()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Min != 0.5 || m.Max != 0.0 || m.Curve != Logarithmic {
+		t.Errorf("Expecting the range and curve to be overridden, got %v", m)
+	}
+	ch := 16
+	for _, def := range []*MappingDef{
+		{CC: 128, Target: "bpm"},
+		{CC: 1, Target: "unknown"},
+		{CC: 1, Target: "bpm", Curve: "wobbly"},
+		{CC: 1, Target: "bpm", InputChannel: &ch},
+	} {
+		if _, err := def.GetMapping(); err == nil {
+			t.Errorf("Expecting an error for %v", def)
+		}
+	}
+}
+
+func Test_Mapping_Apply(t *testing.T) {
+	r := newTestReceiver()
+	mappings := []*Mapping{
+		{Controller: 1, Target: Reverb, Channel: 3, Min: 0, Max: 127},
+		{Controller: 2, Target: GrainSpeed, Channel: 4, Min: 0, Max: 2},
+		{Controller: 3, Target: BPM, Min: 0, Max: 254},
+		{Controller: 4, Target: Register, Register: 5, Min: 0, Max: 7},
+		{Controller: 5, Target: FloatRegister, Register: 6, Min: 0, Max: 1},
+	}
+	for _, m := range mappings {
+		m.Apply(r, 127)
+	}
+	if len(r.Events) != 2 {
+		t.Fatalf("Expecting two synth events, got %d", len(r.Events))
+	}
+	if ev := r.Events[0]; ev.Type != synth.SetReverb || ev.Channel != 3 || ev.Values[0] != 127 {
+		t.Errorf("Expecting a reverb event, got %v", ev)
+	}
+	if ev := r.Events[1]; ev.Type != synth.SetGrainSpeed || ev.Channel != 4 || ev.FloatValues[0] != 2.0 {
+		t.Errorf("Expecting a grain speed event, got %v", ev)
+	}
+	if r.BPM != 254 || r.Registers[5] != 7 || r.FloatRegisters[6] != 1.0 {
+		t.Errorf("Expecting the sequencer to be updated, got %v", r)
+	}
+}
+
+func Test_Mapper_Learn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mappings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "mappings.yaml")
+
+	mapper, err := NewMapperFromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newTestReceiver()
+	if mapper.HandleControlChange(r, 0, 21, 64) {
+		t.Errorf("Expecting unmapped controllers not to be handled")
+	}
+	var learned *Mapping
+	mapper.OnLearn = func(m *Mapping) { learned = m }
+	if err := mapper.Learn(&MappingDef{Target: "master_gain"}); err != nil {
+		t.Fatal(err)
+	}
+	if !mapper.HandleControlChange(r, 2, 21, 127) {
+		t.Errorf("Expecting the control change to be learned")
+	}
+	if learned == nil || learned.Controller != 21 || learned.InputChannel != 2 || mapper.IsLearning() {
+		t.Errorf("Expecting CC 21 on channel 2 to be learned, got %v", learned)
+	}
+	if mapper.HandleControlChange(r, 3, 21, 127) {
+		t.Errorf("Expecting learned mappings to be bound to the input channel")
+	}
+	if !mapper.HandleControlChange(r, 2, 21, 0) {
+		t.Errorf("Expecting the learned mapping to be applied")
+	}
+	if len(r.Events) != 2 || r.Events[1].Type != synth.SetMasterGain || r.Events[1].FloatValues[0] != 0.0 {
+		t.Errorf("Expecting master gain events, got %v", r.Events)
+	}
+
+	// Learning the same controller again replaces the mapping.
+	mapper.Learn(&MappingDef{Target: "bpm"})
+	mapper.HandleControlChange(r, 2, 21, 0)
+	reloaded, err := NewMapperFromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.Mappings) != 1 || reloaded.Mappings[0].Target != BPM || reloaded.Mappings[0].InputChannel != 2 {
+		t.Errorf("Expecting the learned mapping to be saved, got %v", reloaded.Def.Mappings)
+	}
+}
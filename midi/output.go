@@ -0,0 +1,132 @@
+package midi
+
+import (
+	"math"
+
+	"github.com/bspaans/bleep/synth"
+	"gitlab.com/gomidi/midi/mid"
+)
+
+// Output translates synthesizer events into MIDI messages and sends them to
+// an output port. It implements synth.EventOutput.
+type Output struct {
+	Out mid.Out
+}
+
+func NewOutput(out mid.Out) *Output {
+	return &Output{
+		Out: out,
+	}
+}
+
+func (o *Output) Open() error {
+	return o.Out.Open()
+}
+
+func (o *Output) Close() error {
+	return o.Out.Close()
+}
+
+func (o *Output) SendEvent(ev *synth.Event) error {
+	for _, msg := range MessagesFromEvent(ev) {
+		if err := o.Out.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var controllerForEvent = map[synth.EventType]byte{
+	synth.SetModWheel:                1,
+	synth.SetChannelVolume:           7,
+	synth.SetChannelPanning:          10,
+	synth.SetChannelExpressionVolume: 11,
+	synth.SetSustain:                 64,
+	synth.SetTimbre:                  74,
+	synth.SetReverb:                  91,
+	synth.SetTremelo:                 92,
+	synth.SetChorus:                  93,
+	synth.SetPhaser:                  95,
+}
+
+// MessagesFromEvent converts a synthesizer event into raw MIDI messages. It's
+// the reverse of EventFromMIDI. Returns nil for events that have no MIDI
+// equivalent.
+func MessagesFromEvent(ev *synth.Event) [][]byte {
+	if ev.Channel < 0 || ev.Channel > 15 {
+		return nil
+	}
+	ch := byte(ev.Channel)
+	values := ev.Values
+	if cc, ok := controllerForEvent[ev.Type]; ok && len(values) > 0 {
+		return [][]byte{{0xB0 | ch, cc, dataByte(values[0])}}
+	}
+	switch ev.Type {
+	case synth.NoteOn:
+		if len(values) < 2 || values[0] > 127 {
+			return nil
+		}
+		return [][]byte{{0x90 | ch, dataByte(values[0]), dataByte(values[1])}}
+	case synth.NoteOff:
+		if len(values) < 1 || values[0] > 127 {
+			return nil
+		}
+		velocity := 0
+		if len(values) > 1 {
+			velocity = values[1]
+		}
+		return [][]byte{{0x80 | ch, dataByte(values[0]), dataByte(velocity)}}
+	case synth.ProgramChange:
+		if len(values) < 1 {
+			return nil
+		}
+		return [][]byte{{0xC0 | ch, dataByte(values[0])}}
+	case synth.PitchBend:
+		if len(values) < 1 {
+			return nil
+		}
+		lsb := 0
+		if len(values) > 1 {
+			lsb = values[1]
+		}
+		return [][]byte{{0xE0 | ch, dataByte(lsb), dataByte(values[0])}}
+	case synth.ChannelPressure:
+		if len(values) < 1 {
+			return nil
+		}
+		return [][]byte{{0xD0 | ch, dataByte(values[0])}}
+	case synth.Aftertouch:
+		if len(values) < 2 {
+			return nil
+		}
+		return [][]byte{{0xA0 | ch, dataByte(values[0]), dataByte(values[1])}}
+	case synth.SilenceChannel:
+		// All Sound Off and Reset All Controllers aren't used, because the
+		// channel setup should survive.
+		return [][]byte{{0xB0 | ch, 64, 0}, {0xB0 | ch, 123, 0}}
+	case synth.SetPitchbendRange:
+		if len(ev.FloatValues) < 1 {
+			return nil
+		}
+		semitones := math.Floor(ev.FloatValues[0])
+		cents := math.Round((ev.FloatValues[0] - semitones) * 100)
+		return [][]byte{
+			{0xB0 | ch, 101, 0},
+			{0xB0 | ch, 100, 0},
+			{0xB0 | ch, 6, dataByte(int(semitones))},
+			{0xB0 | ch, 38, dataByte(int(cents))},
+			{0xB0 | ch, 101, 127},
+			{0xB0 | ch, 100, 127},
+		}
+	}
+	return nil
+}
+
+func dataByte(v int) byte {
+	if v < 0 {
+		return 0
+	} else if v > 127 {
+		return 127
+	}
+	return byte(v)
+}
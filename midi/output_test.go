@@ -0,0 +1,71 @@
+package midi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bspaans/bleep/synth"
+)
+
+func Test_Output_loopback(t *testing.T) {
+	drv := NewLoopbackDriver(1)
+	defer drv.Close()
+	ins, _ := drv.Ins()
+	outs, _ := drv.Outs()
+
+	received := []*synth.Event{}
+	input := NewMIDIInput(ins[0])
+	if err := input.Start(func(ev *synth.Event) {
+		received = append(received, ev)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	output := NewOutput(outs[0])
+	if err := output.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := []*synth.Event{
+		synth.NewEvent(synth.NoteOn, 3, []int{60, 100}),
+		synth.NewEvent(synth.NoteOff, 3, []int{60}),
+		synth.NewEvent(synth.ProgramChange, 1, []int{42}),
+		synth.NewEvent(synth.SetChannelVolume, 2, []int{90}),
+		synth.NewEvent(synth.SetSustain, 2, []int{127}),
+		synth.NewEvent(synth.PitchBend, 4, []int{100, 5}),
+		synth.NewEvent(synth.ChannelPressure, 5, []int{33}),
+		synth.NewEvent(synth.Aftertouch, 5, []int{61, 34}),
+	}
+	for _, ev := range events {
+		if err := output.SendEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(received) != len(events) {
+		t.Fatalf("Expecting %d events, got %d", len(events), len(received))
+	}
+	for i, ev := range events {
+		expected := ev
+		if ev.Type == synth.NoteOff {
+			expected = synth.NewEvent(synth.NoteOff, 3, []int{60})
+		}
+		if !reflect.DeepEqual(expected, received[i]) {
+			t.Errorf("Expecting %v, got %v", expected, received[i])
+		}
+	}
+
+	// The pitch bend range is sent in semitones (CC 6) and cents (CC 38).
+	received = nil
+	output.SendEvent(synth.NewFloatEvent(synth.SetPitchbendRange, 6, []float64{24.5}))
+	expected := synth.NewFloatEvent(synth.SetPitchbendRange, 6, []float64{24.5})
+	if len(received) != 2 || !reflect.DeepEqual(expected, received[1]) {
+		t.Errorf("Expecting %v, got %v", expected, received)
+	}
+
+	// Events without a MIDI equivalent aren't sent.
+	if msgs := MessagesFromEvent(synth.NewFloatEvent(synth.SetGrainGain, 0, []float64{1.0})); msgs != nil {
+		t.Errorf("Expecting no messages, got %v", msgs)
+	}
+	if msgs := MessagesFromEvent(synth.NewEvent(synth.NoteOn, 0, []int{128, 100})); msgs != nil {
+		t.Errorf("Expecting no messages for the grain note, got %v", msgs)
+	}
+}
@@ -0,0 +1,105 @@
+package midi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gitlab.com/gomidi/midi/mid"
+)
+
+// FindIn looks up an input port by number or by name. A name matches when
+// it's equal to the port's name or, failing that, when it's part of exactly
+// one port's name. The port isn't opened.
+func FindIn(d mid.Driver, port string) (mid.In, error) {
+	ins, err := d.Ins()
+	if err != nil {
+		return nil, fmt.Errorf("can't list MIDI input ports: %s", err.Error())
+	}
+	ports := make([]mid.Port, len(ins))
+	for i, in := range ins {
+		ports[i] = in
+	}
+	ix, err := findPort(ports, port)
+	if err != nil {
+		return nil, fmt.Errorf("MIDI input port %s", err.Error())
+	}
+	return ins[ix], nil
+}
+
+// FindOut looks up an output port like FindIn.
+func FindOut(d mid.Driver, port string) (mid.Out, error) {
+	outs, err := d.Outs()
+	if err != nil {
+		return nil, fmt.Errorf("can't list MIDI output ports: %s", err.Error())
+	}
+	ports := make([]mid.Port, len(outs))
+	for i, out := range outs {
+		ports[i] = out
+	}
+	ix, err := findPort(ports, port)
+	if err != nil {
+		return nil, fmt.Errorf("MIDI output port %s", err.Error())
+	}
+	return outs[ix], nil
+}
+
+// ListPorts describes the input and output ports of the driver.
+func ListPorts(d mid.Driver) (string, error) {
+	ins, err := d.Ins()
+	if err != nil {
+		return "", err
+	}
+	outs, err := d.Outs()
+	if err != nil {
+		return "", err
+	}
+	result := "MIDI input ports:\n"
+	for _, in := range ins {
+		result += fmt.Sprintf("  %d: %s\n", in.Number(), in.String())
+	}
+	result += "MIDI output ports:\n"
+	for _, out := range outs {
+		result += fmt.Sprintf("  %d: %s\n", out.Number(), out.String())
+	}
+	return result, nil
+}
+
+func findPort(ports []mid.Port, port string) (int, error) {
+	if number, err := strconv.Atoi(port); err == nil {
+		for i, p := range ports {
+			if p.Number() == number {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%d not found; available: %s", number, portNames(ports))
+	}
+	for i, p := range ports {
+		if p.String() == port {
+			return i, nil
+		}
+	}
+	matches := []int{}
+	for i, p := range ports {
+		if strings.Contains(strings.ToLower(p.String()), strings.ToLower(port)) {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], nil
+	} else if len(matches) > 1 {
+		return 0, fmt.Errorf("'%s' is ambiguous; available: %s", port, portNames(ports))
+	}
+	return 0, fmt.Errorf("'%s' not found; available: %s", port, portNames(ports))
+}
+
+func portNames(ports []mid.Port) string {
+	if len(ports) == 0 {
+		return "none"
+	}
+	names := make([]string, len(ports))
+	for i, p := range ports {
+		names[i] = fmt.Sprintf("%d (%s)", p.Number(), p.String())
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,36 @@
+package midi
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_FindIn(t *testing.T) {
+	d := NewLoopbackDriver(3)
+	for port, expected := range map[string]int{"1": 1, "loopback in 2": 2, "IN 0": 0} {
+		in, err := FindIn(d, port)
+		if err != nil {
+			t.Errorf("Expecting port '%s' to be found, got %s", port, err.Error())
+		} else if in.Number() != expected {
+			t.Errorf("Expecting port '%s' to be %d, got %d", port, expected, in.Number())
+		}
+	}
+	for _, port := range []string{"3", "loopback", "out"} {
+		if _, err := FindIn(d, port); err == nil {
+			t.Errorf("Expecting an error for port '%s'", port)
+		} else if !strings.Contains(err.Error(), "0 (loopback in 0)") {
+			t.Errorf("Expecting the available ports in the error, got %s", err.Error())
+		}
+	}
+}
+
+func Test_FindOut(t *testing.T) {
+	d := NewLoopbackDriver(2)
+	out, err := FindOut(d, "out 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Number() != 1 {
+		t.Errorf("Expecting port 1, got %d", out.Number())
+	}
+}
@@ -44,3 +44,16 @@ func Test_IntCycleAutomation_basic(t *testing.T) {
 		}
 	}
 }
+
+func Test_FloatTimelineAutomation(t *testing.T) {
+
+	unit := FloatTimelineAutomation([]uint{0, 4, 8}, []float64{120, 140, 100}, []bool{false, false, true})
+
+	expected := []float64{120, 120, 120, 120, 140, 130, 120, 110, 100, 100, 100}
+	for i, e := range expected {
+		got := unit(nil, uint(i), uint(i))
+		if e != got {
+			t.Errorf("Expecting %dth element to be %f got %f", i, e, got)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"time"
 
 	. "github.com/bspaans/bleep/sequencer/status"
 	"github.com/bspaans/bleep/theory"
@@ -100,7 +101,7 @@ func IntRandomAutomation(min, max int) IntAutomation {
 		if min > max {
 			min, max = max, min
 		}
-		return rand.Intn(max-min) + min
+		return random(s).Intn(max-min) + min
 	}
 }
 
@@ -110,10 +111,21 @@ func FloatRandomAutomation(min, max float64) FloatAutomation {
 			min, max = max, min
 		}
 		randomRange := max - min
-		return rand.Float64()*randomRange + min
+		return random(s).Float64()*randomRange + min
 	}
 }
 
+// Use the sequencer's random number generator if there is one, so that
+// playback can be reproduced.
+func random(s *Status) *rand.Rand {
+	if s != nil && s.Random != nil {
+		return s.Random
+	}
+	return globalRandom
+}
+
+var globalRandom = rand.New(rand.NewSource(time.Now().UnixNano()))
+
 func IntSweepAutomation(min, max, changeEvery, step int) IntAutomation {
 	if changeEvery == 0 {
 		changeEvery = 1
@@ -232,12 +244,18 @@ func ChordCycleArrayAutomation(changeEvery int, chords [][]int) IntArrayAutomati
 		return v
 	}
 }
+
+// Chord expects a chord that theory.ChordOnNoteInt knows about; unknown
+// chords produce no notes.
 func Chord(chord string, baseNoteF, octavesF, inversionsF IntAutomation) IntArrayAutomation {
 	return func(s *Status, counter, t uint) []int {
 		baseNote := baseNoteF(s, counter, t)
 		inversions := inversionsF(s, counter, t)
 		octaves := octavesF(s, counter, t)
-		baseValues := theory.ChordOnNoteInt(baseNote, chord)
+		baseValues, err := theory.ChordOnNoteInt(baseNote, chord)
+		if err != nil {
+			return []int{}
+		}
 		baseValues = theory.InvertChord(baseValues, inversions)
 
 		values := []int{}
@@ -320,3 +338,28 @@ func ChordOnScale(chord string, scaleF IntArrayAutomation, startF, octavesF, inv
 		return values
 	}
 }
+
+// FloatTimelineAutomation returns values[i] from time at[i] onwards. If
+// ramp[i] is set the value moves linearly from values[i-1] to values[i]
+// between at[i-1] and at[i] instead of changing abruptly. The points in time
+// should be sorted.
+func FloatTimelineAutomation(at []uint, values []float64, ramp []bool) FloatAutomation {
+	return func(s *Status, counter, t uint) float64 {
+		if len(values) == 0 {
+			return 0.0
+		}
+		for i := len(at) - 1; i > 0; i-- {
+			if t >= at[i] {
+				return values[i]
+			}
+			if t >= at[i-1] {
+				if !ramp[i] {
+					return values[i-1]
+				}
+				progress := float64(t-at[i-1]) / float64(at[i]-at[i-1])
+				return values[i-1] + progress*(values[i]-values[i-1])
+			}
+		}
+		return values[0]
+	}
+}
@@ -0,0 +1,81 @@
+package automations
+
+import (
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/theory"
+)
+
+// The key used when there is no status, e.g. in tests.
+const defaultKey = 60
+const defaultScale = "ionian"
+
+func currentKey(s *Status) (int, string) {
+	if s == nil || s.Scale == "" {
+		return defaultKey, defaultScale
+	}
+	return s.Key, s.Scale
+}
+
+func degreeToNote(s *Status, d theory.Degree) int {
+	return d.OnScale(currentKey(s))
+}
+
+// IntDegreeAutomation returns the note for the scale degree in the current
+// key.
+func IntDegreeAutomation(degree theory.Degree) IntAutomation {
+	return func(s *Status, counter, t uint) int {
+		return degreeToNote(s, degree)
+	}
+}
+
+// IntArrayDegreeAutomation returns the notes for the scale degrees in the
+// current key.
+func IntArrayDegreeAutomation(degrees []theory.Degree) IntArrayAutomation {
+	return func(s *Status, counter, t uint) []int {
+		result := make([]int, len(degrees))
+		for i, d := range degrees {
+			result[i] = degreeToNote(s, d)
+		}
+		return result
+	}
+}
+
+// IntDegreeCycleAutomation cycles through the scale degrees, resolving each
+// against the current key.
+func IntDegreeCycleAutomation(degrees []theory.Degree) IntAutomation {
+	l := uint(len(degrees))
+	return func(s *Status, counter, t uint) int {
+		return degreeToNote(s, degrees[counter%l])
+	}
+}
+
+// IntDegreeRandomAutomation picks a random scale degree between min and max
+// (inclusive) and resolves it against the current key.
+func IntDegreeRandomAutomation(min, max int) IntAutomation {
+	if min > max {
+		min, max = max, min
+	}
+	return func(s *Status, counter, t uint) int {
+		degree := random(s).Intn(max-min+1) + min
+		return degreeToNote(s, theory.Degree{Degree: degree})
+	}
+}
+
+// RomanNumeralCycleAutomation cycles through the chords of a roman numeral
+// progression in the current key, changing every changeEvery steps. If
+// voiceLeading is set the chords are voiced as close as possible to the one
+// before them.
+func RomanNumeralCycleAutomation(changeEvery int, numerals []*theory.RomanNumeral, voiceLeading bool) IntArrayAutomation {
+	return func(s *Status, counter, t uint) []int {
+		key, scale := currentKey(s)
+		chords := make([][]int, len(numerals))
+		for i, n := range numerals {
+			chords[i] = n.OnKey(key, scale)
+		}
+		if voiceLeading {
+			chords = theory.VoiceLeadProgression(chords)
+		}
+		ix := counter % uint(changeEvery*len(chords))
+		return chords[ix/uint(changeEvery)]
+	}
+}
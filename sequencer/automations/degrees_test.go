@@ -0,0 +1,58 @@
+package automations
+
+import (
+	"testing"
+
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/theory"
+)
+
+func Test_IntArrayDegreeAutomation_follows_key(t *testing.T) {
+	degrees, _ := theory.ParseDegrees([]interface{}{1, 3, 5, "b7"})
+	unit := IntArrayDegreeAutomation(degrees)
+	status := NewStatus(120, 4)
+	cases := []struct {
+		Key      int
+		Scale    string
+		Expected []int
+	}{
+		{60, "ionian", []int{60, 64, 67, 70}},
+		{62, "ionian", []int{62, 66, 69, 72}},
+		{62, "dorian", []int{62, 65, 69, 71}},
+	}
+	for _, c := range cases {
+		status.Key = c.Key
+		status.Scale = c.Scale
+		got := unit(&status, 0, 0)
+		for i, e := range c.Expected {
+			if got[i] != e {
+				t.Errorf("Expecting %v in %d %s, got %v", c.Expected, c.Key, c.Scale, got)
+				break
+			}
+		}
+	}
+}
+
+func Test_IntDegreeCycleAutomation(t *testing.T) {
+	degrees, _ := theory.ParseDegrees([]interface{}{1, 2, 8})
+	unit := IntDegreeCycleAutomation(degrees)
+	expected := []int{60, 62, 72, 60}
+	for i, e := range expected {
+		if got := unit(nil, uint(i), 0); got != e {
+			t.Errorf("Expecting %d at %d, got %d", e, i, got)
+		}
+	}
+}
+
+func Test_IntDegreeRandomAutomation(t *testing.T) {
+	status := NewStatus(120, 4)
+	status.Key = 57
+	status.Scale = "aeolian"
+	allowed := map[int]bool{57: true, 59: true, 60: true}
+	unit := IntDegreeRandomAutomation(1, 3)
+	for i := uint(0); i < 100; i++ {
+		if got := unit(&status, i, 0); !allowed[got] {
+			t.Fatalf("Expecting one of the first three degrees of A minor, got %d", got)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+package automations
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	. "github.com/bspaans/bleep/sequencer/status"
+)
+
+// CompileExpression compiles an arithmetic expression into an automation.
+// Expressions are compiled once, so that evaluating them on every tick is
+// cheap. All values are floats; comparisons and logical operators evaluate
+// to 1.0 (true) or 0.0 (false).
+//
+// The following are supported:
+//
+//   - numbers: 1, 0.5, pi
+//   - variables: counter, t, bpm, granularity
+//   - registers: r0..r127 (int), f0..f127 (float), a0[i] and len(a0) (int arrays)
+//   - operators: + - * / % == != < <= > >= && || ! and cond ? a : b
+//   - functions: sin cos tan abs floor ceil round sqrt min max pow clamp rand
+//
+// Division or modulo by zero evaluates to zero.
+func CompileExpression(expr string) (FloatAutomation, error) {
+	tokens, err := tokenizeExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &expressionParser{tokens: tokens}
+	f, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s' in expression '%s'", p.tokens[p.pos].value, expr)
+	}
+	return FloatAutomation(f), nil
+}
+
+// IntExpressionAutomation evaluates the expression and rounds the result to
+// the nearest integer.
+func IntExpressionAutomation(expr string) (IntAutomation, error) {
+	f, err := CompileExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+	return func(s *Status, counter, t uint) int {
+		return int(math.Round(f(s, counter, t)))
+	}, nil
+}
+
+type exprFunc func(s *Status, counter, t uint) float64
+
+type tokenType int
+
+const (
+	numberToken tokenType = iota
+	identToken
+	operatorToken
+)
+
+type token struct {
+	ty    tokenType
+	value string
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%", "<", ">", "!", "?", ":", "(", ")", "[", "]", ","}
+
+func tokenizeExpression(expr string) ([]token, error) {
+	result := []token{}
+	runes := []rune(expr)
+	i := 0
+	for i < len(runes) {
+		c := runes[i]
+		if unicode.IsSpace(c) {
+			i++
+		} else if unicode.IsDigit(c) || c == '.' {
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			result = append(result, token{numberToken, string(runes[start:i])})
+		} else if unicode.IsLetter(c) || c == '_' {
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			result = append(result, token{identToken, string(runes[start:i])})
+		} else {
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					result = append(result, token{operatorToken, op})
+					i += len([]rune(op))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected character '%c' in expression '%s'", c, expr)
+			}
+		}
+	}
+	return result, nil
+}
+
+type expressionParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *expressionParser) peek(op string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].ty == operatorToken && p.tokens[p.pos].value == op
+}
+
+func (p *expressionParser) accept(ops ...string) (string, bool) {
+	for _, op := range ops {
+		if p.peek(op) {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *expressionParser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		if p.pos < len(p.tokens) {
+			return fmt.Errorf("expecting '%s', got '%s'", op, p.tokens[p.pos].value)
+		}
+		return fmt.Errorf("expecting '%s', got end of expression", op)
+	}
+	return nil
+}
+
+func (p *expressionParser) parseTernary() (exprFunc, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("?"); !ok {
+		return cond, nil
+	}
+	a, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	b, err := p.parseTernary()
+	if err != nil {
+		return nil, err
+	}
+	return func(s *Status, counter, t uint) float64 {
+		if cond(s, counter, t) != 0.0 {
+			return a(s, counter, t)
+		}
+		return b(s, counter, t)
+	}, nil
+}
+
+// Binary operators from lowest to highest precedence.
+var binaryOperators = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *expressionParser) parseBinary(level int) (exprFunc, error) {
+	if level == len(binaryOperators) {
+		return p.parseUnary()
+	}
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(binaryOperators[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryOperator(op, left, right)
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1.0
+	}
+	return 0.0
+}
+
+func binaryOperator(op string, a, b exprFunc) exprFunc {
+	var f func(x, y float64) float64
+	switch op {
+	case "||":
+		return func(s *Status, counter, t uint) float64 {
+			return boolToFloat(a(s, counter, t) != 0.0 || b(s, counter, t) != 0.0)
+		}
+	case "&&":
+		return func(s *Status, counter, t uint) float64 {
+			return boolToFloat(a(s, counter, t) != 0.0 && b(s, counter, t) != 0.0)
+		}
+	case "==":
+		f = func(x, y float64) float64 { return boolToFloat(x == y) }
+	case "!=":
+		f = func(x, y float64) float64 { return boolToFloat(x != y) }
+	case "<":
+		f = func(x, y float64) float64 { return boolToFloat(x < y) }
+	case "<=":
+		f = func(x, y float64) float64 { return boolToFloat(x <= y) }
+	case ">":
+		f = func(x, y float64) float64 { return boolToFloat(x > y) }
+	case ">=":
+		f = func(x, y float64) float64 { return boolToFloat(x >= y) }
+	case "+":
+		f = func(x, y float64) float64 { return x + y }
+	case "-":
+		f = func(x, y float64) float64 { return x - y }
+	case "*":
+		f = func(x, y float64) float64 { return x * y }
+	case "/":
+		f = func(x, y float64) float64 {
+			if y == 0.0 {
+				return 0.0
+			}
+			return x / y
+		}
+	case "%":
+		f = func(x, y float64) float64 {
+			if y == 0.0 {
+				return 0.0
+			}
+			return math.Mod(x, y)
+		}
+	}
+	return func(s *Status, counter, t uint) float64 {
+		return f(a(s, counter, t), b(s, counter, t))
+	}
+}
+
+func (p *expressionParser) parseUnary() (exprFunc, error) {
+	op, ok := p.accept("-", "!")
+	if !ok {
+		return p.parsePrimary()
+	}
+	a, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if op == "-" {
+		return func(s *Status, counter, t uint) float64 {
+			return -a(s, counter, t)
+		}, nil
+	}
+	return func(s *Status, counter, t uint) float64 {
+		return boolToFloat(a(s, counter, t) == 0.0)
+	}, nil
+}
+
+func (p *expressionParser) parsePrimary() (exprFunc, error) {
+	if _, ok := p.accept("("); ok {
+		f, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+	}
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	if tok.ty == numberToken {
+		v, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", tok.value)
+		}
+		return func(s *Status, counter, t uint) float64 { return v }, nil
+	} else if tok.ty == operatorToken {
+		return nil, fmt.Errorf("unexpected '%s'", tok.value)
+	}
+	if p.peek("(") {
+		return p.parseCall(tok.value)
+	}
+	if p.peek("[") {
+		register, err := parseRegister(tok.value, 'a')
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+		ix, err := p.parseTernary()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return func(s *Status, counter, t uint) float64 {
+			values := s.IntArrayRegisters[register]
+			i := int(ix(s, counter, t))
+			if i < 0 || i >= len(values) {
+				return 0.0
+			}
+			return float64(values[i])
+		}, nil
+	}
+	return variable(tok.value)
+}
+
+func variable(name string) (exprFunc, error) {
+	switch name {
+	case "counter":
+		return func(s *Status, counter, t uint) float64 { return float64(counter) }, nil
+	case "t":
+		return func(s *Status, counter, t uint) float64 { return float64(t) }, nil
+	case "bpm":
+		return func(s *Status, counter, t uint) float64 { return s.BPM }, nil
+	case "granularity":
+		return func(s *Status, counter, t uint) float64 { return float64(s.Granularity) }, nil
+	case "pi":
+		return func(s *Status, counter, t uint) float64 { return math.Pi }, nil
+	}
+	if strings.HasPrefix(name, "r") {
+		register, err := parseRegister(name, 'r')
+		if err != nil {
+			return nil, err
+		}
+		return func(s *Status, counter, t uint) float64 {
+			return float64(s.IntRegisters[register])
+		}, nil
+	} else if strings.HasPrefix(name, "f") {
+		register, err := parseRegister(name, 'f')
+		if err != nil {
+			return nil, err
+		}
+		return func(s *Status, counter, t uint) float64 {
+			return s.FloatRegisters[register]
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown variable '%s'", name)
+}
+
+// parseRegister parses register names like r12; registers range from 0 to
+// 127.
+func parseRegister(name string, prefix byte) (int, error) {
+	if len(name) < 2 || name[0] != prefix {
+		return 0, fmt.Errorf("unknown register '%s'", name)
+	}
+	register, err := strconv.Atoi(name[1:])
+	if err != nil || register < 0 || register > 127 {
+		return 0, fmt.Errorf("unknown register '%s'", name)
+	}
+	return register, nil
+}
+
+var expressionFunctions = map[string]struct {
+	args int
+	f    func(args []float64) float64
+}{
+	"sin":   {1, func(a []float64) float64 { return math.Sin(a[0]) }},
+	"cos":   {1, func(a []float64) float64 { return math.Cos(a[0]) }},
+	"tan":   {1, func(a []float64) float64 { return math.Tan(a[0]) }},
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"floor": {1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"ceil":  {1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"round": {1, func(a []float64) float64 { return math.Round(a[0]) }},
+	"sqrt":  {1, func(a []float64) float64 { return math.Sqrt(math.Max(a[0], 0.0)) }},
+	"min":   {2, func(a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":   {2, func(a []float64) float64 { return math.Max(a[0], a[1]) }},
+	"pow":   {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"clamp": {3, func(a []float64) float64 { return math.Max(a[1], math.Min(a[2], a[0])) }},
+}
+
+func (p *expressionParser) parseCall(name string) (exprFunc, error) {
+	p.pos++ // (
+	if name == "len" {
+		return p.parseLen()
+	}
+	args := []exprFunc{}
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseTernary()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if name == "rand" {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("rand() doesn't take any arguments")
+		}
+		return func(s *Status, counter, t uint) float64 {
+			return random(s).Float64()
+		}, nil
+	}
+	fn, ok := expressionFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function '%s'", name)
+	}
+	if len(args) != fn.args {
+		return nil, fmt.Errorf("%s() expects %d argument(s), got %d", name, fn.args, len(args))
+	}
+	return func(s *Status, counter, t uint) float64 {
+		values := make([]float64, len(args))
+		for i, arg := range args {
+			values[i] = arg(s, counter, t)
+		}
+		return fn.f(values)
+	}, nil
+}
+
+// parseLen parses len(aN), which returns the length of an int array register.
+func (p *expressionParser) parseLen() (exprFunc, error) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].ty != identToken {
+		return nil, fmt.Errorf("len() expects an array register")
+	}
+	register, err := parseRegister(p.tokens[p.pos].value, 'a')
+	if err != nil {
+		return nil, err
+	}
+	p.pos++
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return func(s *Status, counter, t uint) float64 {
+		return float64(len(s.IntArrayRegisters[register]))
+	}, nil
+}
@@ -0,0 +1,69 @@
+package automations
+
+import (
+	"testing"
+
+	. "github.com/bspaans/bleep/sequencer/status"
+)
+
+func Test_CompileExpression(t *testing.T) {
+	status := NewStatus(120, 64)
+	status.IntRegisters[1] = 62
+	status.FloatRegisters[2] = 0.5
+	status.IntArrayRegisters[3] = []int{60, 64, 67}
+	cases := []struct {
+		Expr     string
+		Counter  uint
+		T        uint
+		Expected float64
+	}{
+		{"1 + 2 * 3", 0, 0, 7},
+		{"(1 + 2) * 3", 0, 0, 9},
+		{"-t + 10", 0, 4, 6},
+		{"r1 % 12 + 48", 0, 0, 50},
+		{"f2 * 2", 0, 0, 1},
+		{"t % 4 == 0 ? 127 : 90", 0, 8, 127},
+		{"t % 4 == 0 ? 127 : 90", 0, 9, 90},
+		{"counter > 2 && counter < 5", 3, 0, 1},
+		{"counter > 2 && counter < 5", 5, 0, 0},
+		{"!(counter == 1) || 0", 1, 0, 0},
+		{"a3[counter % len(a3)]", 4, 0, 64},
+		{"a3[10]", 0, 0, 0},
+		{"max(3, min(10, 5))", 0, 0, 5},
+		{"clamp(t, 0, 100)", 0, 200, 100},
+		{"sin(0) * 20 + 60", 0, 0, 60},
+		{"granularity / 4 + bpm", 0, 0, 136},
+		{"1 / 0", 0, 0, 0},
+		{"t / 2 >= 1 ? 1 : t / 2 < 0.5 ? 2 : 3", 0, 1, 3},
+	}
+	for _, c := range cases {
+		unit, err := CompileExpression(c.Expr)
+		if err != nil {
+			t.Errorf("Failed to compile '%s': %s", c.Expr, err.Error())
+			continue
+		}
+		if got := unit(&status, c.Counter, c.T); got != c.Expected {
+			t.Errorf("Expecting %v for '%s', got %v", c.Expected, c.Expr, got)
+		}
+	}
+}
+
+func Test_CompileExpression_errors(t *testing.T) {
+	for _, expr := range []string{"", "1 +", "(1", "foo", "r128", "sin(1, 2)", "nope(1)", "1 ? 2", "a1", "len(r1)", "1 $ 2", "1 2"} {
+		if _, err := CompileExpression(expr); err == nil {
+			t.Errorf("Expecting an error for '%s'", expr)
+		}
+	}
+}
+
+func Test_IntExpressionAutomation(t *testing.T) {
+	unit, err := IntExpressionAutomation("t / 4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for tick, e := range map[uint]int{0: 0, 1: 0, 2: 1, 5: 1, 6: 2} {
+		if got := unit(nil, 0, tick); got != e {
+			t.Errorf("Expecting %d at t=%d, got %d", e, tick, got)
+		}
+	}
+}
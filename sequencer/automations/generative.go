@@ -0,0 +1,248 @@
+package automations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/theory"
+)
+
+// MarkovChain is a first order Markov chain over the states 0..n-1.
+// Transitions[i][j] is the relative weight of moving from state i to state j.
+type MarkovChain struct {
+	Transitions [][]float64
+}
+
+func NewMarkovChain(states int) *MarkovChain {
+	transitions := make([][]float64, states)
+	for i := range transitions {
+		transitions[i] = make([]float64, states)
+	}
+	return &MarkovChain{
+		Transitions: transitions,
+	}
+}
+
+// LearnMarkovChain counts the transitions between consecutive values in the
+// sequence. It returns the distinct values, which are the chain's states,
+// together with the chain itself.
+func LearnMarkovChain(sequence [][]int) ([][]int, *MarkovChain) {
+	values := [][]int{}
+	index := map[string]int{}
+	states := []int{}
+	for _, v := range sequence {
+		key := fmt.Sprint(v)
+		ix, ok := index[key]
+		if !ok {
+			ix = len(values)
+			index[key] = ix
+			values = append(values, v)
+		}
+		states = append(states, ix)
+	}
+	chain := NewMarkovChain(len(values))
+	for i := 1; i < len(states); i++ {
+		chain.Transitions[states[i-1]][states[i]] += 1.0
+	}
+	return values, chain
+}
+
+// Next picks the next state. Returns -1 if there are no transitions out of
+// the current state.
+func (m *MarkovChain) Next(s *Status, state int) int {
+	if state < 0 || state >= len(m.Transitions) {
+		return -1
+	}
+	total := 0.0
+	for _, w := range m.Transitions[state] {
+		total += w
+	}
+	if total <= 0.0 {
+		return -1
+	}
+	r := random(s).Float64() * total
+	for next, w := range m.Transitions[state] {
+		if w <= 0.0 {
+			continue
+		}
+		if r < w {
+			return next
+		}
+		r -= w
+	}
+	return -1
+}
+
+// stepper keeps track of a generative process that moves one step every
+// time the counter increases. The process is restarted when the counter goes
+// back to zero or moves backwards (e.g. when the sequencer is rewound).
+type stepper struct {
+	started bool
+	last    uint
+}
+
+// advance returns whether the process should restart and whether it should
+// take a step.
+func (st *stepper) advance(counter uint) (bool, bool) {
+	if !st.started || counter < st.last {
+		st.started = true
+		st.last = counter
+		return true, false
+	}
+	step := counter > st.last
+	st.last = counter
+	return false, step
+}
+
+func markovWalk(chain *MarkovChain, start int) func(s *Status, counter uint) int {
+	st := &stepper{}
+	state := start
+	return func(s *Status, counter uint) int {
+		restart, step := st.advance(counter)
+		if restart {
+			state = start
+		} else if step {
+			state = chain.Next(s, state)
+			if state < 0 {
+				state = start
+			}
+		}
+		return state
+	}
+}
+
+// IntMarkovAutomation walks the Markov chain, moving to a new state every
+// time the counter increases, and returns the value for the current state.
+// When a state without transitions is reached the walk starts over.
+func IntMarkovAutomation(chain *MarkovChain, values []int, start int) IntAutomation {
+	walk := markovWalk(chain, start)
+	return func(s *Status, counter, t uint) int {
+		return values[walk(s, counter)]
+	}
+}
+
+// IntArrayMarkovAutomation is like IntMarkovAutomation, but its states are
+// arrays (e.g. chords).
+func IntArrayMarkovAutomation(chain *MarkovChain, values [][]int, start int) IntArrayAutomation {
+	walk := markovWalk(chain, start)
+	return func(s *Status, counter, t uint) []int {
+		return values[walk(s, counter)]
+	}
+}
+
+// LSystem rewrites the axiom using the rules the given number of times. Every
+// character is a symbol; characters without a rule are left as is.
+func LSystem(axiom string, rules map[string]string, iterations int) string {
+	current := axiom
+	for i := 0; i < iterations; i++ {
+		var b strings.Builder
+		for _, c := range current {
+			if r, ok := rules[string(c)]; ok {
+				b.WriteString(r)
+			} else {
+				b.WriteRune(c)
+			}
+		}
+		current = b.String()
+	}
+	return current
+}
+
+// LSystemNotes maps the symbols of an L-system string to notes. A symbol in
+// degrees plays that scale degree relative to the current position; "+" and
+// "-" move the current position up or down a degree, and "[" and "]" save and
+// restore it. Other symbols are ignored.
+func LSystemNotes(str string, degrees map[string]int, scale string, baseNote int) []int {
+	result := []int{}
+	position := 0
+	stack := []int{}
+	for _, c := range str {
+		symbol := string(c)
+		if d, ok := degrees[symbol]; ok {
+			result = append(result, theory.ScaleDegreeToNoteInt(baseNote, scale, position+d))
+		} else if symbol == "+" {
+			position++
+		} else if symbol == "-" {
+			position--
+		} else if symbol == "[" {
+			stack = append(stack, position)
+		} else if symbol == "]" && len(stack) > 0 {
+			position = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return result
+}
+
+// IntScaleRandomWalkAutomation takes a random step of at most maxStep scale
+// degrees every time the counter increases, staying between the minDegree
+// and maxDegree of the scale built on baseNote.
+func IntScaleRandomWalkAutomation(scale string, baseNote, start, maxStep, minDegree, maxDegree int) IntAutomation {
+	if minDegree > maxDegree {
+		minDegree, maxDegree = maxDegree, minDegree
+	}
+	if maxStep < 1 {
+		maxStep = 1
+	}
+	st := &stepper{}
+	degree := start
+	return func(s *Status, counter, t uint) int {
+		restart, step := st.advance(counter)
+		if restart {
+			degree = start
+		} else if step {
+			degree += random(s).Intn(2*maxStep+1) - maxStep
+			if degree < minDegree {
+				degree = 2*minDegree - degree
+			}
+			if degree > maxDegree {
+				degree = 2*maxDegree - degree
+			}
+			if degree < minDegree {
+				degree = minDegree
+			} else if degree > maxDegree {
+				degree = maxDegree
+			}
+		}
+		return theory.ScaleDegreeToNoteInt(baseNote, scale, degree)
+	}
+}
+
+// sortedKeys is used to get a deterministic state order from YAML maps.
+func sortedKeys(m map[int]map[int]float64) []int {
+	keys := []int{}
+	for k, targets := range m {
+		keys = append(keys, k)
+		for t := range targets {
+			keys = append(keys, t)
+		}
+	}
+	sort.Ints(keys)
+	result := []int{}
+	for i, k := range keys {
+		if i == 0 || keys[i-1] != k {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+// MarkovChainFromTable creates a chain from a transition table that maps a
+// value to the relative weights of the values that can follow it. It returns
+// the chain's states alongside the chain.
+func MarkovChainFromTable(table map[int]map[int]float64) ([]int, *MarkovChain) {
+	values := sortedKeys(table)
+	index := map[int]int{}
+	for i, v := range values {
+		index[v] = i
+	}
+	chain := NewMarkovChain(len(values))
+	for from, targets := range table {
+		for to, w := range targets {
+			chain.Transitions[index[from]][index[to]] = w
+		}
+	}
+	return values, chain
+}
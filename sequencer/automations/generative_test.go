@@ -0,0 +1,108 @@
+package automations
+
+import (
+	"testing"
+
+	. "github.com/bspaans/bleep/sequencer/status"
+)
+
+func Test_LearnMarkovChain(t *testing.T) {
+	values, chain := LearnMarkovChain([][]int{{60}, {62}, {60}, {64}, {60}, {62}})
+	if len(values) != 3 {
+		t.Fatalf("Expecting 3 states, got %v", values)
+	}
+	if values[0][0] != 60 || values[1][0] != 62 || values[2][0] != 64 {
+		t.Errorf("Expecting states in order of appearance, got %v", values)
+	}
+	if chain.Transitions[0][1] != 2.0 || chain.Transitions[0][2] != 1.0 || chain.Transitions[1][0] != 1.0 {
+		t.Errorf("Unexpected transitions %v", chain.Transitions)
+	}
+}
+
+func Test_IntMarkovAutomation(t *testing.T) {
+	values, chain := MarkovChainFromTable(map[int]map[int]float64{
+		60: {62: 1.0},
+		62: {64: 1.0},
+		64: {60: 1.0},
+	})
+	status := NewStatus(120, 4)
+	unit := IntMarkovAutomation(chain, values, 0)
+	expected := []int{60, 62, 64, 60, 62}
+	for i, e := range expected {
+		if got := unit(&status, uint(i), 0); got != e {
+			t.Errorf("Expecting %d at step %d, got %d", e, i, got)
+		}
+	}
+	if got := unit(&status, 4, 0); got != 62 {
+		t.Errorf("Expecting the same value for the same counter, got %d", got)
+	}
+	if got := unit(&status, 0, 0); got != 60 {
+		t.Errorf("Expecting the chain to restart, got %d", got)
+	}
+}
+
+func Test_IntMarkovAutomation_dead_end(t *testing.T) {
+	values, chain := MarkovChainFromTable(map[int]map[int]float64{60: {62: 1.0}})
+	status := NewStatus(120, 4)
+	unit := IntMarkovAutomation(chain, values, 0)
+	expected := []int{60, 62, 60, 62}
+	for i, e := range expected {
+		if got := unit(&status, uint(i), 0); got != e {
+			t.Errorf("Expecting %d at step %d, got %d", e, i, got)
+		}
+	}
+}
+
+func Test_LSystem(t *testing.T) {
+	unit := LSystem("A", map[string]string{"A": "AB", "B": "A"}, 4)
+	if unit != "ABAABABA" {
+		t.Errorf("Expecting ABAABABA, got %s", unit)
+	}
+}
+
+func Test_LSystemNotes(t *testing.T) {
+	unit := LSystemNotes("A+B[+A]-A", map[string]int{"A": 0, "B": 2}, "diatonic", 60)
+	expected := []int{60, 65, 64, 60}
+	if len(unit) != len(expected) {
+		t.Fatalf("Expecting %v, got %v", expected, unit)
+	}
+	for i, e := range expected {
+		if unit[i] != e {
+			t.Errorf("Expecting %v, got %v", expected, unit)
+		}
+	}
+}
+
+func Test_LSystemNotes_modes_start_on_the_base_note(t *testing.T) {
+	unit := LSystemNotes("A+A+A", map[string]int{"A": 0}, "dorian", 62)
+	expected := []int{62, 64, 65}
+	for i, e := range expected {
+		if unit[i] != e {
+			t.Fatalf("Expecting %v, got %v", expected, unit)
+		}
+	}
+}
+
+func Test_IntScaleRandomWalkAutomation(t *testing.T) {
+	status := NewStatus(120, 4)
+	status.SetSeed(42)
+	inScale := map[int]bool{}
+	for _, n := range []int{60, 62, 64, 65, 67, 69, 71, 72} {
+		inScale[n] = true
+	}
+	unit := IntScaleRandomWalkAutomation("diatonic", 60, 0, 2, 0, 7)
+	prev := unit(&status, 0, 0)
+	if prev != 60 {
+		t.Errorf("Expecting walk to start on 60, got %d", prev)
+	}
+	for i := uint(1); i < 200; i++ {
+		got := unit(&status, i, 0)
+		if !inScale[got] {
+			t.Fatalf("Expecting note in scale and range, got %d", got)
+		}
+		if got-prev > 4 || prev-got > 4 {
+			t.Fatalf("Expecting steps of at most two degrees, got %d -> %d", prev, got)
+		}
+		prev = got
+	}
+}
@@ -0,0 +1,163 @@
+package sequencer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bspaans/bleep/midi"
+	"github.com/bspaans/bleep/sequencer/sequences"
+	"github.com/bspaans/bleep/synth"
+)
+
+type clockMessage struct {
+	Message      midi.ClockMessage
+	SongPosition int
+}
+
+// The number of MIDI clock pulses in the first `ticks` sequencer ticks.
+func clockPulsesUntil(ticks, granularity uint) uint {
+	return (ticks*midi.ClocksPerQuarterNote + granularity - 1) / granularity
+}
+
+// The number of sequencer ticks in the first `pulses` MIDI clock pulses.
+func clockTicksUntil(pulses, granularity uint) uint {
+	return pulses * granularity / midi.ClocksPerQuarterNote
+}
+
+// The song position in sixteenth notes.
+func (seq *Sequencer) songPosition() int {
+	return int(seq.Status.Time * 4 / uint(seq.Granularity))
+}
+
+// clockPulseOffsets returns when the MIDI clock pulses in the given tick
+// should be sent, as fractions of the tick.
+func clockPulseOffsets(tick, granularity uint) []float64 {
+	result := []float64{}
+	for p := clockPulsesUntil(tick, granularity); p < clockPulsesUntil(tick+1, granularity); p++ {
+		at := float64(p*granularity) / midi.ClocksPerQuarterNote
+		result = append(result, at-float64(tick))
+	}
+	return result
+}
+
+// sendClockPulses sends the MIDI clock pulses that fall within the current
+// tick, which started at `start` and lasts tickDuration. Every pulse is sent
+// at its own time, so that devices following the clock get an even 24 pulses
+// per quarter note whatever the granularity is. The clock keeps running
+// while the sequencer is stopped, so that other devices can follow the tempo.
+func (seq *Sequencer) sendClockPulses(start time.Time, tickDuration time.Duration) {
+	if seq.ClockOutput == nil {
+		return
+	}
+	for _, offset := range clockPulseOffsets(seq.clockTicks, uint(seq.Granularity)) {
+		at := start.Add(time.Duration(offset * float64(tickDuration)))
+		if wait := time.Until(at); wait > 0 {
+			time.Sleep(wait)
+		}
+		if err := seq.ClockOutput.Pulse(); err != nil {
+			fmt.Println("Failed to send MIDI clock:", err.Error())
+			seq.ClockOutput = nil
+			return
+		}
+	}
+	seq.clockTicks++
+}
+
+// sendTransport tells the devices following the clock about the current
+// position and whether the sequencer is playing. The song position can only
+// be sent while they are stopped, so they are stopped first when the
+// position changes during playback.
+func (seq *Sequencer) sendTransport() {
+	if seq.ClockOutput == nil || seq.ClockSlave {
+		return
+	}
+	if seq.clockRunning {
+		seq.ClockOutput.Stop()
+		seq.clockRunning = false
+	}
+	if !seq.Status.Playing {
+		seq.ClockOutput.SongPosition(seq.songPosition())
+		return
+	}
+	if seq.Status.Time == 0 {
+		seq.ClockOutput.Start()
+	} else {
+		seq.ClockOutput.SongPosition(seq.songPosition())
+		seq.ClockOutput.Continue()
+	}
+	seq.clockRunning = true
+}
+
+// handleClock follows incoming MIDI clock and transport messages in slave
+// mode. Every clock pulse moves the sequencer forward by a 24th of a beat.
+func (seq *Sequencer) handleClock(msg *clockMessage, s chan *synth.Event) {
+	if !seq.ClockSlave {
+		return
+	}
+	switch msg.Message {
+	case midi.ClockStart:
+		seq.Status.ResetTime()
+		seq.clockPulses = 0
+		seq.Status.Playing = true
+	case midi.ClockContinue:
+		seq.Status.Playing = true
+	case midi.ClockStop:
+		if seq.Status.Playing {
+			s <- synth.NewEvent(synth.SilenceAllChannels, 0, nil)
+		}
+		seq.Status.Playing = false
+	case midi.ClockSongPosition:
+		seq.Status.Time = uint(msg.SongPosition) * uint(seq.Granularity) / 4
+		seq.clockPulses = uint(msg.SongPosition) * midi.ClocksPerMIDIBeat
+	case midi.ClockPulse:
+		seq.followClockTempo()
+		if !seq.Status.Playing {
+			return
+		}
+		granularity := uint(seq.Granularity)
+		ticks := clockTicksUntil(seq.clockPulses+1, granularity) - clockTicksUntil(seq.clockPulses, granularity)
+		for i := uint(0); i < ticks; i++ {
+			seq.tick(sequences.ChannelSink(s))
+		}
+		seq.clockPulses++
+	}
+}
+
+// followClockTempo estimates the tempo from the time between clock pulses,
+// so that anything using the BPM (e.g. the arpeggiator and tempo automations)
+// stays in sync.
+func (seq *Sequencer) followClockTempo() {
+	now := time.Now()
+	if !seq.lastClockPulse.IsZero() {
+		interval := now.Sub(seq.lastClockPulse).Seconds()
+		if interval > 0.0 && interval < 0.25 {
+			bpm := 60.0 / (interval * midi.ClocksPerQuarterNote)
+			seq.BPM += (bpm - seq.BPM) * 0.1
+		}
+	}
+	seq.lastClockPulse = now
+}
+
+// Send MIDI clock and transport messages to the output.
+func (seq *Sequencer) SetClockOutput(out *midi.ClockOutput) {
+	ev := NewSequencerEvent(SetClockOutput)
+	ev.Value = out
+	seq.Inputs <- ev
+}
+
+// In slave mode the sequencer doesn't keep its own time, but follows MIDI
+// clock messages passed in with HandleClock.
+func (seq *Sequencer) SetClockSlave(slave bool) {
+	ev := NewSequencerEvent(SetClockSlave)
+	ev.Value = slave
+	seq.Inputs <- ev
+}
+
+func (seq *Sequencer) HandleClock(msg midi.ClockMessage, songPosition int) {
+	ev := NewSequencerEvent(ClockEvent)
+	ev.Value = &clockMessage{
+		Message:      msg,
+		SongPosition: songPosition,
+	}
+	seq.Inputs <- ev
+}
@@ -0,0 +1,155 @@
+package sequencer
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bspaans/bleep/midi"
+	"github.com/bspaans/bleep/synth"
+)
+
+// testOut is a MIDI output port that records the messages sent to it.
+type testOut struct {
+	Sent [][]byte
+}
+
+func (t *testOut) Open() error             { return nil }
+func (t *testOut) Close() error            { return nil }
+func (t *testOut) IsOpen() bool            { return true }
+func (t *testOut) Number() int             { return 0 }
+func (t *testOut) String() string          { return "test" }
+func (t *testOut) Underlying() interface{} { return nil }
+func (t *testOut) Send(b []byte) error {
+	t.Sent = append(t.Sent, b)
+	return nil
+}
+
+func Test_Sequencer_clock_output(t *testing.T) {
+	for _, granularity := range []int{16, 24, 64, 96} {
+		out := &testOut{}
+		seq := NewSequencer(120, granularity)
+		seq.ClockOutput = midi.NewClockOutput(out)
+		for i := 0; i < granularity*2; i++ {
+			seq.sendClockPulses(time.Now(), 0)
+		}
+		if len(out.Sent) != 48 {
+			t.Errorf("Expecting 48 pulses in two beats with granularity %d, got %d", granularity, len(out.Sent))
+		}
+	}
+}
+
+func Test_clockPulseOffsets_are_evenly_spaced(t *testing.T) {
+	for _, granularity := range []uint{16, 24, 64, 96} {
+		pulse := 0
+		for tick := uint(0); tick < granularity*2; tick++ {
+			for _, offset := range clockPulseOffsets(tick, granularity) {
+				if offset < 0 || offset >= 1 {
+					t.Fatalf("Expecting offsets within the tick, got %f", offset)
+				}
+				expected := float64(pulse) * float64(granularity) / 24
+				if math.Abs(float64(tick)+offset-expected) > 1e-9 {
+					t.Fatalf("Expecting pulse %d at tick %f with granularity %d, got %f", pulse, expected, granularity, float64(tick)+offset)
+				}
+				pulse++
+			}
+		}
+		if pulse != 48 {
+			t.Errorf("Expecting 48 pulses in two beats with granularity %d, got %d", granularity, pulse)
+		}
+	}
+}
+
+func Test_Sequencer_sendClockPulses_spreads_pulses_over_the_tick(t *testing.T) {
+	out := &timedOut{}
+	seq := NewSequencer(120, 16)
+	seq.ClockOutput = midi.NewClockOutput(out)
+	start := time.Now()
+	seq.sendClockPulses(start, 30*time.Millisecond)
+	if len(out.At) != 2 {
+		t.Fatalf("Expecting two pulses in the first tick, got %d", len(out.At))
+	}
+	if gap := out.At[1].Sub(out.At[0]); gap < 15*time.Millisecond {
+		t.Errorf("Expecting the second pulse two thirds into the tick, got a gap of %s", gap)
+	}
+}
+
+// timedOut records when messages are sent to it.
+type timedOut struct {
+	testOut
+	At []time.Time
+}
+
+func (t *timedOut) Send(b []byte) error {
+	t.At = append(t.At, time.Now())
+	return t.testOut.Send(b)
+}
+
+func Test_Sequencer_clock_transport(t *testing.T) {
+	out := &testOut{}
+	seq := NewSequencer(120, 64)
+	seq.ClockOutput = midi.NewClockOutput(out)
+	expectSent := func(expected [][]byte) {
+		if len(out.Sent) != len(expected) {
+			t.Fatalf("Expecting %v, got %v", expected, out.Sent)
+		}
+		for i, msg := range expected {
+			if string(out.Sent[i]) != string(msg) {
+				t.Errorf("Expecting %v, got %v", msg, out.Sent[i])
+			}
+		}
+		out.Sent = nil
+	}
+
+	seq.Status.Playing = true
+	seq.Status.Time = 96
+	seq.sendTransport()
+	expectSent([][]byte{{0xF2, 6, 0}, {0xFB}})
+
+	// Moving during playback stops the devices before sending the position
+	seq.Status.Time = 192
+	seq.sendTransport()
+	expectSent([][]byte{{0xFC}, {0xF2, 12, 0}, {0xFB}})
+
+	seq.Status.Playing = false
+	seq.sendTransport()
+	expectSent([][]byte{{0xFC}, {0xF2, 12, 0}})
+
+	// While stopped only the position is sent
+	seq.Status.Time = 0
+	seq.sendTransport()
+	expectSent([][]byte{{0xF2, 0, 0}})
+
+	seq.Status.Playing = true
+	seq.sendTransport()
+	expectSent([][]byte{{0xFA}})
+}
+
+func Test_Sequencer_clock_slave(t *testing.T) {
+	seq := NewSequencer(120, 64)
+	seq.ClockSlave = true
+	s := make(chan *synth.Event, 1024)
+	seq.handleClock(&clockMessage{Message: midi.ClockStart}, s)
+	for i := 0; i < 36; i++ {
+		seq.handleClock(&clockMessage{Message: midi.ClockPulse}, s)
+	}
+	if seq.Status.Time != 96 {
+		t.Errorf("Expecting time 96 after one and a half beats, got %d", seq.Status.Time)
+	}
+	seq.handleClock(&clockMessage{Message: midi.ClockStop}, s)
+	seq.handleClock(&clockMessage{Message: midi.ClockPulse}, s)
+	if seq.Status.Time != 96 {
+		t.Errorf("Expecting time to stand still while stopped, got %d", seq.Status.Time)
+	}
+	seq.handleClock(&clockMessage{Message: midi.ClockSongPosition, SongPosition: 8}, s)
+	if seq.Status.Time != 128 {
+		t.Errorf("Expecting time 128 at song position 8, got %d", seq.Status.Time)
+	}
+	seq.handleClock(&clockMessage{Message: midi.ClockContinue}, s)
+	for i := 0; i < 24; i++ {
+		seq.handleClock(&clockMessage{Message: midi.ClockPulse}, s)
+	}
+	if seq.Status.Time != 192 {
+		t.Errorf("Expecting time 192 one beat after continuing, got %d", seq.Status.Time)
+	}
+}
@@ -0,0 +1,94 @@
+package definitions
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/bspaans/bleep/sequencer/automations"
+	. "github.com/bspaans/bleep/sequencer/sequences"
+	"github.com/bspaans/bleep/util"
+)
+
+// ArpeggioDef plays the notes of an array automation one at a time. Gate is
+// the length of each note as a fraction of the rate.
+type ArpeggioDef struct {
+	Notes              []int                  `json:"notes,omitempty" yaml:"notes,omitempty"`
+	NotesAutomation    *IntArrayAutomationDef `json:"auto_notes,omitempty" yaml:"auto_notes,omitempty"`
+	Channel            int                    `json:"channel" yaml:"channel"`
+	Mode               string                 `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Octaves            int                    `json:"octaves,omitempty" yaml:"octaves,omitempty"`
+	Rate               interface{}            `json:"rate,omitempty" yaml:"rate,omitempty"`
+	Gate               float64                `json:"gate,omitempty" yaml:"gate,omitempty"`
+	Latch              bool                   `json:"latch,omitempty" yaml:"latch,omitempty"`
+	Velocity           int                    `json:"velocity,omitempty" yaml:"velocity,omitempty"`
+	VelocityAutomation *AutomationDef         `json:"auto_velocity,omitempty" yaml:"auto_velocity,omitempty"`
+}
+
+func (e *ArpeggioDef) GetSequence(ctx *context) (Sequence, error) {
+	mode, err := parseArpeggioMode(e.Mode)
+	if err != nil {
+		return nil, err
+	}
+	rate, duration, err := parseArpeggioTiming(e.Rate, e.Gate, ctx.Granularity)
+	if err != nil {
+		return nil, err
+	}
+	notesF := IntArrayIdAutomation(e.Notes)
+	if e.NotesAutomation != nil {
+		notesF_, err := e.NotesAutomation.GetAutomation(ctx)
+		if err != nil {
+			return nil, util.WrapError("auto_notes", err)
+		}
+		notesF = notesF_
+	}
+	velocityF := IntIdAutomation(e.Velocity)
+	if e.VelocityAutomation != nil {
+		velocityF_, err := e.VelocityAutomation.GetAutomation(ctx)
+		if err != nil {
+			return nil, util.WrapError("auto_velocity", err)
+		}
+		velocityF = velocityF_
+	}
+	if e.VelocityAutomation == nil && e.Velocity == 0 {
+		return nil, fmt.Errorf("missing velocity or auto_velocity")
+	}
+	return Arpeggio(e.Channel, rate, duration, mode, e.Octaves, e.Latch, notesF, velocityF), nil
+}
+
+func parseArpeggioMode(mode string) (string, error) {
+	if mode == "" {
+		return "up", nil
+	}
+	for _, m := range ArpeggioModes {
+		if m == mode {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unknown mode '%s'; expecting one of %s", mode, strings.Join(ArpeggioModes, ", "))
+}
+
+// parseArpeggioTiming returns the rate and the note duration in ticks.
+func parseArpeggioTiming(r interface{}, gate float64, granularity int) (uint, uint, error) {
+	rate := Sixteenth(granularity)
+	if r != nil {
+		rate_, err := parseDuration(r, granularity)
+		if err != nil {
+			return 0, 0, util.WrapError("rate", err)
+		}
+		rate = rate_
+	}
+	if rate == 0 {
+		return 0, 0, fmt.Errorf("rate should be at least one tick")
+	}
+	if gate == 0.0 {
+		gate = 0.5
+	}
+	if gate < 0.0 || gate > 1.0 {
+		return 0, 0, fmt.Errorf("gate should be between 0.0 and 1.0, got %v", gate)
+	}
+	duration := uint(gate * float64(rate))
+	if duration == 0 {
+		duration = 1
+	}
+	return rate, duration, nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	. "github.com/bspaans/bleep/sequencer/automations"
+	"github.com/bspaans/bleep/theory"
+	"github.com/bspaans/bleep/util"
 )
 
 type AutomationDef struct {
@@ -17,9 +19,16 @@ type AutomationDef struct {
 	Transpose    *IntTransposeDef `json:"transpose,omitempty" yaml:"transpose,omitempty"`
 	IntConstant  *IntConstantDef  `json:"int_constant,omitempty" yaml:"int_constant,omitempty"`
 	NoteConstant *IntConstantDef  `json:"note_constant,omitempty" yaml:"note_constant,omitempty"`
+	Markov       *MarkovDef       `json:"markov,omitempty" yaml:"markov,omitempty"`
+	LSystem      *LSystemDef      `json:"lsystem,omitempty" yaml:"lsystem,omitempty"`
+	RandomWalk   *RandomWalkDef   `json:"random_walk,omitempty" yaml:"random_walk,omitempty"`
+	Expression   *string          `json:"expr,omitempty" yaml:"expr,omitempty"`
+	Degree       interface{}      `json:"degree,omitempty" yaml:"degree,omitempty"`
+	DegreeCycle  []interface{}    `json:"degree_cycle,omitempty" yaml:"degree_cycle,omitempty"`
+	DegreeRandom *DegreeRandomDef `json:"degree_random,omitempty" yaml:"degree_random,omitempty"`
 }
 
-func (a *AutomationDef) GetAutomation() (IntAutomation, error) {
+func (a *AutomationDef) GetAutomation(ctx *context) (IntAutomation, error) {
 	if a.BackAndForth != nil {
 		return IntBackAndForthAutomation(*a.BackAndForth), nil
 	} else if a.Cycle != nil {
@@ -39,11 +48,56 @@ func (a *AutomationDef) GetAutomation() (IntAutomation, error) {
 	} else if a.NoteConstant != nil {
 		return IntIdAutomation(a.NoteConstant.Value), nil
 	} else if a.Transpose != nil {
-		automation, err := a.Transpose.AutomationDef.GetAutomation()
+		automation, err := a.Transpose.AutomationDef.GetAutomation(ctx)
 		if err != nil {
 			return nil, err
 		}
 		return IntTransposeAutomation(a.Transpose.Transpose, automation), nil
+	} else if a.Markov != nil {
+		automation, err := a.Markov.GetAutomation(ctx)
+		if err != nil {
+			return nil, util.WrapError("markov", err)
+		}
+		return automation, nil
+	} else if a.LSystem != nil {
+		automation, err := a.LSystem.GetAutomation(ctx)
+		if err != nil {
+			return nil, util.WrapError("lsystem", err)
+		}
+		return automation, nil
+	} else if a.RandomWalk != nil {
+		automation, err := a.RandomWalk.GetAutomation(ctx)
+		if err != nil {
+			return nil, util.WrapError("random_walk", err)
+		}
+		return automation, nil
+	} else if a.Expression != nil {
+		automation, err := IntExpressionAutomation(*a.Expression)
+		if err != nil {
+			return nil, util.WrapError("expr", err)
+		}
+		return automation, nil
+	} else if a.Degree != nil {
+		degree, err := theory.DegreeFromValue(a.Degree)
+		if err != nil {
+			return nil, util.WrapError("degree", err)
+		}
+		return IntDegreeAutomation(degree), nil
+	} else if a.DegreeCycle != nil {
+		degrees, err := theory.ParseDegrees(a.DegreeCycle)
+		if err != nil {
+			return nil, util.WrapError("degree_cycle", err)
+		}
+		if len(degrees) == 0 {
+			return nil, util.WrapError("degree_cycle", fmt.Errorf("expecting at least one degree"))
+		}
+		return IntDegreeCycleAutomation(degrees), nil
+	} else if a.DegreeRandom != nil {
+		automation, err := a.DegreeRandom.GetAutomation(ctx)
+		if err != nil {
+			return nil, util.WrapError("degree_random", err)
+		}
+		return automation, nil
 	}
 	return nil, fmt.Errorf("Missing automation")
 }
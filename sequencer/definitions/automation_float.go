@@ -12,6 +12,7 @@ type FloatAutomationDef struct {
 	Register     *int               `json:"register,omitempty" yaml:"register,omitempty"`
 	Transpose    *FloatTransposeDef `json:"transpose,omitempty" yaml:"transpose,omitempty"`
 	Random       *FloatRandomDef    `json:"random,omitempty" yaml:"random,omitempty"`
+	Expression   *string            `json:"expr,omitempty" yaml:"expr,omitempty"`
 }
 
 func (a *FloatAutomationDef) GetAutomation() (FloatAutomation, error) {
@@ -27,6 +28,12 @@ func (a *FloatAutomationDef) GetAutomation() (FloatAutomation, error) {
 			return nil, util.WrapError("transpose", err)
 		}
 		return FloatTransposeAutomation(a.Transpose.Transpose, automation), nil
+	} else if a.Expression != nil {
+		automation, err := CompileExpression(*a.Expression)
+		if err != nil {
+			return nil, util.WrapError("expr", err)
+		}
+		return automation, nil
 	}
 	return nil, fmt.Errorf("Missing automation")
 }
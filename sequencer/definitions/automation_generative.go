@@ -0,0 +1,202 @@
+package definitions
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bspaans/bleep/midi"
+	. "github.com/bspaans/bleep/sequencer/automations"
+	"github.com/bspaans/bleep/theory"
+	"github.com/bspaans/bleep/util"
+)
+
+// The maximum length of an L-system string after rewriting.
+const maxLSystemLength = 1 << 16
+
+// MarkovDef describes a Markov chain over notes. The transition table maps a
+// note to the relative weights of the notes that can follow it. Instead of a
+// table a MIDI file can be given to learn the transitions from; the highest
+// note of every onset in the file is used. Relative file paths are resolved
+// against the directory of the sequencer definition.
+type MarkovDef struct {
+	Transitions map[int]map[int]float64 `json:"transitions,omitempty" yaml:"transitions,omitempty"`
+	File        string                  `json:"file,omitempty" yaml:"file,omitempty"`
+	Channels    []int                   `json:"channels,omitempty" yaml:"channels,omitempty"`
+	Start       *int                    `json:"start,omitempty" yaml:"start,omitempty"`
+}
+
+func (m *MarkovDef) GetAutomation(ctx *context) (IntAutomation, error) {
+	var values []int
+	var chain *MarkovChain
+	start := 0
+	if m.File != "" && m.Transitions != nil {
+		return nil, fmt.Errorf("expecting either 'transitions' or 'file', not both")
+	} else if m.File != "" {
+		notes, err := readMarkovFile(ctx, m.File, m.Channels)
+		if err != nil {
+			return nil, err
+		}
+		melody := make([][]int, len(notes))
+		for i, n := range notes {
+			melody[i] = []int{n[len(n)-1]}
+		}
+		states, chain_ := LearnMarkovChain(melody)
+		for _, s := range states {
+			values = append(values, s[0])
+		}
+		chain = chain_
+	} else if m.Transitions != nil {
+		values, chain = MarkovChainFromTable(m.Transitions)
+	} else {
+		return nil, fmt.Errorf("missing 'transitions' or 'file'")
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("expecting at least one state")
+	}
+	if m.Start != nil {
+		start = -1
+		for i, v := range values {
+			if v == *m.Start {
+				start = i
+			}
+		}
+		if start < 0 {
+			return nil, fmt.Errorf("start note %d is not in the chain", *m.Start)
+		}
+	}
+	return IntMarkovAutomation(chain, values, start), nil
+}
+
+// MarkovArrayDef describes a Markov chain over arrays, e.g. chords. The
+// transition table refers to the states by their index. When learning from a
+// MIDI file, all the notes starting at the same time make up a state.
+type MarkovArrayDef struct {
+	States      [][]int                 `json:"states,omitempty" yaml:"states,omitempty"`
+	Transitions map[int]map[int]float64 `json:"transitions,omitempty" yaml:"transitions,omitempty"`
+	File        string                  `json:"file,omitempty" yaml:"file,omitempty"`
+	Channels    []int                   `json:"channels,omitempty" yaml:"channels,omitempty"`
+	Start       int                     `json:"start,omitempty" yaml:"start,omitempty"`
+}
+
+func (m *MarkovArrayDef) GetAutomation(ctx *context) (IntArrayAutomation, error) {
+	var values [][]int
+	var chain *MarkovChain
+	if m.File != "" && (m.States != nil || m.Transitions != nil) {
+		return nil, fmt.Errorf("expecting either 'states' and 'transitions' or 'file', not both")
+	} else if m.File != "" {
+		notes, err := readMarkovFile(ctx, m.File, m.Channels)
+		if err != nil {
+			return nil, err
+		}
+		values, chain = LearnMarkovChain(notes)
+	} else {
+		if len(m.States) == 0 {
+			return nil, fmt.Errorf("missing 'states'")
+		}
+		values = m.States
+		chain = NewMarkovChain(len(values))
+		for from, targets := range m.Transitions {
+			for to, w := range targets {
+				if from < 0 || from >= len(values) || to < 0 || to >= len(values) {
+					return nil, util.WrapError("transitions", fmt.Errorf("unknown state in transition %d -> %d", from, to))
+				}
+				chain.Transitions[from][to] = w
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("expecting at least one state")
+	}
+	if m.Start < 0 || m.Start >= len(values) {
+		return nil, fmt.Errorf("start state %d out of range", m.Start)
+	}
+	return IntArrayMarkovAutomation(chain, values, m.Start), nil
+}
+
+func readMarkovFile(ctx *context, file string, channels []int) ([][]int, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(ctx.BaseDir, file)
+	}
+	seqs, err := midi.ReadMidiFile(file)
+	if err != nil {
+		return nil, util.WrapError("file", err)
+	}
+	notes := seqs.Notes(channels)
+	if len(notes) == 0 {
+		return nil, util.WrapError("file", fmt.Errorf("no notes found in '%s'", file))
+	}
+	return notes, nil
+}
+
+// LSystemDef rewrites the axiom using the rules and maps the resulting
+// symbols to scale degrees (see automations.LSystemNotes).
+type LSystemDef struct {
+	Axiom      string            `json:"axiom" yaml:"axiom"`
+	Rules      map[string]string `json:"rules" yaml:"rules"`
+	Iterations int               `json:"iterations" yaml:"iterations"`
+	Degrees    map[string]int    `json:"degrees" yaml:"degrees"`
+	Scale      string            `json:"scale" yaml:"scale"`
+	BaseNote   int               `json:"base_note" yaml:"base_note"`
+}
+
+func (l *LSystemDef) GetNotes() ([]int, error) {
+	if l.Axiom == "" {
+		return nil, fmt.Errorf("missing axiom")
+	}
+	if _, ok := theory.Scales[l.Scale]; !ok {
+		return nil, fmt.Errorf("unknown scale '%s'", l.Scale)
+	}
+	for symbol := range l.Rules {
+		if len([]rune(symbol)) != 1 {
+			return nil, util.WrapError("rules", fmt.Errorf("expecting a single character symbol, got '%s'", symbol))
+		}
+	}
+	str := l.Axiom
+	for i := 0; i < l.Iterations; i++ {
+		str = LSystem(str, l.Rules, 1)
+		if len(str) > maxLSystemLength {
+			return nil, fmt.Errorf("string grows beyond %d symbols after %d iterations", maxLSystemLength, i+1)
+		}
+	}
+	notes := LSystemNotes(str, l.Degrees, l.Scale, l.BaseNote)
+	if len(notes) == 0 {
+		return nil, fmt.Errorf("the L-system doesn't produce any notes; are the degrees set?")
+	}
+	return notes, nil
+}
+
+func (l *LSystemDef) GetAutomation(ctx *context) (IntAutomation, error) {
+	notes, err := l.GetNotes()
+	if err != nil {
+		return nil, err
+	}
+	return IntCycleAutomation(notes), nil
+}
+
+// RandomWalkDef walks randomly over the degrees of a scale, taking steps of
+// at most max_step degrees and staying between min and max.
+type RandomWalkDef struct {
+	Scale    string `json:"scale" yaml:"scale"`
+	BaseNote int    `json:"base_note" yaml:"base_note"`
+	Start    int    `json:"start,omitempty" yaml:"start,omitempty"`
+	MaxStep  int    `json:"max_step,omitempty" yaml:"max_step,omitempty"`
+	Min      int    `json:"min" yaml:"min"`
+	Max      int    `json:"max" yaml:"max"`
+}
+
+func (r *RandomWalkDef) GetAutomation(ctx *context) (IntAutomation, error) {
+	if _, ok := theory.Scales[r.Scale]; !ok {
+		return nil, fmt.Errorf("unknown scale '%s'", r.Scale)
+	}
+	if r.Min > r.Max {
+		return nil, fmt.Errorf("min should be smaller than max")
+	}
+	if r.Start < r.Min || r.Start > r.Max {
+		return nil, fmt.Errorf("start should be between min and max")
+	}
+	maxStep := r.MaxStep
+	if maxStep == 0 {
+		maxStep = 1
+	}
+	return IntScaleRandomWalkAutomation(r.Scale, r.BaseNote, r.Start, maxStep, r.Min, r.Max), nil
+}
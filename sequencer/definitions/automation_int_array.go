@@ -19,54 +19,141 @@ type IntArrayAutomationDef struct {
 	Chord         *ChordDef             `json:"chord,omitempty" yaml:"chord,omitempty"`
 	Scale         *ScaleDef             `json:"scale,omitempty" yaml:"scale,omitempty"`
 	ChordOnScale  *ChordOnScaleDef      `json:"chord_on_scale,omitempty" yaml:"chord_on_scale,omitempty"`
+	Markov        *MarkovArrayDef       `json:"markov,omitempty" yaml:"markov,omitempty"`
+	LSystem       *LSystemDef           `json:"lsystem,omitempty" yaml:"lsystem,omitempty"`
+	Degree        []interface{}         `json:"degree,omitempty" yaml:"degree,omitempty"`
 }
 
-func (a *IntArrayAutomationDef) GetAutomation() (IntArrayAutomation, error) {
+func (a *IntArrayAutomationDef) GetAutomation(ctx *context) (IntArrayAutomation, error) {
 	if a.Register != nil {
 		return IntArrayRegisterAutomation(*a.Register), nil
 	} else if a.CycleChords != nil {
-		return a.CycleChords.GetAutomation()
+		return a.CycleChords.GetAutomation(ctx)
 	} else if a.Chord != nil {
-		return a.Chord.GetAutomation()
+		return a.Chord.GetAutomation(ctx)
 	} else if a.Scale != nil {
-		return a.Scale.GetAutomation()
+		return a.Scale.GetAutomation(ctx)
 	} else if a.ChordOnScale != nil {
-		return a.ChordOnScale.GetAutomation()
+		return a.ChordOnScale.GetAutomation(ctx)
 	} else if a.Constant != nil {
 		return IntArrayIdAutomation(a.Constant.Value), nil
 	} else if a.NotesConstant != nil {
 		return IntArrayIdAutomation(a.NotesConstant.Value), nil
 	} else if a.Transpose != nil {
-		automation, err := a.Transpose.IntArrayAutomationDef.GetAutomation()
+		automation, err := a.Transpose.IntArrayAutomationDef.GetAutomation(ctx)
 		if err != nil {
 			return nil, err
 		}
 		return IntArrayTransposeAutomation(a.Transpose.Transpose, automation), nil
 	} else if a.Index != nil {
-		automation, err := a.Index.IntArrayAutomationDef.GetAutomation()
+		automation, err := a.Index.IntArrayAutomationDef.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("index", err)
 		}
 		indexF := IntIdAutomation(a.Index.Index)
 		if a.Index.AutoIndex != nil {
-			indexF_, err := a.Index.AutoIndex.GetAutomation()
+			indexF_, err := a.Index.AutoIndex.GetAutomation(ctx)
 			if err != nil {
 				return nil, util.WrapError("index > auto_value", err)
 			}
 			indexF = indexF_
 		}
 		return IntArrayIndexAutomation(indexF, automation), nil
+	} else if a.Markov != nil {
+		automation, err := a.Markov.GetAutomation(ctx)
+		if err != nil {
+			return nil, util.WrapError("markov", err)
+		}
+		return automation, nil
+	} else if a.LSystem != nil {
+		notes, err := a.LSystem.GetNotes()
+		if err != nil {
+			return nil, util.WrapError("lsystem", err)
+		}
+		return IntArrayIdAutomation(notes), nil
+	} else if a.Degree != nil {
+		degrees, err := theory.ParseDegrees(a.Degree)
+		if err != nil {
+			return nil, util.WrapError("degree", err)
+		}
+		return IntArrayDegreeAutomation(degrees), nil
 	}
 	return nil, fmt.Errorf("Missing array automation")
 }
 
+// CycleChordsDef cycles through chords, changing every `count` steps. The
+// chords are either given as notes, as chord symbols (e.g. "F#m7b5/E") with
+// the roots in octave 4, or as a roman numeral progression (e.g. "ii7",
+// "V7", "Imaj7"). Without a key the progression follows the sequencer's
+// current key and scale. With voice_leading every chord is voiced as close as
+// possible to the chord before it.
 type CycleChordsDef struct {
-	Count  int     `json:"count" yaml:"count"`
-	Chords [][]int `json:"chords" yaml:"chords"`
+	Count        int         `json:"count" yaml:"count"`
+	Chords       [][]int     `json:"chords,omitempty" yaml:"chords,omitempty"`
+	Symbols      []string    `json:"symbols,omitempty" yaml:"symbols,omitempty"`
+	Progression  []string    `json:"progression,omitempty" yaml:"progression,omitempty"`
+	Key          interface{} `json:"key,omitempty" yaml:"key,omitempty"`
+	Scale        string      `json:"scale,omitempty" yaml:"scale,omitempty"`
+	VoiceLeading bool        `json:"voice_leading,omitempty" yaml:"voice_leading,omitempty"`
 }
 
-func (c *CycleChordsDef) GetAutomation() (IntArrayAutomation, error) {
-	return ChordCycleArrayAutomation(c.Count, c.Chords), nil
+func (c *CycleChordsDef) GetAutomation(ctx *context) (IntArrayAutomation, error) {
+	count := c.Count
+	if count == 0 {
+		count = 1
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("count should be positive, got %d", count)
+	}
+	given := 0
+	for _, l := range []int{len(c.Chords), len(c.Symbols), len(c.Progression)} {
+		if l > 0 {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, fmt.Errorf("expecting one of 'chords', 'symbols' or 'progression'")
+	}
+	if (c.Key != nil || c.Scale != "") && len(c.Progression) == 0 {
+		return nil, fmt.Errorf("'key' and 'scale' can only be used with 'progression'")
+	}
+	if err := validateScale(c.Scale); err != nil {
+		return nil, err
+	}
+	chords := c.Chords
+	if len(c.Symbols) > 0 {
+		chords_, err := theory.ParseChordSymbols(c.Symbols, 4)
+		if err != nil {
+			return nil, util.WrapError("symbols", err)
+		}
+		chords = chords_
+	} else if len(c.Progression) > 0 {
+		numerals, err := theory.ParseRomanNumerals(c.Progression)
+		if err != nil {
+			return nil, util.WrapError("progression", err)
+		}
+		if c.Key == nil && c.Scale == "" {
+			return RomanNumeralCycleAutomation(count, numerals, c.VoiceLeading), nil
+		}
+		key, scale := 60, c.Scale
+		if c.Key != nil {
+			key, err = theory.ParseKey(c.Key)
+			if err != nil {
+				return nil, util.WrapError("key", err)
+			}
+		}
+		if scale == "" {
+			scale = "ionian"
+		}
+		chords = make([][]int, len(numerals))
+		for i, n := range numerals {
+			chords[i] = n.OnKey(key, scale)
+		}
+	}
+	if c.VoiceLeading {
+		chords = theory.VoiceLeadProgression(chords)
+	}
+	return ChordCycleArrayAutomation(count, chords), nil
 }
 
 type ScaleDef struct {
@@ -83,7 +170,7 @@ func (c *ScaleDef) IsConstant() bool {
 	return c.BaseNoteAutomation == nil && c.OctavesAutomation == nil && c.InversionsAutomation == nil
 }
 
-func (c *ScaleDef) GetAutomation() (IntArrayAutomation, error) {
+func (c *ScaleDef) GetAutomation(ctx *context) (IntArrayAutomation, error) {
 	if c.IsConstant() {
 		baseValues := theory.ScaleOnNoteInt(c.BaseNote, c.Scale)
 		baseValues = theory.InvertChord(baseValues, c.Inversions)
@@ -102,7 +189,7 @@ func (c *ScaleDef) GetAutomation() (IntArrayAutomation, error) {
 	}
 	var noteA, octavesA, inversionsA IntAutomation
 	if c.BaseNoteAutomation != nil {
-		noteA_, err := c.BaseNoteAutomation.GetAutomation()
+		noteA_, err := c.BaseNoteAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("auto_base_note", err)
 		}
@@ -111,7 +198,7 @@ func (c *ScaleDef) GetAutomation() (IntArrayAutomation, error) {
 		noteA = IntIdAutomation(c.BaseNote)
 	}
 	if c.OctavesAutomation != nil {
-		octavesA_, err := c.OctavesAutomation.GetAutomation()
+		octavesA_, err := c.OctavesAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("auto_octaves", err)
 		}
@@ -120,7 +207,7 @@ func (c *ScaleDef) GetAutomation() (IntArrayAutomation, error) {
 		octavesA = IntIdAutomation(c.Octaves)
 	}
 	if c.InversionsAutomation != nil {
-		inversionsA_, err := c.InversionsAutomation.GetAutomation()
+		inversionsA_, err := c.InversionsAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("auto_inversions", err)
 		}
@@ -142,17 +229,17 @@ type ChordOnScaleDef struct {
 	InversionsAutomation *AutomationDef         `json:"auto_inversions,omitempty" yaml:"auto_inversions,omitempty"`
 }
 
-func (c *ChordOnScaleDef) GetAutomation() (IntArrayAutomation, error) {
+func (c *ChordOnScaleDef) GetAutomation(ctx *context) (IntArrayAutomation, error) {
 	if c.Scale == nil {
 		return nil, util.WrapError("scale", errors.New("Missing scale"))
 	}
-	scaleF, err := c.Scale.GetAutomation()
+	scaleF, err := c.Scale.GetAutomation(ctx)
 	if err != nil {
 		return nil, util.WrapError("scale", err)
 	}
 	var startA, octavesA, inversionsA IntAutomation
 	if c.StartAutomation != nil {
-		startA_, err := c.StartAutomation.GetAutomation()
+		startA_, err := c.StartAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("auto_start", err)
 		}
@@ -161,7 +248,7 @@ func (c *ChordOnScaleDef) GetAutomation() (IntArrayAutomation, error) {
 		startA = IntIdAutomation(c.Start)
 	}
 	if c.OctavesAutomation != nil {
-		octavesA_, err := c.OctavesAutomation.GetAutomation()
+		octavesA_, err := c.OctavesAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("auto_octaves", err)
 		}
@@ -170,7 +257,7 @@ func (c *ChordOnScaleDef) GetAutomation() (IntArrayAutomation, error) {
 		octavesA = IntIdAutomation(c.Octaves)
 	}
 	if c.InversionsAutomation != nil {
-		inversionsA_, err := c.InversionsAutomation.GetAutomation()
+		inversionsA_, err := c.InversionsAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("auto_inversions", err)
 		}
@@ -195,9 +282,12 @@ func (c *ChordDef) IsConstant() bool {
 	return c.BaseNoteAutomation == nil && c.OctavesAutomation == nil && c.InversionsAutomation == nil
 }
 
-func (c *ChordDef) GetAutomation() (IntArrayAutomation, error) {
+func (c *ChordDef) GetAutomation(ctx *context) (IntArrayAutomation, error) {
+	if _, err := theory.ChordOnNoteInt(c.BaseNote, c.Chord); err != nil {
+		return nil, util.WrapError("chord", err)
+	}
 	if c.IsConstant() {
-		baseValues := theory.ChordOnNoteInt(c.BaseNote, c.Chord)
+		baseValues, _ := theory.ChordOnNoteInt(c.BaseNote, c.Chord)
 		baseValues = theory.InvertChord(baseValues, c.Inversions)
 		octaves := c.Octaves
 		values := []int{}
@@ -214,7 +304,7 @@ func (c *ChordDef) GetAutomation() (IntArrayAutomation, error) {
 	}
 	var noteA, octavesA, inversionsA IntAutomation
 	if c.BaseNoteAutomation != nil {
-		noteA_, err := c.BaseNoteAutomation.GetAutomation()
+		noteA_, err := c.BaseNoteAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("auto_base_note", err)
 		}
@@ -223,7 +313,7 @@ func (c *ChordDef) GetAutomation() (IntArrayAutomation, error) {
 		noteA = IntIdAutomation(c.BaseNote)
 	}
 	if c.OctavesAutomation != nil {
-		octavesA_, err := c.OctavesAutomation.GetAutomation()
+		octavesA_, err := c.OctavesAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("auto_octaves", err)
 		}
@@ -232,7 +322,7 @@ func (c *ChordDef) GetAutomation() (IntArrayAutomation, error) {
 		octavesA = IntIdAutomation(c.Octaves)
 	}
 	if c.InversionsAutomation != nil {
-		inversionsA_, err := c.InversionsAutomation.GetAutomation()
+		inversionsA_, err := c.InversionsAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("auto_inversions", err)
 		}
@@ -0,0 +1,134 @@
+package definitions
+
+import (
+	"fmt"
+
+	. "github.com/bspaans/bleep/sequencer/automations"
+	. "github.com/bspaans/bleep/sequencer/sequences"
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/util"
+)
+
+// IfDef plays `then` while the condition holds and `else` otherwise. The
+// condition either compares an int register with a value, or is an
+// expression (see the expr automation) that holds when it's non-zero.
+type IfDef struct {
+	Register   *int         `json:"register,omitempty" yaml:"register,omitempty"`
+	Operator   string       `json:"operator,omitempty" yaml:"operator,omitempty"`
+	Value      int          `json:"value,omitempty" yaml:"value,omitempty"`
+	Expression string       `json:"expr,omitempty" yaml:"expr,omitempty"`
+	Then       *SequenceDef `json:"then" yaml:"then"`
+	Else       *SequenceDef `json:"else,omitempty" yaml:"else,omitempty"`
+}
+
+func (e *IfDef) GetSequence(ctx *context) (Sequence, error) {
+	condition, err := e.getCondition()
+	if err != nil {
+		return nil, err
+	}
+	then, err := e.Then.GetSequence(ctx)
+	if err != nil {
+		return nil, util.WrapError("then", err)
+	}
+	var otherwise Sequence
+	if e.Else != nil {
+		otherwise, err = e.Else.GetSequence(ctx)
+		if err != nil {
+			return nil, util.WrapError("else", err)
+		}
+	}
+	return If(condition, then, otherwise), nil
+}
+
+func (e *IfDef) getCondition() (func(status *Status, t uint) bool, error) {
+	if e.Register != nil && e.Expression != "" {
+		return nil, fmt.Errorf("expecting either 'register' or 'expr', not both")
+	} else if e.Expression != "" {
+		f, err := CompileExpression(e.Expression)
+		if err != nil {
+			return nil, util.WrapError("expr", err)
+		}
+		return func(status *Status, t uint) bool {
+			return f(status, t, t) != 0.0
+		}, nil
+	} else if e.Register != nil {
+		if err := validateRegister(*e.Register); err != nil {
+			return nil, err
+		}
+		register, value := *e.Register, e.Value
+		operator := e.Operator
+		if operator == "" {
+			operator = "=="
+		}
+		compare, ok := registerComparisons[operator]
+		if !ok {
+			return nil, util.WrapError("operator", fmt.Errorf("unknown operator '%s'", operator))
+		}
+		return func(status *Status, t uint) bool {
+			return compare(status.IntRegisters[register], value)
+		}, nil
+	}
+	return nil, fmt.Errorf("missing 'register' or 'expr'")
+}
+
+var registerComparisons = map[string]func(a, b int) bool{
+	"==": func(a, b int) bool { return a == b },
+	"!=": func(a, b int) bool { return a != b },
+	"<":  func(a, b int) bool { return a < b },
+	"<=": func(a, b int) bool { return a <= b },
+	">":  func(a, b int) bool { return a > b },
+	">=": func(a, b int) bool { return a >= b },
+}
+
+func validateRegister(register int) error {
+	if register < 0 || register > 127 {
+		return util.WrapError("register", fmt.Errorf("expecting a register between 0 and 127, got %d", register))
+	}
+	return nil
+}
+
+// SwitchOnRegisterDef plays the sequence of the case that matches the value
+// of an int register, or the default sequence if none do.
+type SwitchOnRegisterDef struct {
+	Register int                `json:"register" yaml:"register"`
+	Cases    []*RegisterCaseDef `json:"cases" yaml:"cases"`
+	Default  *SequenceDef       `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+type RegisterCaseDef struct {
+	Value    int          `json:"value" yaml:"value"`
+	Sequence *SequenceDef `json:"sequence" yaml:"sequence"`
+}
+
+func (e *SwitchOnRegisterDef) GetSequence(ctx *context) (Sequence, error) {
+	if err := validateRegister(e.Register); err != nil {
+		return nil, err
+	}
+	if len(e.Cases) == 0 {
+		return nil, fmt.Errorf("expecting at least one case")
+	}
+	values := []int{}
+	cases := []Sequence{}
+	seen := map[int]bool{}
+	for i, c := range e.Cases {
+		if seen[c.Value] {
+			return nil, util.WrapError(fmt.Sprintf("cases [%d]", i), fmt.Errorf("duplicate value %d", c.Value))
+		}
+		seen[c.Value] = true
+		s, err := c.Sequence.GetSequence(ctx)
+		if err != nil {
+			return nil, util.WrapError(fmt.Sprintf("cases [%d]", i), err)
+		}
+		values = append(values, c.Value)
+		cases = append(cases, s)
+	}
+	var def Sequence
+	if e.Default != nil {
+		s, err := e.Default.GetSequence(ctx)
+		if err != nil {
+			return nil, util.WrapError("default", err)
+		}
+		def = s
+	}
+	return SwitchOnRegister(e.Register, values, cases, def), nil
+}
@@ -10,8 +10,8 @@ type ChannelAutomationDef struct {
 	AutomationDef `json:",inline" yaml:",inline"`
 }
 
-func (p *ChannelAutomationDef) GetSequence(automation func(int, IntAutomation) Sequence) (Sequence, error) {
-	automationF, err := p.AutomationDef.GetAutomation()
+func (p *ChannelAutomationDef) GetSequence(ctx *context, automation func(int, IntAutomation) Sequence) (Sequence, error) {
+	automationF, err := p.AutomationDef.GetAutomation(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -23,7 +23,7 @@ type FloatChannelAutomationDef struct {
 	FloatAutomationDef `yaml:",inline"`
 }
 
-func (p *FloatChannelAutomationDef) GetSequence(automation func(int, FloatAutomation) Sequence) (Sequence, error) {
+func (p *FloatChannelAutomationDef) GetSequence(ctx *context, automation func(int, FloatAutomation) Sequence) (Sequence, error) {
 	automationF, err := p.FloatAutomationDef.GetAutomation()
 	if err != nil {
 		return nil, err
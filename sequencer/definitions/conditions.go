@@ -0,0 +1,116 @@
+package definitions
+
+import (
+	"fmt"
+
+	. "github.com/bspaans/bleep/sequencer/sequences"
+	"github.com/bspaans/bleep/util"
+)
+
+type ChanceDef struct {
+	Probability float64      `json:"probability" yaml:"probability"`
+	Sequence    *SequenceDef `json:"sequence" yaml:"sequence"`
+}
+
+func (e *ChanceDef) GetSequence(ctx *context) (Sequence, error) {
+	if e.Probability < 0.0 || e.Probability > 1.0 {
+		return nil, fmt.Errorf("probability should be between 0.0 and 1.0, got %v", e.Probability)
+	}
+	s, err := e.Sequence.GetSequence(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Chance(e.Probability, s), nil
+}
+
+type EveryNOfMDef struct {
+	N        int          `json:"n" yaml:"n"`
+	M        int          `json:"m" yaml:"m"`
+	Cycle    interface{}  `json:"cycle,omitempty" yaml:"cycle,omitempty"`
+	Sequence *SequenceDef `json:"sequence" yaml:"sequence"`
+}
+
+func (e *EveryNOfMDef) GetSequence(ctx *context) (Sequence, error) {
+	if e.N < 1 || e.N > e.M {
+		return nil, fmt.Errorf("expecting 1 <= n <= m, got n=%d and m=%d", e.N, e.M)
+	}
+	cycle := Whole(ctx.Granularity)
+	if e.Cycle != nil {
+		cycle_, err := parseDuration(e.Cycle, ctx.Granularity)
+		if err != nil {
+			return nil, util.WrapError("cycle", err)
+		}
+		cycle = cycle_
+	}
+	if cycle == 0 {
+		return nil, fmt.Errorf("cycle should be at least one tick")
+	}
+	s, err := e.Sequence.GetSequence(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return EveryNOfM(e.N, e.M, cycle, s), nil
+}
+
+type FillDef struct {
+	Every    interface{}  `json:"every" yaml:"every"`
+	Length   interface{}  `json:"length,omitempty" yaml:"length,omitempty"`
+	Sequence *SequenceDef `json:"sequence" yaml:"sequence"`
+}
+
+func (e *FillDef) GetSequence(ctx *context) (Sequence, error) {
+	section, err := parseDuration(e.Every, ctx.Granularity)
+	if err != nil {
+		return nil, util.WrapError("every", err)
+	}
+	if section == 0 {
+		return nil, util.WrapError("every", fmt.Errorf("the section should be at least one tick"))
+	}
+	length := Whole(ctx.Granularity)
+	if e.Length != nil {
+		length_, err := parseDuration(e.Length, ctx.Granularity)
+		if err != nil {
+			return nil, util.WrapError("length", err)
+		}
+		length = length_
+	}
+	if length > section {
+		return nil, fmt.Errorf("the fill can't be longer than the section")
+	}
+	s, err := e.Sequence.GetSequence(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Fill(section, length, s), nil
+}
+
+// HumanizeDef adds random timing and velocity changes to a sequence. Timing
+// is the maximum offset as a fraction of a step and should be less than 0.5.
+type HumanizeDef struct {
+	Step     interface{}  `json:"step,omitempty" yaml:"step,omitempty"`
+	Timing   float64      `json:"timing,omitempty" yaml:"timing,omitempty"`
+	Velocity int          `json:"velocity,omitempty" yaml:"velocity,omitempty"`
+	Sequence *SequenceDef `json:"sequence" yaml:"sequence"`
+}
+
+func (e *HumanizeDef) GetSequence(ctx *context) (Sequence, error) {
+	step := Sixteenth(ctx.Granularity)
+	if e.Step != nil {
+		step_, err := parseDuration(e.Step, ctx.Granularity)
+		if err != nil {
+			return nil, util.WrapError("step", err)
+		}
+		step = step_
+	}
+	if step == 0 {
+		return nil, fmt.Errorf("step should be at least one tick")
+	}
+	if e.Timing < 0.0 || e.Timing >= 0.5 {
+		return nil, fmt.Errorf("timing should be between 0.0 and 0.5, got %v", e.Timing)
+	}
+	s, err := e.Sequence.GetSequence(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Humanize(step, e.Timing*float64(step), e.Velocity, s), nil
+}
@@ -0,0 +1,16 @@
+package definitions
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_FillDef_rejects_an_empty_section(t *testing.T) {
+	for _, every := range []interface{}{0, 0.001} {
+		def := &FillDef{Every: every, Length: 0, Sequence: &SequenceDef{}}
+		_, err := def.GetSequence(&context{Granularity: 64})
+		if err == nil || !strings.Contains(err.Error(), "every") {
+			t.Errorf("Expecting an every error for %v, got %v", every, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package definitions
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bspaans/bleep/midi"
+	. "github.com/bspaans/bleep/sequencer/sequences"
+	"github.com/bspaans/bleep/util"
+)
+
+// GrooveDef moves the events of a sequence off the grid. Swing is given as a
+// percentage: 50 is straight time and 66 is roughly triplet swing. A template
+// can add timing (as a fraction of a step) and velocity offsets per step, or
+// extract these from a MIDI file.
+type GrooveDef struct {
+	Swing    float64            `json:"swing,omitempty" yaml:"swing,omitempty"`
+	Step     interface{}        `json:"step,omitempty" yaml:"step,omitempty"`
+	Template *GrooveTemplateDef `json:"template,omitempty" yaml:"template,omitempty"`
+	Sequence *SequenceDef       `json:"sequence" yaml:"sequence"`
+}
+
+type GrooveTemplateDef struct {
+	Timing   []float64 `json:"timing,omitempty" yaml:"timing,omitempty"`
+	Velocity []int     `json:"velocity,omitempty" yaml:"velocity,omitempty"`
+	File     string    `json:"file,omitempty" yaml:"file,omitempty"`
+	Steps    int       `json:"steps,omitempty" yaml:"steps,omitempty"`
+}
+
+func (e *GrooveDef) GetSequence(ctx *context) (Sequence, error) {
+	step := Sixteenth(ctx.Granularity)
+	if e.Step != nil {
+		step_, err := parseDuration(e.Step, ctx.Granularity)
+		if err != nil {
+			return nil, util.WrapError("step", err)
+		}
+		step = step_
+	}
+	if step == 0 {
+		return nil, fmt.Errorf("step should be at least one tick")
+	}
+	groove := &Groove{StepLength: step}
+	if e.Swing != 0.0 {
+		if e.Swing <= 0 || e.Swing >= 100 {
+			return nil, fmt.Errorf("swing should be a percentage between 0 and 100, got %v", e.Swing)
+		}
+		groove = NewSwingGroove(step, e.Swing/100)
+	}
+	if e.Template != nil {
+		template, err := e.Template.GetGroove(ctx, step)
+		if err != nil {
+			return nil, util.WrapError("template", err)
+		}
+		groove = groove.Add(template)
+	}
+	s, err := e.Sequence.GetSequence(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return GrooveSequence(groove, s), nil
+}
+
+func (e *GrooveTemplateDef) GetGroove(ctx *context, step uint) (*Groove, error) {
+	if e.File != "" {
+		steps := e.Steps
+		if steps < 0 {
+			return nil, util.WrapError("steps", fmt.Errorf("expecting a positive number of steps, got %d", steps))
+		} else if steps == 0 {
+			steps = 16
+		}
+		file := e.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(ctx.BaseDir, file)
+		}
+		mid, err := midi.ReadMidiFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return GrooveFromMIDI(mid, ctx.Granularity, step, steps), nil
+	}
+	timing := make([]float64, len(e.Timing))
+	for i, offset := range e.Timing {
+		timing[i] = offset * float64(step)
+	}
+	return &Groove{
+		StepLength: step,
+		Timing:     timing,
+		Velocity:   e.Velocity,
+	}, nil
+}
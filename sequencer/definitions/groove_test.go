@@ -0,0 +1,14 @@
+package definitions
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_GrooveTemplateDef_rejects_negative_steps(t *testing.T) {
+	def := &GrooveTemplateDef{File: "groove.mid", Steps: -1}
+	_, err := def.GetGroove(&context{Granularity: 64}, 16)
+	if err == nil || !strings.Contains(err.Error(), "steps") {
+		t.Errorf("Expecting a steps error, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package definitions
+
+import (
+	"fmt"
+
+	. "github.com/bspaans/bleep/sequencer/automations"
+	. "github.com/bspaans/bleep/sequencer/sequences"
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/theory"
+	"github.com/bspaans/bleep/util"
+)
+
+// KeyDef changes the current key and/or scale. The key is either a note name
+// (e.g. "F#3") or a MIDI note number.
+type KeyDef struct {
+	Key        interface{}    `json:"key,omitempty" yaml:"key,omitempty"`
+	Scale      string         `json:"scale,omitempty" yaml:"scale,omitempty"`
+	Automation *AutomationDef `json:"auto_key,omitempty" yaml:"auto_key,omitempty"`
+}
+
+func (e *KeyDef) GetSequence(ctx *context) (Sequence, error) {
+	if err := validateScale(e.Scale); err != nil {
+		return nil, err
+	}
+	if e.Key != nil && e.Automation != nil {
+		return nil, fmt.Errorf("expecting either 'key' or 'auto_key', not both")
+	}
+	if e.Automation != nil {
+		keyF, err := e.Automation.GetAutomation(ctx)
+		if err != nil {
+			return nil, util.WrapError("auto_key", err)
+		}
+		return KeyAutomation(keyF, e.Scale), nil
+	}
+	if e.Key == nil {
+		if e.Scale == "" {
+			return nil, fmt.Errorf("missing 'key', 'auto_key' or 'scale'")
+		}
+		return KeyAutomation(func(s *Status, counter, t uint) int {
+			return s.Key
+		}, e.Scale), nil
+	}
+	key, err := theory.ParseKey(e.Key)
+	if err != nil {
+		return nil, util.WrapError("key", err)
+	}
+	return KeyAutomation(IntIdAutomation(key), e.Scale), nil
+}
+
+func validateScale(scale string) error {
+	if scale == "" {
+		return nil
+	}
+	if _, ok := theory.Scales[scale]; !ok {
+		return util.WrapError("scale", fmt.Errorf("unknown scale '%s'", scale))
+	}
+	return nil
+}
+
+// GetKey returns the key and scale the sequencer starts in. Defaults to C
+// major.
+func (s *SequencerDef) GetKey() (int, string, error) {
+	key, scale := 60, "ionian"
+	if s.Key != nil {
+		k, err := theory.ParseKey(s.Key)
+		if err != nil {
+			return 0, "", util.WrapError("key", err)
+		}
+		key = k
+	}
+	if s.Scale != "" {
+		if err := validateScale(s.Scale); err != nil {
+			return 0, "", err
+		}
+		scale = s.Scale
+	}
+	return key, scale, nil
+}
+
+type DegreeRandomDef struct {
+	Min int `json:"min" yaml:"min"`
+	Max int `json:"max" yaml:"max"`
+}
+
+func (e *DegreeRandomDef) GetAutomation(ctx *context) (IntAutomation, error) {
+	if e.Min < 1 || e.Max < 1 {
+		return nil, fmt.Errorf("degrees start at 1")
+	}
+	return IntDegreeRandomAutomation(e.Min, e.Max), nil
+}
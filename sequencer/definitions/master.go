@@ -0,0 +1,61 @@
+package definitions
+
+import (
+	"github.com/bspaans/bleep/instruments"
+	"github.com/bspaans/bleep/synth"
+	"github.com/bspaans/bleep/util"
+)
+
+// MasterDef configures the processing of the mixed output. For example:
+//
+//	master:
+//	  compressor:
+//	    threshold: -18
+//	    ratio: 2
+//	  limiter:
+//	    ceiling: -0.3
+//	  dither:
+//	    noise_shaping: true
+type MasterDef struct {
+	Compressor *instruments.DynamicsOptionsDef `json:"compressor,omitempty" yaml:"compressor,omitempty"`
+	Limiter    *instruments.LimiterOptionsDef  `json:"limiter,omitempty" yaml:"limiter,omitempty"`
+	Dither     *DitherDef                      `json:"dither,omitempty" yaml:"dither,omitempty"`
+}
+
+// DitherDef enables dither when converting to the output bit depth.
+type DitherDef struct {
+	NoiseShaping bool `json:"noise_shaping,omitempty" yaml:"noise_shaping,omitempty"`
+}
+
+func (m *MasterDef) Validate() error {
+	if m.Compressor != nil {
+		if err := m.Compressor.ValidateWithoutSidechain(); err != nil {
+			return util.WrapError("compressor", err)
+		}
+	}
+	if m.Limiter != nil {
+		if err := m.Limiter.Validate(); err != nil {
+			return util.WrapError("limiter", err)
+		}
+	}
+	return nil
+}
+
+// GetMasterBus returns a new master bus. The filters keep state, so every
+// synthesizer needs its own.
+func (m *MasterDef) GetMasterBus() (*synth.MasterBus, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	bus := &synth.MasterBus{}
+	if m.Compressor != nil {
+		bus.Compressor = m.Compressor.CompressorFilter()
+	}
+	if m.Limiter != nil {
+		bus.Limiter = m.Limiter.Filter()
+	}
+	if m.Dither != nil {
+		bus.Dither = synth.NewDither(m.Dither.NoiseShaping)
+	}
+	return bus, nil
+}
@@ -13,6 +13,7 @@ type MIDISequencesDef struct {
 	OutputChannels []int   `yaml:"output_channels"`
 	Speed          float64 `yaml:"speed"`
 	Loop           bool    `yaml:"loop"`
+	FollowTempo    bool    `yaml:"follow_tempo"`
 }
 
 func (m *MIDISequencesDef) GetSequence(ctx *context) (sequences.Sequence, error) {
@@ -24,5 +25,5 @@ func (m *MIDISequencesDef) GetSequence(ctx *context) (sequences.Sequence, error)
 	if err != nil {
 		return nil, err
 	}
-	return sequences.MidiSequence(seqs, m.InputChannels, m.OutputChannels, m.Speed, m.Loop), nil
+	return sequences.MidiSequence(seqs, m.InputChannels, m.OutputChannels, m.Speed, m.Loop, m.FollowTempo), nil
 }
@@ -0,0 +1,66 @@
+package definitions
+
+import (
+	"fmt"
+
+	"github.com/bspaans/bleep/synth"
+	"github.com/bspaans/bleep/util"
+)
+
+// MPEDef configures the MPE zones. The lower zone's master channel is 0 and
+// the upper zone's master channel is 15; each zone is configured with the
+// number of member channels it has. The pitch bend ranges default to 48
+// semitones for member channels and 2 for master channels.
+type MPEDef struct {
+	LowerZone            int     `json:"lower_zone,omitempty" yaml:"lower_zone,omitempty"`
+	UpperZone            int     `json:"upper_zone,omitempty" yaml:"upper_zone,omitempty"`
+	PitchbendRange       float64 `json:"pitchbend_range,omitempty" yaml:"pitchbend_range,omitempty"`
+	MasterPitchbendRange float64 `json:"master_pitchbend_range,omitempty" yaml:"master_pitchbend_range,omitempty"`
+}
+
+func (m *MPEDef) Validate() error {
+	if m.LowerZone < 0 || m.LowerZone > 15 {
+		return util.WrapError("lower_zone", fmt.Errorf("expecting a value between 0 and 15"))
+	}
+	if m.UpperZone < 0 || m.UpperZone > 15 {
+		return util.WrapError("upper_zone", fmt.Errorf("expecting a value between 0 and 15"))
+	}
+	if m.LowerZone > 0 && m.UpperZone > 0 && m.LowerZone+m.UpperZone > 14 {
+		return fmt.Errorf("the lower and upper zone can have at most 14 member channels together")
+	}
+	if m.PitchbendRange < 0 || m.MasterPitchbendRange < 0 {
+		return fmt.Errorf("the pitch bend range can't be negative")
+	}
+	return nil
+}
+
+// GetEvents returns the synth events that set up the zones.
+func (m *MPEDef) GetEvents() ([]*synth.Event, error) {
+	if err := m.Validate(); err != nil {
+		return nil, util.WrapError("mpe", err)
+	}
+	result := []*synth.Event{}
+	zones := []*synth.MPEZone{}
+	if m.LowerZone > 0 {
+		zones = append(zones, &synth.MPEZone{Master: 0, Members: m.LowerZone})
+	}
+	if m.UpperZone > 0 {
+		zones = append(zones, &synth.MPEZone{Master: 15, Members: m.UpperZone})
+	}
+	for _, zone := range zones {
+		result = append(result, synth.NewEvent(synth.SetMPEZone, zone.Master, []int{zone.Members}))
+		if m.MasterPitchbendRange != 0.0 {
+			result = append(result, synth.NewFloatEvent(synth.SetPitchbendRange, zone.Master, []float64{m.MasterPitchbendRange}))
+		}
+		if m.PitchbendRange != 0.0 {
+			// The range is set for the whole zone when it's sent to one of
+			// its members.
+			member := 1
+			if zone.Master == 15 {
+				member = 14
+			}
+			result = append(result, synth.NewFloatEvent(synth.SetPitchbendRange, member, []float64{m.PitchbendRange}))
+		}
+	}
+	return result, nil
+}
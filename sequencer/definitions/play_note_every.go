@@ -34,7 +34,7 @@ func (e *PlayNoteEveryDef) GetSequence(ctx *context) (Sequence, error) {
 	}
 	noteF := IntIdAutomation(e.Note)
 	if e.NoteAutomation != nil {
-		noteF_, err := e.NoteAutomation.GetAutomation()
+		noteF_, err := e.NoteAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("play_note > auto_note", err)
 		}
@@ -45,7 +45,7 @@ func (e *PlayNoteEveryDef) GetSequence(ctx *context) (Sequence, error) {
 	}
 	velocityF := IntIdAutomation(e.Velocity)
 	if e.VelocityAutomation != nil {
-		velocityF_, err := e.VelocityAutomation.GetAutomation()
+		velocityF_, err := e.VelocityAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("play_note > auto_velocity", err)
 		}
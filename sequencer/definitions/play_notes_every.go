@@ -33,7 +33,7 @@ func (e *PlayNotesEveryDef) GetSequence(ctx *context) (Sequence, error) {
 	}
 	notesF := IntArrayIdAutomation(e.Notes)
 	if e.NotesAutomation != nil {
-		notesF_, err := e.NotesAutomation.GetAutomation()
+		notesF_, err := e.NotesAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("play_notes > auto_notes", err)
 		}
@@ -41,7 +41,7 @@ func (e *PlayNotesEveryDef) GetSequence(ctx *context) (Sequence, error) {
 	}
 	velocityF := IntIdAutomation(e.Velocity)
 	if e.VelocityAutomation != nil {
-		velocityF_, err := e.VelocityAutomation.GetAutomation()
+		velocityF_, err := e.VelocityAutomation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("play_note > auto_velocity", err)
 		}
@@ -0,0 +1,75 @@
+package definitions
+
+import (
+	"fmt"
+
+	"github.com/bspaans/bleep/channels"
+	"github.com/bspaans/bleep/sequencer/processors"
+	"github.com/bspaans/bleep/theory"
+	"github.com/bspaans/bleep/util"
+)
+
+// GetProcessors returns the live input processors for every channel that has
+// a quantizer, chord memory or arpeggiator configured, keyed by channel.
+// Incoming notes are quantized first, then expanded into chords and finally
+// arpeggiated.
+func (s *SequencerDef) GetProcessors() (map[int]processors.Processor, error) {
+	result := map[int]processors.Processor{}
+	for i, ch := range s.Channels {
+		p, err := getChannelProcessor(ch, s.Granularity)
+		if err != nil {
+			return nil, util.WrapError(fmt.Sprintf("channels [%d]", i), err)
+		}
+		if p != nil {
+			result[ch.Channel] = p
+		}
+	}
+	return result, nil
+}
+
+func getChannelProcessor(ch *channels.ChannelDef, granularity int) (processors.Processor, error) {
+	chain := []processors.Processor{}
+	if ch.Quantize != nil {
+		q, err := processors.NewQuantizer(ch.Quantize.Scale, ch.Quantize.Root)
+		if err != nil {
+			return nil, util.WrapError("quantize", err)
+		}
+		chain = append(chain, q)
+	}
+	if ch.ChordMemory != nil {
+		intervals, err := getChordMemoryIntervals(ch.ChordMemory)
+		if err != nil {
+			return nil, util.WrapError("chord_memory", err)
+		}
+		chain = append(chain, processors.NewChordMemory(intervals))
+	}
+	if ch.Arpeggiator != nil {
+		a := ch.Arpeggiator
+		mode, err := parseArpeggioMode(a.Mode)
+		if err != nil {
+			return nil, util.WrapError("arpeggiator", err)
+		}
+		rate, duration, err := parseArpeggioTiming(a.Rate, a.Gate, granularity)
+		if err != nil {
+			return nil, util.WrapError("arpeggiator", err)
+		}
+		chain = append(chain, processors.NewArpeggiator(ch.Channel, mode, a.Octaves, rate, duration, a.Latch))
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return processors.Chain(chain...), nil
+}
+
+func getChordMemoryIntervals(def *channels.ChordMemoryDef) ([]int, error) {
+	if def.Chord != "" && def.Intervals != nil {
+		return nil, fmt.Errorf("expecting either 'chord' or 'intervals', not both")
+	}
+	if def.Intervals != nil {
+		if len(def.Intervals) == 0 {
+			return nil, fmt.Errorf("expecting at least one interval")
+		}
+		return def.Intervals, nil
+	}
+	return theory.ChordOnNoteInt(0, def.Chord)
+}
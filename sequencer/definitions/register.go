@@ -12,11 +12,11 @@ type RegisterDef struct {
 	Automation *AutomationDef `json:"auto_value,omitempty" yaml:"auto_value,omitempty"`
 }
 
-func (e *RegisterDef) GetSequence() (Sequence, error) {
+func (e *RegisterDef) GetSequence(ctx *context) (Sequence, error) {
 
 	valueF := IntIdAutomation(e.Value)
 	if e.Automation != nil {
-		valueF_, err := e.Automation.GetAutomation()
+		valueF_, err := e.Automation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("register", err)
 		}
@@ -31,7 +31,7 @@ type FloatRegisterDef struct {
 	Automation *FloatAutomationDef `json:"auto_value,omitempty" yaml:"auto_value,omitempty"`
 }
 
-func (e *FloatRegisterDef) GetSequence() (Sequence, error) {
+func (e *FloatRegisterDef) GetSequence(ctx *context) (Sequence, error) {
 
 	valueF := FloatIdAutomation(e.Value)
 	if e.Automation != nil {
@@ -50,11 +50,11 @@ type IntArrayRegisterDef struct {
 	Automation *IntArrayAutomationDef `json:"auto_values,omitempty" yaml:"auto_values,omitempty"`
 }
 
-func (e *IntArrayRegisterDef) GetSequence() (Sequence, error) {
+func (e *IntArrayRegisterDef) GetSequence(ctx *context) (Sequence, error) {
 
 	valueF := IntArrayIdAutomation(e.Value)
 	if e.Automation != nil {
-		valueF_, err := e.Automation.GetAutomation()
+		valueF_, err := e.Automation.GetAutomation(ctx)
 		if err != nil {
 			return nil, util.WrapError("int_array_register", err)
 		}
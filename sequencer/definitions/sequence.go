@@ -13,6 +13,7 @@ type SequenceDef struct {
 	Euclidian      *EuclidianDef              `json:"euclidian,omitempty" yaml:"euclidian,omitempty"`
 	PlayNoteEvery  *PlayNoteEveryDef          `json:"play_note,omitempty" yaml:"play_note,omitempty"`
 	PlayNotesEvery *PlayNotesEveryDef         `json:"play_notes,omitempty" yaml:"play_notes,omitempty"`
+	Arpeggio       *ArpeggioDef               `json:"arpeggio,omitempty" yaml:"arpeggio,omitempty"`
 	Panning        *ChannelAutomationDef      `json:"panning,omitempty" yaml:"panning,omitempty"`
 	Reverb         *ChannelAutomationDef      `json:"reverb,omitempty" yaml:"reverb,omitempty"`
 	ReverbTime     *FloatChannelAutomationDef `json:"reverb_time,omitempty" yaml:"reverb_time,omitempty"`
@@ -20,6 +21,7 @@ type SequenceDef struct {
 	LPF_Cutoff     *ChannelAutomationDef      `json:"lpf_cutoff,omitempty" yaml:"lpf_cutoff,omitempty"`
 	HPF_Cutoff     *ChannelAutomationDef      `json:"hpf_cutoff,omitempty" yaml:"hpf_cutoff,omitempty"`
 	Volume         *ChannelAutomationDef      `json:"volume,omitempty" yaml:"volume,omitempty"`
+	Mute           *ChannelAutomationDef      `json:"mute,omitempty" yaml:"mute,omitempty"`
 	GrainSize      *FloatChannelAutomationDef `json:"grain_size,omitempty" yaml:"grain_size,omitempty"`
 	GrainBirthRate *FloatChannelAutomationDef `json:"grain_birth_rate,omitempty" yaml:"grain_birth_rate,omitempty"`
 	GrainSpread    *FloatChannelAutomationDef `json:"grain_spread,omitempty" yaml:"grain_spread,omitempty"`
@@ -31,6 +33,15 @@ type SequenceDef struct {
 	FloatRegister  *FloatRegisterDef          `json:"float_register,omitempty" yaml:"float_register,omitempty"`
 	ArrayRegister  *IntArrayRegisterDef       `json:"array_register,omitempty" yaml:"array_register,omitempty"`
 	MIDI           *MIDISequencesDef          `json:"midi,omitempty" yaml:"midi,omitempty"`
+	Tempo          *TempoDef                  `json:"tempo,omitempty" yaml:"tempo,omitempty"`
+	Key            *KeyDef                    `json:"key,omitempty" yaml:"key,omitempty"`
+	Groove         *GrooveDef                 `json:"groove,omitempty" yaml:"groove,omitempty"`
+	Chance         *ChanceDef                 `json:"chance,omitempty" yaml:"chance,omitempty"`
+	EveryNOfM      *EveryNOfMDef              `json:"every_n_of_m,omitempty" yaml:"every_n_of_m,omitempty"`
+	Fill           *FillDef                   `json:"fill,omitempty" yaml:"fill,omitempty"`
+	Humanize       *HumanizeDef               `json:"humanize,omitempty" yaml:"humanize,omitempty"`
+	If             *IfDef                     `json:"if,omitempty" yaml:"if,omitempty"`
+	SwitchRegister *SwitchOnRegisterDef       `json:"switch_on_register,omitempty" yaml:"switch_on_register,omitempty"`
 	Combine        []*SequenceDef             `json:"combine,omitempty" yaml:"combine,omitempty"`
 }
 
@@ -57,51 +68,84 @@ func (e *SequenceDef) GetSequence(ctx *context) (Sequence, error) {
 	} else if e.PlayNotesEvery != nil {
 		field = "play_notes"
 		result, err = e.PlayNotesEvery.GetSequence(ctx)
+	} else if e.Arpeggio != nil {
+		field = "arpeggio"
+		result, err = e.Arpeggio.GetSequence(ctx)
 	} else if e.Panning != nil {
 		field = "panning"
-		result, err = e.Panning.GetSequence(PanningAutomation)
+		result, err = e.Panning.GetSequence(ctx, PanningAutomation)
 	} else if e.Reverb != nil {
 		field = "reverb"
-		result, err = e.Reverb.GetSequence(ReverbAutomation)
+		result, err = e.Reverb.GetSequence(ctx, ReverbAutomation)
 	} else if e.ReverbTime != nil {
 		field = "reverb_time"
-		result, err = e.ReverbTime.GetSequence(ReverbTimeAutomation)
+		result, err = e.ReverbTime.GetSequence(ctx, ReverbTimeAutomation)
 	} else if e.LPF_Cutoff != nil {
 		field = "lpf_cutoff"
-		result, err = e.LPF_Cutoff.GetSequence(LPF_CutoffAutomation)
+		result, err = e.LPF_Cutoff.GetSequence(ctx, LPF_CutoffAutomation)
 	} else if e.HPF_Cutoff != nil {
 		field = "hpf_cutoff"
-		result, err = e.HPF_Cutoff.GetSequence(HPF_CutoffAutomation)
+		result, err = e.HPF_Cutoff.GetSequence(ctx, HPF_CutoffAutomation)
 	} else if e.Tremelo != nil {
 		field = "tremelo"
-		result, err = e.Tremelo.GetSequence(TremeloAutomation)
+		result, err = e.Tremelo.GetSequence(ctx, TremeloAutomation)
 	} else if e.Volume != nil {
 		field = "volume"
-		result, err = e.Volume.GetSequence(ChannelVolumeAutomation)
+		result, err = e.Volume.GetSequence(ctx, ChannelVolumeAutomation)
+	} else if e.Mute != nil {
+		field = "mute"
+		result, err = e.Mute.GetSequence(ctx, MuteAutomation)
 	} else if e.GrainSize != nil {
 		field = "grain_size"
-		result, err = e.GrainSize.GetSequence(GrainSizeAutomation)
+		result, err = e.GrainSize.GetSequence(ctx, GrainSizeAutomation)
 	} else if e.GrainBirthRate != nil {
 		field = "grain_birth_rate"
-		result, err = e.GrainBirthRate.GetSequence(GrainBirthRateAutomation)
+		result, err = e.GrainBirthRate.GetSequence(ctx, GrainBirthRateAutomation)
 	} else if e.GrainSpread != nil {
 		field = "grain_spread"
-		result, err = e.GrainSpread.GetSequence(GrainSpreadAutomation)
+		result, err = e.GrainSpread.GetSequence(ctx, GrainSpreadAutomation)
 	} else if e.GrainSpeed != nil {
 		field = "grain_speed"
-		result, err = e.GrainSpeed.GetSequence(GrainSpeedAutomation)
+		result, err = e.GrainSpeed.GetSequence(ctx, GrainSpeedAutomation)
 	} else if e.Register != nil {
 		field = "register"
-		result, err = e.Register.GetSequence()
+		result, err = e.Register.GetSequence(ctx)
 	} else if e.FloatRegister != nil {
 		field = "float_register"
-		result, err = e.FloatRegister.GetSequence()
+		result, err = e.FloatRegister.GetSequence(ctx)
 	} else if e.ArrayRegister != nil {
 		field = "array_register"
-		result, err = e.ArrayRegister.GetSequence()
+		result, err = e.ArrayRegister.GetSequence(ctx)
 	} else if e.MIDI != nil {
 		field = "midi"
 		result, err = e.MIDI.GetSequence(ctx)
+	} else if e.Tempo != nil {
+		field = "tempo"
+		result, err = e.Tempo.GetSequence(ctx)
+	} else if e.Key != nil {
+		field = "key"
+		result, err = e.Key.GetSequence(ctx)
+	} else if e.Groove != nil {
+		field = "groove"
+		result, err = e.Groove.GetSequence(ctx)
+	} else if e.Chance != nil {
+		field = "chance"
+		result, err = e.Chance.GetSequence(ctx)
+	} else if e.EveryNOfM != nil {
+		field = "every_n_of_m"
+		result, err = e.EveryNOfM.GetSequence(ctx)
+	} else if e.Fill != nil {
+		field = "fill"
+		result, err = e.Fill.GetSequence(ctx)
+	} else if e.Humanize != nil {
+		field = "humanize"
+		result, err = e.Humanize.GetSequence(ctx)
+	} else if e.If != nil {
+		field = "if"
+		result, err = e.If.GetSequence(ctx)
+	} else if e.SwitchRegister != nil {
+		field = "switch_on_register"
+		result, err = e.SwitchRegister.GetSequence(ctx)
 	} else if e.After != nil {
 		field = "after"
 		result, err = e.After.GetSequence(ctx)
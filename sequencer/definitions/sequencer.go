@@ -6,16 +6,24 @@ import (
 	"path/filepath"
 
 	"github.com/bspaans/bleep/channels"
+	"github.com/bspaans/bleep/instruments"
 	. "github.com/bspaans/bleep/sequencer/sequences"
+	"github.com/bspaans/bleep/synth"
 	"github.com/bspaans/bleep/util"
 	"gopkg.in/yaml.v2"
 )
 
 type SequencerDef struct {
-	BPM                  float64       `json:"bpm" yaml:"bpm"`
-	Granularity          int           `json:"granularity" yaml:"granularity"`
-	Sequences            []SequenceDef `json:"sequences" yaml:"sequences"`
-	Tracks               []TrackDef    `json:"tracks" yaml:"tracks"`
+	BPM                  float64             `json:"bpm" yaml:"bpm"`
+	Granularity          int                 `json:"granularity" yaml:"granularity"`
+	Seed                 int64               `json:"seed,omitempty" yaml:"seed,omitempty"`
+	Key                  interface{}         `json:"key,omitempty" yaml:"key,omitempty"`
+	Scale                string              `json:"scale,omitempty" yaml:"scale,omitempty"`
+	Tuning               *channels.TuningDef `json:"tuning,omitempty" yaml:"tuning,omitempty"`
+	MPE                  *MPEDef             `json:"mpe,omitempty" yaml:"mpe,omitempty"`
+	Master               *MasterDef          `json:"master,omitempty" yaml:"master,omitempty"`
+	Sequences            []SequenceDef       `json:"sequences" yaml:"sequences"`
+	Tracks               []TrackDef          `json:"tracks" yaml:"tracks"`
 	channels.ChannelsDef `json:",inline" yaml:",inline"`
 	FromFile             string `json:"-" yaml:"-"`
 }
@@ -78,13 +86,115 @@ func NewSequencerDefFromFile(file string) (*SequencerDef, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewSequencerDefFromYaml(contents, file)
+}
+
+// NewSequencerDefFromYaml parses a sequencer definition. Files referenced by
+// the definition are resolved relative to fromFile, or to the working
+// directory if it's empty.
+func NewSequencerDefFromYaml(contents []byte, fromFile string) (*SequencerDef, error) {
 	result := SequencerDef{}
 	if err := yaml.Unmarshal(contents, &result); err != nil {
 		return nil, err
 	}
 	if len(result.Sequences) == 0 {
-		return nil, fmt.Errorf("No sequences in sequencer def %s", file)
+		return nil, fmt.Errorf("No sequences in sequencer def %s", fromFile)
 	}
-	result.FromFile = file
+	result.FromFile = fromFile
 	return &result, nil
 }
+
+// Validate checks that the sequences, input processors, key, tuning and
+// channel setup can all be loaded. The sequencer itself only logs these
+// errors.
+func (s *SequencerDef) Validate() error {
+	if s.BPM <= 0 {
+		return util.WrapError("bpm", fmt.Errorf("expecting a positive value"))
+	}
+	if s.Granularity <= 0 {
+		return util.WrapError("granularity", fmt.Errorf("expecting a positive value"))
+	}
+	if _, err := s.GetSequences(); err != nil {
+		return err
+	}
+	if _, err := s.GetProcessors(); err != nil {
+		return err
+	}
+	if _, _, err := s.GetKey(); err != nil {
+		return err
+	}
+	if s.MPE != nil {
+		if err := s.MPE.Validate(); err != nil {
+			return util.WrapError("mpe", err)
+		}
+	}
+	if s.Master != nil {
+		if err := s.Master.Validate(); err != nil {
+			return util.WrapError("master", err)
+		}
+	}
+	ctx, err := instruments.NewContext(s.FromFile, nil)
+	if err != nil {
+		return err
+	}
+	if s.Tuning != nil {
+		if _, err := s.Tuning.GetTuning(ctx); err != nil {
+			return util.WrapError("tuning", err)
+		}
+	}
+	for i, ch := range s.ChannelsDef.Channels {
+		if err := validateChannelDef(ctx, ch, s.BPM); err != nil {
+			return util.WrapError(fmt.Sprintf("channels[%d]", i), err)
+		}
+	}
+	return nil
+}
+
+func validateChannelDef(ctx *instruments.Context, ch *channels.ChannelDef, bpm float64) error {
+	if ch.Channel < 0 || ch.Channel > 15 {
+		return util.WrapError("channel", fmt.Errorf("expecting a value between 0 and 15"))
+	}
+	if _, err := synth.ParseChannelOutput(ch.Output); err != nil {
+		return util.WrapError("output", err)
+	}
+	if ch.Generator != nil {
+		if err := ch.Generator.Validate(ctx); err != nil {
+			return util.WrapError("generator", err)
+		}
+	}
+	if ch.Tuning != nil {
+		if _, err := ch.Tuning.GetTuning(ctx); err != nil {
+			return util.WrapError("tuning", err)
+		}
+	}
+	if ch.ReverbTime != nil {
+		if _, err := channels.ParseDuration(ch.ReverbTime, bpm); err != nil {
+			return util.WrapError("reverb_time", err)
+		}
+	}
+	fields := []string{"gate", "expander", "compressor"}
+	for i, def := range []*instruments.DynamicsOptionsDef{ch.Gate, ch.Expander, ch.Compressor} {
+		if def != nil {
+			if err := validateDynamicsDef(def, ch.Channel); err != nil {
+				return util.WrapError(fields[i], err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateDynamicsDef(def *instruments.DynamicsOptionsDef, channel int) error {
+	if err := def.Validate(); err != nil {
+		return err
+	}
+	if def.Sidechain == nil {
+		return nil
+	}
+	sidechain := *def.Sidechain
+	if sidechain < 0 || sidechain > 15 {
+		return util.WrapError("sidechain", fmt.Errorf("expecting a value between 0 and 15"))
+	} else if sidechain == channel {
+		return util.WrapError("sidechain", fmt.Errorf("expecting another channel"))
+	}
+	return nil
+}
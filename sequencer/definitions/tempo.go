@@ -0,0 +1,69 @@
+package definitions
+
+import (
+	"fmt"
+
+	. "github.com/bspaans/bleep/sequencer/automations"
+	. "github.com/bspaans/bleep/sequencer/sequences"
+	"github.com/bspaans/bleep/util"
+)
+
+// TempoDef changes the BPM of the sequencer. The tempo can be set to a
+// constant, be driven by a float automation or follow a tempo map:
+//
+//	tempo:
+//	  map:
+//	  - at: 0
+//	    bpm: 120
+//	  - at: 16
+//	    bpm: 140
+//	  - at: 24
+//	    bpm: 90
+//	    ramp: true
+//
+// A map point without `ramp` is a step change; with `ramp` the tempo moves
+// linearly from the previous point, which can be used for accelerandos and
+// ritardandos. Scheduled events are measured in ticks, so notes that are
+// already playing stay in time when the tempo changes.
+type TempoDef struct {
+	BPM        float64             `json:"bpm,omitempty" yaml:"bpm,omitempty"`
+	Automation *FloatAutomationDef `json:"auto_bpm,omitempty" yaml:"auto_bpm,omitempty"`
+	Map        []*TempoChangeDef   `json:"map,omitempty" yaml:"map,omitempty"`
+}
+
+type TempoChangeDef struct {
+	At   interface{} `json:"at" yaml:"at"`
+	BPM  float64     `json:"bpm" yaml:"bpm"`
+	Ramp bool        `json:"ramp,omitempty" yaml:"ramp,omitempty"`
+}
+
+func (e *TempoDef) GetSequence(ctx *context) (Sequence, error) {
+	if e.Automation != nil {
+		bpmF, err := e.Automation.GetAutomation()
+		if err != nil {
+			return nil, util.WrapError("auto_bpm", err)
+		}
+		return TempoAutomation(bpmF), nil
+	} else if len(e.Map) > 0 {
+		at := make([]uint, len(e.Map))
+		values := make([]float64, len(e.Map))
+		ramp := make([]bool, len(e.Map))
+		for i, change := range e.Map {
+			t, err := parseDuration(change.At, ctx.Granularity)
+			if err != nil {
+				return nil, util.WrapError(fmt.Sprintf("map [%d]", i), err)
+			}
+			if i > 0 && t <= at[i-1] {
+				return nil, util.WrapError(fmt.Sprintf("map [%d]", i), fmt.Errorf("tempo changes should be in chronological order"))
+			}
+			if change.BPM <= 0 {
+				return nil, util.WrapError(fmt.Sprintf("map [%d]", i), fmt.Errorf("missing bpm"))
+			}
+			at[i], values[i], ramp[i] = t, change.BPM, change.Ramp
+		}
+		return TempoAutomation(FloatTimelineAutomation(at, values, ramp)), nil
+	} else if e.BPM > 0 {
+		return TempoAutomation(FloatIdAutomation(e.BPM)), nil
+	}
+	return nil, fmt.Errorf("missing bpm, auto_bpm or map")
+}
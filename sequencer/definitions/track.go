@@ -0,0 +1,6 @@
+package definitions
+
+type TrackDef struct {
+	Name      string        `json:"name,omitempty" yaml:"name,omitempty"`
+	Sequences []SequenceDef `json:"sequences" yaml:"sequences"`
+}
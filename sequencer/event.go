@@ -1,6 +1,9 @@
 package sequencer
 
-import "github.com/bspaans/bleep/sequencer/definitions"
+import (
+	"github.com/bspaans/bleep/sequencer/definitions"
+	"github.com/bspaans/bleep/synth"
+)
 
 type EventType int
 
@@ -24,12 +27,23 @@ const (
 	SaveFile EventType = iota
 
 	QuitSequencer EventType = iota
+
+	LiveEvent EventType = iota
+
+	SetClockOutput EventType = iota
+	SetClockSlave  EventType = iota
+	ClockEvent     EventType = iota
+
+	SetBPM           EventType = iota
+	SetRegister      EventType = iota
+	SetFloatRegister EventType = iota
 )
 
 type SequencerEvent struct {
 	Type         EventType
 	SequencerDef *definitions.SequencerDef
 	Value        interface{}
+	Event        *synth.Event
 }
 
 func NewSequencerEvent(ty EventType) *SequencerEvent {
@@ -0,0 +1,65 @@
+package sequencer
+
+import (
+	"sync"
+
+	"github.com/bspaans/bleep/synth"
+)
+
+// The number of ticks a Pattern remembers.
+const patternSize = 4096
+
+// Pattern remembers which channels started notes on the most recent ticks, so
+// that the user interfaces can show what's been playing. Ticks that haven't
+// been played since the sequencer was (re)loaded are empty; after a rewind
+// the notes of the previous pass are kept until they're played over.
+type Pattern struct {
+	mux   sync.Mutex
+	times []uint
+	notes []uint32
+}
+
+func NewPattern() *Pattern {
+	p := &Pattern{}
+	p.Clear()
+	return p
+}
+
+func (p *Pattern) Clear() {
+	p.mux.Lock()
+	p.times = make([]uint, patternSize)
+	p.notes = make([]uint32, patternSize)
+	p.mux.Unlock()
+}
+
+// Record the note ons of tick t. Every played tick is recorded, so that it
+// replaces what was played on the same tick before.
+func (p *Pattern) Record(t uint, events []*synth.Event) {
+	notes := uint32(0)
+	for _, ev := range events {
+		if ev.Type == synth.NoteOn && len(ev.Values) > 1 && ev.Values[1] > 0 && ev.Channel >= 0 && ev.Channel < 32 {
+			notes |= 1 << uint(ev.Channel)
+		}
+	}
+	p.mux.Lock()
+	// Times are stored off by one, so that the zero value means unplayed
+	p.times[t%patternSize] = t + 1
+	p.notes[t%patternSize] = notes
+	p.mux.Unlock()
+}
+
+// Notes returns the channels that started notes on the ticks in [from, to),
+// as a bit mask per tick.
+func (p *Pattern) Notes(from, to uint) []uint32 {
+	result := []uint32{}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	for t := from; t < to; t++ {
+		notes := uint32(0)
+		if p.times[t%patternSize] == t+1 {
+			notes = p.notes[t%patternSize]
+		}
+		result = append(result, notes)
+	}
+	return result
+}
@@ -0,0 +1,36 @@
+package sequencer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bspaans/bleep/sequencer/sequences"
+	"github.com/bspaans/bleep/synth"
+)
+
+func Test_Sequencer_records_the_pattern(t *testing.T) {
+	seq := NewSequencer(120, 4)
+	seq.Sequences = []sequences.Sequence{
+		sequences.PlayNoteEvery(2, 1, 1, 60, 100),
+		sequences.EveryWithOffset(4, 3, sequences.PlayNoteEvery(1, 1, 9, 36, 100)),
+	}
+	for i := 0; i < 8; i++ {
+		seq.Step(func(ev *synth.Event) {})
+	}
+	expected := []uint32{2, 0, 2, 1 << 9, 2, 0, 2, 1 << 9, 0}
+	if notes := seq.Pattern.Notes(0, 9); !reflect.DeepEqual(notes, expected) {
+		t.Errorf("Expecting %v, got %v", expected, notes)
+	}
+
+	// Rewinding keeps the previous pass until it's played over
+	seq.Status.Time = 0
+	seq.Sequences = nil
+	seq.Step(func(ev *synth.Event) {})
+	if notes := seq.Pattern.Notes(0, 3); !reflect.DeepEqual(notes, []uint32{0, 0, 2}) {
+		t.Errorf("Expecting the first tick to be replaced, got %v", notes)
+	}
+	seq.Pattern.Clear()
+	if notes := seq.Pattern.Notes(2, 3); notes[0] != 0 {
+		t.Errorf("Expecting the pattern to be cleared, got %v", notes)
+	}
+}
@@ -0,0 +1,105 @@
+package processors
+
+import (
+	. "github.com/bspaans/bleep/sequencer/sequences"
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/synth"
+)
+
+// The Arpeggiator plays the notes that are currently being held one at a
+// time, clocked by the sequencer. Incoming NoteOn and NoteOff events are
+// consumed. If Latch is set the notes keep playing after they have been
+// released, until a new note is pressed.
+type Arpeggiator struct {
+	Channel  int
+	Mode     string
+	Octaves  int
+	Rate     uint
+	Duration uint
+	Latch    bool
+
+	held     []int
+	latched  []int
+	velocity int
+	step     uint
+}
+
+func NewArpeggiator(channel int, mode string, octaves int, rate, duration uint, latch bool) *Arpeggiator {
+	return &Arpeggiator{
+		Channel:  channel,
+		Mode:     mode,
+		Octaves:  octaves,
+		Rate:     rate,
+		Duration: duration,
+		Latch:    latch,
+		held:     []int{},
+		latched:  []int{},
+		velocity: 100,
+	}
+}
+
+func (a *Arpeggiator) Process(status *Status, ev *synth.Event) []*synth.Event {
+	if !isNoteEvent(ev) {
+		return []*synth.Event{ev}
+	}
+	note := ev.Values[0]
+	if ev.Type == synth.NoteOn {
+		if len(a.held) == 0 {
+			a.latched = []int{}
+			a.step = 0
+		}
+		if len(ev.Values) > 1 {
+			a.velocity = ev.Values[1]
+		}
+		a.held = appendUnique(a.held, note)
+		a.latched = appendUnique(a.latched, note)
+	} else {
+		a.held = remove(a.held, note)
+	}
+	return nil
+}
+
+func (a *Arpeggiator) Tick(status *Status) []*synth.Event {
+	if a.Rate == 0 || status.Time%a.Rate != 0 {
+		return nil
+	}
+	notes := a.held
+	if a.Latch {
+		notes = a.latched
+	}
+	pattern := ArpeggioPattern(notes, a.Mode, a.Octaves)
+	if len(pattern) == 0 {
+		return nil
+	}
+	var note int
+	if a.Mode == "random" {
+		note = pattern[status.Random.Intn(len(pattern))]
+	} else {
+		note = pattern[a.step%uint(len(pattern))]
+	}
+	a.step++
+	if note < 0 || note > 127 {
+		return nil
+	}
+	status.ScheduleEvent(a.Duration, synth.NewEvent(synth.NoteOff, a.Channel, []int{note}))
+	return []*synth.Event{synth.NewEvent(synth.NoteOn, a.Channel, []int{note, a.velocity})}
+}
+
+func appendUnique(notes []int, note int) []int {
+	for _, n := range notes {
+		if n == note {
+			return notes
+		}
+	}
+	return append(notes, note)
+}
+
+func remove(notes []int, note int) []int {
+	result := []int{}
+	for _, n := range notes {
+		if n != note {
+			result = append(result, n)
+		}
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package processors
+
+import (
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/synth"
+)
+
+// ChordMemory turns every incoming note into a chord. The chord is stored as
+// a list of intervals in semitones relative to the played note; e.g.
+// []int{0, 4, 7} for a major triad.
+type ChordMemory struct {
+	Intervals []int
+}
+
+func NewChordMemory(intervals []int) *ChordMemory {
+	return &ChordMemory{
+		Intervals: intervals,
+	}
+}
+
+func (c *ChordMemory) Process(status *Status, ev *synth.Event) []*synth.Event {
+	if !isNoteEvent(ev) {
+		return []*synth.Event{ev}
+	}
+	result := []*synth.Event{}
+	for _, interval := range c.Intervals {
+		note := ev.Values[0] + interval
+		if note < 0 || note > 127 {
+			continue
+		}
+		result = append(result, withNote(ev, note))
+	}
+	return result
+}
+
+func (c *ChordMemory) Tick(status *Status) []*synth.Event {
+	return nil
+}
@@ -0,0 +1,61 @@
+package processors
+
+import (
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/synth"
+)
+
+// A Processor sits between a live input (e.g. a MIDI keyboard) and the
+// synthesizer and transforms the incoming events. Process is called for every
+// incoming event and returns the events that should be passed on. Tick is
+// called on every sequencer tick while the sequencer is playing, which allows
+// processors to generate events of their own.
+type Processor interface {
+	Process(status *Status, ev *synth.Event) []*synth.Event
+	Tick(status *Status) []*synth.Event
+}
+
+type chain []Processor
+
+// Chain the processors so that the output of each processor is fed into the
+// next one.
+func Chain(processors ...Processor) Processor {
+	if len(processors) == 1 {
+		return processors[0]
+	}
+	return chain(processors)
+}
+
+func (c chain) Process(status *Status, ev *synth.Event) []*synth.Event {
+	return c.process(status, 0, []*synth.Event{ev})
+}
+
+func (c chain) Tick(status *Status) []*synth.Event {
+	result := []*synth.Event{}
+	for i, p := range c {
+		result = append(result, c.process(status, i+1, p.Tick(status))...)
+	}
+	return result
+}
+
+func (c chain) process(status *Status, from int, events []*synth.Event) []*synth.Event {
+	for _, p := range c[from:] {
+		next := []*synth.Event{}
+		for _, ev := range events {
+			next = append(next, p.Process(status, ev)...)
+		}
+		events = next
+	}
+	return events
+}
+
+func isNoteEvent(ev *synth.Event) bool {
+	return (ev.Type == synth.NoteOn || ev.Type == synth.NoteOff) && len(ev.Values) > 0
+}
+
+func withNote(ev *synth.Event, note int) *synth.Event {
+	values := make([]int, len(ev.Values))
+	copy(values, ev.Values)
+	values[0] = note
+	return synth.NewEvent(ev.Type, ev.Channel, values)
+}
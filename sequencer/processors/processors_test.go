@@ -0,0 +1,139 @@
+package processors
+
+import (
+	"testing"
+
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/synth"
+)
+
+func notes(events []*synth.Event) []int {
+	result := []int{}
+	for _, ev := range events {
+		result = append(result, ev.Values[0])
+	}
+	return result
+}
+
+func expectNotes(t *testing.T, expected, got []int) {
+	if len(expected) != len(got) {
+		t.Errorf("Expecting %v, got %v", expected, got)
+		return
+	}
+	for i, e := range expected {
+		if got[i] != e {
+			t.Errorf("Expecting %v, got %v", expected, got)
+			return
+		}
+	}
+}
+
+func Test_Quantizer(t *testing.T) {
+	unit, err := NewQuantizer("ionian", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := NewStatus(120, 4)
+	for note, expected := range map[int]int{60: 60, 61: 60, 63: 62, 66: 65, 70: 69, 71: 71} {
+		result := unit.Process(&status, synth.NewEvent(synth.NoteOn, 0, []int{note, 100}))
+		expectNotes(t, []int{expected}, notes(result))
+		if result[0].Values[1] != 100 {
+			t.Errorf("Expecting velocity to be preserved, got %v", result[0].Values)
+		}
+	}
+	if _, err := NewQuantizer("nope", 0); err == nil {
+		t.Errorf("Expecting error for unknown scale")
+	}
+}
+
+func Test_Quantizer_modes_start_on_the_root(t *testing.T) {
+	// D dorian has the same notes as C major
+	unit, err := NewQuantizer("dorian", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for note, expected := range map[int]int{61: 60, 62: 62, 66: 65, 68: 67, 71: 71} {
+		if got := unit.Quantize(note); got != expected {
+			t.Errorf("Expecting %d to be quantized to %d in D dorian, got %d", note, expected, got)
+		}
+	}
+}
+
+func Test_ChordMemory(t *testing.T) {
+	unit := NewChordMemory([]int{0, 4, 7})
+	status := NewStatus(120, 4)
+	expectNotes(t, []int{60, 64, 67}, notes(unit.Process(&status, synth.NewEvent(synth.NoteOn, 0, []int{60, 100}))))
+	expectNotes(t, []int{60, 64, 67}, notes(unit.Process(&status, synth.NewEvent(synth.NoteOff, 0, []int{60}))))
+	other := unit.Process(&status, synth.NewEvent(synth.ProgramChange, 0, []int{3}))
+	if len(other) != 1 || other[0].Type != synth.ProgramChange {
+		t.Errorf("Expecting other events to be passed through, got %v", other)
+	}
+}
+
+func playArpeggiator(unit Processor, status *Status, ticks uint) []*synth.Event {
+	result := []*synth.Event{}
+	for i := uint(0); i < ticks; i++ {
+		for _, ev := range status.GetScheduledEvents(status.Time) {
+			result = append(result, ev.Event)
+		}
+		result = append(result, unit.Tick(status)...)
+		status.IncrementTime()
+	}
+	return result
+}
+
+func noteOns(events []*synth.Event) []int {
+	result := []int{}
+	for _, ev := range events {
+		if ev.Type == synth.NoteOn {
+			result = append(result, ev.Values[0])
+		}
+	}
+	return result
+}
+
+func Test_Arpeggiator(t *testing.T) {
+	unit := NewArpeggiator(1, "up", 1, 2, 1, false)
+	status := NewStatus(120, 4)
+	for _, n := range []int{67, 60, 64} {
+		if result := unit.Process(&status, synth.NewEvent(synth.NoteOn, 1, []int{n, 90})); len(result) != 0 {
+			t.Errorf("Expecting notes to be consumed, got %v", result)
+		}
+	}
+	events := playArpeggiator(unit, &status, 8)
+	expectNotes(t, []int{60, 64, 67, 60}, noteOns(events))
+	if len(events) != 8 {
+		t.Errorf("Expecting a NoteOff for every NoteOn, got %d events", len(events))
+	}
+	if events[0].Values[1] != 90 || events[0].Channel != 1 {
+		t.Errorf("Expecting velocity 90 on channel 1, got %v", events[0])
+	}
+	for _, n := range []int{67, 60, 64} {
+		unit.Process(&status, synth.NewEvent(synth.NoteOff, 1, []int{n}))
+	}
+	expectNotes(t, []int{}, noteOns(playArpeggiator(unit, &status, 8)))
+}
+
+func Test_Arpeggiator_latch(t *testing.T) {
+	unit := NewArpeggiator(0, "up", 1, 1, 1, true)
+	status := NewStatus(120, 4)
+	for _, n := range []int{60, 64} {
+		unit.Process(&status, synth.NewEvent(synth.NoteOn, 0, []int{n, 100}))
+	}
+	for _, n := range []int{60, 64} {
+		unit.Process(&status, synth.NewEvent(synth.NoteOff, 0, []int{n}))
+	}
+	expectNotes(t, []int{60, 64, 60}, noteOns(playArpeggiator(unit, &status, 3)))
+
+	// A new note after everything has been released replaces the latched notes.
+	unit.Process(&status, synth.NewEvent(synth.NoteOn, 0, []int{62, 100}))
+	expectNotes(t, []int{62, 62}, noteOns(playArpeggiator(unit, &status, 2)))
+}
+
+func Test_Chain(t *testing.T) {
+	q, _ := NewQuantizer("ionian", 0)
+	unit := Chain(q, NewChordMemory([]int{0, 4}), NewArpeggiator(0, "up", 1, 1, 1, false))
+	status := NewStatus(120, 4)
+	unit.Process(&status, synth.NewEvent(synth.NoteOn, 0, []int{61, 100}))
+	expectNotes(t, []int{60, 64, 60}, noteOns(playArpeggiator(unit, &status, 3)))
+}
@@ -0,0 +1,51 @@
+package processors
+
+import (
+	"fmt"
+
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/synth"
+	"github.com/bspaans/bleep/theory"
+)
+
+// The Quantizer snaps incoming notes to the nearest note in a scale. When a
+// note is exactly in between two scale notes the lower one is picked.
+type Quantizer struct {
+	PitchClasses [12]bool
+}
+
+// NewQuantizer creates a quantizer for one of the scales in theory.Scales on
+// the given root note.
+func NewQuantizer(scale string, root int) (*Quantizer, error) {
+	if _, ok := theory.Scales[scale]; !ok {
+		return nil, fmt.Errorf("Unknown scale '%s'", scale)
+	}
+	q := &Quantizer{}
+	for _, step := range theory.ScaleSteps(scale) {
+		q.PitchClasses[(((root+step)%12)+12)%12] = true
+	}
+	return q, nil
+}
+
+func (q *Quantizer) Quantize(note int) int {
+	for offset := 0; offset <= 6; offset++ {
+		if n := note - offset; n >= 0 && q.PitchClasses[n%12] {
+			return n
+		}
+		if n := note + offset; n <= 127 && q.PitchClasses[n%12] {
+			return n
+		}
+	}
+	return note
+}
+
+func (q *Quantizer) Process(status *Status, ev *synth.Event) []*synth.Event {
+	if !isNoteEvent(ev) {
+		return []*synth.Event{ev}
+	}
+	return []*synth.Event{withNote(ev, q.Quantize(ev.Values[0]))}
+}
+
+func (q *Quantizer) Tick(status *Status) []*synth.Event {
+	return nil
+}
@@ -6,11 +6,15 @@ import (
 	"time"
 
 	"github.com/bspaans/bleep/channels"
+	"github.com/bspaans/bleep/filters"
 	"github.com/bspaans/bleep/instruments"
+	"github.com/bspaans/bleep/midi"
 	"github.com/bspaans/bleep/sequencer/definitions"
+	"github.com/bspaans/bleep/sequencer/processors"
 	"github.com/bspaans/bleep/sequencer/sequences"
 	"github.com/bspaans/bleep/sequencer/status"
 	"github.com/bspaans/bleep/synth"
+	"github.com/bspaans/bleep/tuning"
 	"github.com/bspaans/bleep/util"
 )
 
@@ -22,6 +26,28 @@ type Sequencer struct {
 	FromFile            string
 	Started             bool
 	InitialChannelSetup []*channels.ChannelDef
+
+	// DefaultMaster configures the master bus when the definition doesn't
+	// have a master section.
+	DefaultMaster *definitions.MasterDef
+
+	// Pattern records the notes that have been played.
+	Pattern *Pattern
+
+	// Whether the mixer state of the definition has been sent; see loadMixer.
+	mixerLoaded bool
+
+	// Processors transform live input (see HandleLiveEvent) per channel.
+	Processors map[int]processors.Processor
+
+	// ClockOutput receives MIDI clock and transport messages. In ClockSlave
+	// mode the sequencer follows incoming MIDI clock instead of its own.
+	ClockOutput    *midi.ClockOutput
+	ClockSlave     bool
+	clockTicks     uint
+	clockRunning   bool
+	clockPulses    uint
+	lastClockPulse time.Time
 }
 
 func NewSequencer(bpm float64, granularity int) *Sequencer {
@@ -29,6 +55,8 @@ func NewSequencer(bpm float64, granularity int) *Sequencer {
 		Status:              status.NewStatus(bpm, granularity),
 		Sequences:           []sequences.Sequence{},
 		InitialChannelSetup: []*channels.ChannelDef{},
+		Processors:          map[int]processors.Processor{},
+		Pattern:             NewPattern(),
 		Inputs:              make(chan *SequencerEvent, 32),
 	}
 	return seq
@@ -38,10 +66,17 @@ func NewSequencerFromFile(file string) (*Sequencer, error) {
 	if err != nil {
 		return nil, util.WrapError("sequencer", err)
 	}
+	return NewSequencerFromDefinition(s), nil
+}
+
+// NewSequencerFromDefinition returns a sequencer for a definition that's
+// already been loaded. Files referenced by the definition are resolved
+// relative to its FromFile.
+func NewSequencerFromDefinition(s *definitions.SequencerDef) *Sequencer {
 	seq := NewSequencer(s.BPM, s.Granularity)
 	seq.instantiateFromSequencerDef(s)
-	seq.FromFile = file
-	return seq, nil
+	seq.FromFile = s.FromFile
+	return seq
 }
 
 func (seq *Sequencer) Start(s chan *synth.Event) {
@@ -58,27 +93,24 @@ func (seq *Sequencer) Start(s chan *synth.Event) {
 func (seq *Sequencer) start(s chan *synth.Event) {
 
 	seq.Status.ResetTime()
+	seq.sendTransport()
 
 	for {
 
-		start := time.Now()
-
-		if seq.Status.Playing {
-
-			if seq.Status.Time == 0 {
-				s <- synth.NewEvent(synth.SilenceAllChannels, 0, nil)
-				seq.loadInstruments(s)
-			}
-
-			for _, scheduled := range seq.Status.GetScheduledEvents(seq.Status.Time) {
-				s <- scheduled.Event
+		if seq.ClockSlave {
+			ev := <-seq.Inputs
+			if ev.Type == QuitSequencer {
+				fmt.Println("Quitting sequencer")
+				return
 			}
+			seq.handleEvent(ev, s)
+			continue
+		}
 
-			for _, sequence := range seq.Sequences {
-				sequence(&seq.Status, seq.Status.Time, seq.Status.Time, s)
-			}
+		start := time.Now()
 
-			seq.Status.IncrementTime()
+		if seq.Status.Playing {
+			seq.tick(sequences.ChannelSink(s))
 		}
 
 		canRead := true
@@ -96,6 +128,7 @@ func (seq *Sequencer) start(s chan *synth.Event) {
 		}
 		millisecondsPerBeat := 60000.0 / seq.BPM
 		millisecondsPerTick := time.Duration(millisecondsPerBeat / float64(seq.Granularity) * 1000000)
+		seq.sendClockPulses(start, millisecondsPerTick)
 
 		elapsed := time.Now().Sub(start)
 		if elapsed > millisecondsPerTick {
@@ -107,59 +140,201 @@ func (seq *Sequencer) start(s chan *synth.Event) {
 	}
 }
 
-func (seq *Sequencer) loadInstruments(s chan *synth.Event) {
+// Step moves the sequencer forward by one tick without waiting for it, and
+// without handling its inputs. It's used to render sequences offline.
+func (seq *Sequencer) Step(s sequences.Sink) {
+	seq.tick(s)
+}
+
+func (seq *Sequencer) tick(out sequences.Sink) {
+	played := []*synth.Event{}
+	s := func(ev *synth.Event) {
+		played = append(played, ev)
+		out(ev)
+	}
+	if seq.Status.Time == 0 {
+		s(synth.NewEvent(synth.SilenceAllChannels, 0, nil))
+		seq.loadInstruments(s)
+		seq.resetKey()
+	}
+	if !seq.mixerLoaded {
+		seq.loadMixer(s)
+	}
+
+	for _, scheduled := range seq.Status.GetScheduledEvents(seq.Status.Time) {
+		s(scheduled.Event)
+	}
+
+	for _, sequence := range seq.Sequences {
+		sequence(&seq.Status, seq.Status.Time, seq.Status.Time, s)
+	}
+
+	for _, p := range seq.Processors {
+		for _, ev := range p.Tick(&seq.Status) {
+			s(ev)
+		}
+	}
+
+	seq.Pattern.Record(seq.Status.Time, played)
+	seq.Status.IncrementTime()
+}
+
+func (seq *Sequencer) loadInstruments(s sequences.Sink) {
 	ctx, err := instruments.NewContext(seq.FromFile, nil)
 	if err != nil {
 		fmt.Printf("Failed to load context for file %s: %s", seq.FromFile, err.Error())
 		return
 	}
+	var globalTuning *tuning.Tuning
+	if seq.SequencerDef != nil && seq.SequencerDef.Tuning != nil {
+		t, err := seq.SequencerDef.Tuning.GetTuning(ctx)
+		if err != nil {
+			fmt.Println("Failed to load tuning:", err.Error())
+		} else {
+			globalTuning = t
+		}
+	}
+	s(synth.NewTuningEvent(synth.SetGlobalTuning, 0, globalTuning))
+	if seq.SequencerDef != nil && seq.SequencerDef.MPE != nil {
+		events, err := seq.SequencerDef.MPE.GetEvents()
+		if err != nil {
+			fmt.Println("Failed to configure MPE:", err.Error())
+		}
+		for _, ev := range events {
+			s(ev)
+		}
+	}
 	for _, channelDef := range seq.InitialChannelSetup {
 		ch := channelDef.Channel
+		output, err := synth.ParseChannelOutput(channelDef.Output)
+		if err != nil {
+			fmt.Printf("Invalid output for channel %d; %s\n", ch, err.Error())
+		}
+		s(synth.NewEvent(synth.SetChannelOutput, ch, []int{int(output)}))
 		if ch != 9 {
 			if channelDef.Generator == nil {
-				s <- synth.NewEvent(synth.ProgramChange, ch, []int{channelDef.Instrument})
+				s(synth.NewEvent(synth.ProgramChange, ch, []int{channelDef.Instrument}))
 			} else {
 				if err := channelDef.Generator.Validate(ctx); err != nil {
 					fmt.Printf("Failed to load generator for channel %d; %s\n", ch, err.Error())
 				} else {
 					instr := instruments.BankDefToInstrument(channelDef.Generator.Generator, seq.FromFile)
-					s <- synth.NewInstrumentEvent(synth.SetInstrument, ch, instr)
+					s(synth.NewInstrumentEvent(synth.SetInstrument, ch, instr))
 				}
 			}
 		}
-		s <- synth.NewEvent(synth.SetTremelo, ch, []int{channelDef.Tremelo})
-		s <- synth.NewEvent(synth.SetReverb, ch, []int{channelDef.Reverb})
-		s <- synth.NewEvent(synth.SetLPFCutoff, ch, []int{channelDef.LPF_Cutoff})
-		s <- synth.NewEvent(synth.SetHPFCutoff, ch, []int{channelDef.HPF_Cutoff})
-		s <- synth.NewEvent(synth.SetChannelVolume, ch, []int{channelDef.Volume})
-		s <- synth.NewEvent(synth.SetChannelPanning, ch, []int{channelDef.Panning})
-		s <- synth.NewFloatEvent(synth.SetReverbFeedback, ch, []float64{channelDef.ReverbFeedback})
+		if channelDef.Tuning != nil {
+			t, err := channelDef.Tuning.GetTuning(ctx)
+			if err != nil {
+				fmt.Printf("Failed to load tuning for channel %d; %s\n", ch, err.Error())
+			} else {
+				s(synth.NewTuningEvent(synth.SetTuning, ch, t))
+			}
+		}
+		if channelDef.PitchbendRange != 0.0 {
+			s(synth.NewFloatEvent(synth.SetPitchbendRange, ch, []float64{channelDef.PitchbendRange}))
+		}
+		s(synth.NewEvent(synth.SetTremelo, ch, []int{channelDef.Tremelo}))
+		s(synth.NewEvent(synth.SetReverb, ch, []int{channelDef.Reverb}))
+		s(synth.NewEvent(synth.SetLPFCutoff, ch, []int{channelDef.LPF_Cutoff}))
+		s(synth.NewEvent(synth.SetHPFCutoff, ch, []int{channelDef.HPF_Cutoff}))
+		s(synth.NewEvent(synth.SetChannelVolume, ch, []int{channelDef.Volume}))
+		s(synth.NewEvent(synth.SetChannelPanning, ch, []int{channelDef.Panning}))
+		s(synth.NewFloatEvent(synth.SetReverbFeedback, ch, []float64{channelDef.ReverbFeedback}))
 
 		d, err := channels.ParseDuration(channelDef.ReverbTime, seq.BPM)
 		if err == nil {
-			s <- synth.NewFloatEvent(synth.SetReverbTime, ch, []float64{d})
+			s(synth.NewFloatEvent(synth.SetReverbTime, ch, []float64{d}))
 		} else {
 			fmt.Println("Invalid duration:", err.Error())
 		}
 
 		if channelDef.Grain != nil {
 			g := channelDef.Grain
-			s <- synth.NewStringEvent(synth.SetGrain, ch, ctx.GetPathFor(g.File))
-			s <- synth.NewFloatEvent(synth.SetGrainGain, ch, []float64{channelDef.Grain.Gain})
-			s <- synth.NewFloatEvent(synth.SetGrainSize, ch, []float64{channelDef.Grain.GrainSize})
-			s <- synth.NewFloatEvent(synth.SetGrainBirthRate, ch, []float64{channelDef.Grain.BirthRate})
-			s <- synth.NewFloatEvent(synth.SetGrainSpread, ch, []float64{channelDef.Grain.Spread})
-			s <- synth.NewFloatEvent(synth.SetGrainSpeed, ch, []float64{channelDef.Grain.Speed})
-			s <- synth.NewEvent(synth.SetGrainDensity, ch, []int{channelDef.Grain.Density})
+			s(synth.NewStringEvent(synth.SetGrain, ch, ctx.GetPathFor(g.File)))
+			s(synth.NewFloatEvent(synth.SetGrainGain, ch, []float64{channelDef.Grain.Gain}))
+			s(synth.NewFloatEvent(synth.SetGrainSize, ch, []float64{channelDef.Grain.GrainSize}))
+			s(synth.NewFloatEvent(synth.SetGrainBirthRate, ch, []float64{channelDef.Grain.BirthRate}))
+			s(synth.NewFloatEvent(synth.SetGrainSpread, ch, []float64{channelDef.Grain.Spread}))
+			s(synth.NewFloatEvent(synth.SetGrainSpeed, ch, []float64{channelDef.Grain.Speed}))
+			s(synth.NewEvent(synth.SetGrainDensity, ch, []int{channelDef.Grain.Density}))
 		}
 	}
 }
 
+// loadMixer sets up the mixer state that's only applied when a definition
+// is loaded, so that rewinding or looping doesn't undo the changes that have
+// been made live since, or reset the envelopes of the master bus and the
+// channel dynamics.
+func (seq *Sequencer) loadMixer(s sequences.Sink) {
+	seq.mixerLoaded = true
+	master := seq.DefaultMaster
+	if seq.SequencerDef != nil && seq.SequencerDef.Master != nil {
+		master = seq.SequencerDef.Master
+	}
+	if master == nil {
+		// Don't keep the master bus of a previous definition
+		s(synth.NewMasterBusEvent(nil))
+	} else if bus, err := master.GetMasterBus(); err != nil {
+		fmt.Println("Failed to configure the master bus:", err.Error())
+	} else {
+		s(synth.NewMasterBusEvent(bus))
+	}
+	for _, channelDef := range seq.InitialChannelSetup {
+		ch := channelDef.Channel
+		s(synth.NewEvent(synth.SetMute, ch, []int{boolToInt(channelDef.Mute)}))
+		s(synth.NewEvent(synth.SetSolo, ch, []int{boolToInt(channelDef.Solo)}))
+		s(synth.NewEvent(synth.SetSoloSafe, ch, []int{boolToInt(channelDef.SoloSafe)}))
+		s(synth.NewDynamicsEvent(ch, channelDynamics(channelDef)))
+	}
+}
+
+// channelDynamics returns the gate, expander and compressor on the channel.
+func channelDynamics(def *channels.ChannelDef) []*synth.ChannelDynamics {
+	result := []*synth.ChannelDynamics{}
+	add := func(options *instruments.DynamicsOptionsDef, filter filters.SidechainFilter) {
+		if options.Sidechain == nil {
+			result = append(result, synth.NewChannelDynamics(filter))
+		} else {
+			result = append(result, synth.NewSidechainedChannelDynamics(filter, *options.Sidechain))
+		}
+	}
+	if def.Gate != nil {
+		add(def.Gate, def.Gate.GateFilter())
+	}
+	if def.Expander != nil {
+		add(def.Expander, def.Expander.ExpanderFilter())
+	}
+	if def.Compressor != nil {
+		add(def.Compressor, def.Compressor.CompressorFilter())
+	}
+	return result
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func (seq *Sequencer) instantiateFromSequencerDef(s *definitions.SequencerDef) {
 	seq.SequencerDef = s
 	seq.BPM = s.BPM
 	seq.Granularity = s.Granularity
+	if s.Seed != 0 {
+		seq.Status.SetSeed(s.Seed)
+	}
 	seq.InitialChannelSetup = s.ChannelsDef.Channels
+	seq.mixerLoaded = false
+	seq.Pattern.Clear()
+	seq.resetKey()
+	procs, err := s.GetProcessors()
+	if err != nil {
+		fmt.Println("Failed to instantiate input processors:", err.Error())
+	} else {
+		seq.Processors = procs
+	}
 	seqs, err := s.GetSequences()
 	if err != nil {
 		fmt.Println("Failed to instantiate sequencer definition:", err.Error())
@@ -168,11 +343,28 @@ func (seq *Sequencer) instantiateFromSequencerDef(s *definitions.SequencerDef) {
 	seq.Sequences = seqs
 }
 
+// resetKey changes the key and scale back to the ones the sequencer
+// definition starts in.
+func (seq *Sequencer) resetKey() {
+	if seq.SequencerDef == nil {
+		return
+	}
+	key, scale, err := seq.SequencerDef.GetKey()
+	if err != nil {
+		fmt.Println("Invalid key:", err.Error())
+		return
+	}
+	seq.Status.Key = key
+	seq.Status.Scale = scale
+}
+
 func (seq *Sequencer) handleEvent(ev *SequencerEvent, s chan *synth.Event) {
 	if ev.Type == RestartSequencer {
 		seq.Status.ResetTime()
+		seq.sendTransport()
 	} else if ev.Type == ReloadSequencer {
 		seq.Status.ResetTime()
+		seq.sendTransport()
 		fmt.Println("reloading")
 		if seq.FromFile != "" {
 			s, err := definitions.NewSequencerDefFromFile(seq.FromFile)
@@ -205,15 +397,18 @@ func (seq *Sequencer) handleEvent(ev *SequencerEvent, s chan *synth.Event) {
 			return
 		}
 		seq.Status.ResetTime()
+		seq.sendTransport()
 		seq.FromFile = ev.Value.(string)
 		seq.instantiateFromSequencerDef(def)
 		s <- synth.NewEvent(synth.ForceUIReload, 0, nil)
 	} else if ev.Type == SetSequencerDef {
 		seq.instantiateFromSequencerDef(ev.SequencerDef)
 		s <- synth.NewEvent(synth.SilenceAllChannels, 0, nil)
-		seq.loadInstruments(s)
+		seq.loadInstruments(sequences.ChannelSink(s))
+		seq.loadMixer(sequences.ChannelSink(s))
 	} else if ev.Type == ForwardSequencer {
 		seq.Status.Time += uint(seq.Granularity) * 16
+		seq.sendTransport()
 		fmt.Println("t =", seq.Status.Time)
 	} else if ev.Type == BackwardSequencer {
 		if seq.Status.Time < uint(seq.Granularity)*16 {
@@ -221,9 +416,11 @@ func (seq *Sequencer) handleEvent(ev *SequencerEvent, s chan *synth.Event) {
 		} else {
 			seq.Status.Time -= uint(seq.Granularity) * 16
 		}
+		seq.sendTransport()
 		fmt.Println("t =", seq.Status.Time)
 	} else if ev.Type == GoToTime {
 		seq.Status.Time = ev.Value.(uint)
+		seq.sendTransport()
 	} else if ev.Type == IncreaseBPM {
 		seq.BPM += 10
 		fmt.Println("bpm =", seq.BPM)
@@ -233,26 +430,62 @@ func (seq *Sequencer) handleEvent(ev *SequencerEvent, s chan *synth.Event) {
 			seq.BPM = 1
 		}
 		fmt.Println("bpm =", seq.BPM)
+	} else if ev.Type == SetBPM {
+		seq.BPM = ev.Value.(float64)
+		if seq.BPM <= 0 {
+			seq.BPM = 1
+		}
+	} else if ev.Type == SetRegister {
+		r := ev.Value.(*registerValue)
+		if r.Register >= 0 && r.Register < len(seq.Status.IntRegisters) {
+			seq.Status.IntRegisters[r.Register] = int(r.Value)
+		}
+	} else if ev.Type == SetFloatRegister {
+		r := ev.Value.(*registerValue)
+		if r.Register >= 0 && r.Register < len(seq.Status.FloatRegisters) {
+			seq.Status.FloatRegisters[r.Register] = r.Value
+		}
 	} else if ev.Type == QuitSequencer {
 		seq.Status.ResetTime()
 		return
 	} else if ev.Type == StartPlaying {
 		fmt.Println("Start playing")
 		seq.Status.Playing = true
+		seq.sendTransport()
 	} else if ev.Type == StopPlaying {
 		fmt.Println("Stop playing")
 		s <- synth.NewEvent(synth.SilenceAllChannels, 0, nil)
 		seq.Status.Playing = false
 		seq.Status.ResetTime()
+		seq.sendTransport()
 	} else if ev.Type == PausePlaying {
 		fmt.Println("Toggle seq.Status.Playing", seq.Status.Playing)
 		if seq.Status.Playing {
 			s <- synth.NewEvent(synth.SilenceAllChannels, 0, nil)
 		}
 		seq.Status.Playing = !seq.Status.Playing
+		seq.sendTransport()
 	} else if ev.Type == RewindSequencer {
 		fmt.Println("Rewind")
 		seq.Status.ResetTime()
+		seq.sendTransport()
+	} else if ev.Type == LiveEvent {
+		if p, ok := seq.Processors[ev.Event.Channel]; ok {
+			for _, e := range p.Process(&seq.Status, ev.Event) {
+				s <- e
+			}
+		} else {
+			s <- ev.Event
+		}
+	} else if ev.Type == SetClockOutput {
+		seq.ClockOutput = ev.Value.(*midi.ClockOutput)
+		seq.clockRunning = false
+		seq.sendTransport()
+	} else if ev.Type == SetClockSlave {
+		seq.ClockSlave = ev.Value.(bool)
+		seq.lastClockPulse = time.Time{}
+	} else if ev.Type == ClockEvent {
+		seq.handleClock(ev.Value.(*clockMessage), s)
 	}
 }
 
@@ -301,6 +534,37 @@ func (seq *Sequencer) IncreaseBPM() {
 func (seq *Sequencer) DecreaseBPM() {
 	seq.Inputs <- NewSequencerEvent(DecreaseBPM)
 }
+func (seq *Sequencer) SetBPM(bpm float64) {
+	ev := NewSequencerEvent(SetBPM)
+	ev.Value = bpm
+	seq.Inputs <- ev
+}
+
+type registerValue struct {
+	Register int
+	Value    float64
+}
+
+// SetRegister sets an int register, so that sequences and automations
+// reading the register can be controlled live.
+func (seq *Sequencer) SetRegister(register, value int) {
+	ev := NewSequencerEvent(SetRegister)
+	ev.Value = &registerValue{register, float64(value)}
+	seq.Inputs <- ev
+}
+func (seq *Sequencer) SetFloatRegister(register int, value float64) {
+	ev := NewSequencerEvent(SetFloatRegister)
+	ev.Value = &registerValue{register, value}
+	seq.Inputs <- ev
+}
+
+// HandleLiveEvent passes an event from a live input, such as a MIDI keyboard,
+// through the input processors configured for its channel.
+func (seq *Sequencer) HandleLiveEvent(ev *synth.Event) {
+	e := NewSequencerEvent(LiveEvent)
+	e.Event = ev
+	seq.Inputs <- e
+}
 func (seq *Sequencer) Quit() {
 	seq.Inputs <- NewSequencerEvent(QuitSequencer)
 }
@@ -0,0 +1,110 @@
+package sequencer
+
+import (
+	"testing"
+
+	"github.com/bspaans/bleep/instruments"
+	"github.com/bspaans/bleep/sequencer/definitions"
+	"github.com/bspaans/bleep/synth"
+)
+
+const testSequencerDef = `
+bpm: 120
+granularity: 4
+channels:
+- channel: 1
+  instrument: 0
+  reverb_time: Eight
+  mute: true
+  gate:
+    threshold: -40
+- channel: 9
+  reverb_time: Eight
+  solo_safe: true
+sequences:
+- play_note:
+    every: 1
+    channel: 1
+    note: 60
+    duration: 1
+    velocity: 100
+`
+
+func newTestSequencer(t *testing.T) *Sequencer {
+	def, err := definitions.NewSequencerDefFromYaml([]byte(testSequencerDef), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewSequencerFromDefinition(def)
+}
+
+// countEvents steps the sequencer once and counts the events of each type.
+func countEvents(seq *Sequencer) map[synth.EventType]int {
+	result := map[synth.EventType]int{}
+	seq.Step(func(ev *synth.Event) {
+		result[ev.Type]++
+	})
+	return result
+}
+
+func Test_Sequencer_sets_up_the_mixer_only_when_loaded(t *testing.T) {
+	seq := newTestSequencer(t)
+	if counts := countEvents(seq); counts[synth.SetMute] != 2 || counts[synth.SetSoloSafe] != 2 || counts[synth.SetChannelDynamics] != 2 {
+		t.Fatalf("Expecting the channel setup to be applied, got %v", counts)
+	}
+	seq.Status.Time = 0
+	counts := countEvents(seq)
+	if counts[synth.SetMute] != 0 || counts[synth.SetSolo] != 0 || counts[synth.SetSoloSafe] != 0 {
+		t.Errorf("Expecting a rewind to keep the live mute and solo, got %v", counts)
+	}
+	if counts[synth.SetChannelDynamics] != 0 {
+		t.Errorf("Expecting a rewind to keep the channel dynamics, got %v", counts)
+	}
+	if counts[synth.ProgramChange] != 1 {
+		t.Errorf("Expecting a rewind to reload the instruments, got %v", counts)
+	}
+	seq.instantiateFromSequencerDef(seq.SequencerDef)
+	if counts := countEvents(seq); counts[synth.SetMute] != 2 {
+		t.Errorf("Expecting a reload to apply the channel setup again, got %v", counts)
+	}
+}
+
+// masterBuses steps the sequencer once and returns the master buses it sets.
+func masterBuses(seq *Sequencer) []*synth.MasterBus {
+	result := []*synth.MasterBus{}
+	seq.Step(func(ev *synth.Event) {
+		if ev.Type == synth.SetMasterBus {
+			result = append(result, ev.MasterBus)
+		}
+	})
+	return result
+}
+
+func Test_Sequencer_sets_the_master_bus_only_when_loaded(t *testing.T) {
+	seq := newTestSequencer(t)
+	seq.SequencerDef.Master = &definitions.MasterDef{
+		Limiter: &instruments.LimiterOptionsDef{Ceiling: -1},
+	}
+	seq.instantiateFromSequencerDef(seq.SequencerDef)
+	if buses := masterBuses(seq); len(buses) != 1 || buses[0] == nil {
+		t.Fatalf("Expecting the master bus to be set, got %v", buses)
+	}
+	seq.Status.Time = 0
+	if buses := masterBuses(seq); len(buses) != 0 {
+		t.Errorf("Expecting a rewind to keep the master bus, got %v", buses)
+	}
+}
+
+func Test_Sequencer_falls_back_to_the_default_master_bus(t *testing.T) {
+	seq := newTestSequencer(t)
+	if buses := masterBuses(seq); len(buses) != 1 || buses[0] != nil {
+		t.Errorf("Expecting the master bus to be removed, got %v", buses)
+	}
+	seq.DefaultMaster = &definitions.MasterDef{
+		Limiter: &instruments.LimiterOptionsDef{Ceiling: -1},
+	}
+	seq.instantiateFromSequencerDef(seq.SequencerDef)
+	if buses := masterBuses(seq); len(buses) != 1 || buses[0] == nil {
+		t.Errorf("Expecting the default master bus, got %v", buses)
+	}
+}
@@ -0,0 +1,76 @@
+package sequences
+
+import (
+	"sort"
+
+	. "github.com/bspaans/bleep/sequencer/automations"
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/synth"
+)
+
+var ArpeggioModes = []string{"up", "down", "updown", "random", "as_played"}
+
+// ArpeggioPattern returns the order in which the notes should be played for
+// the given mode, spread out over a number of octaves. The "random" mode
+// returns the notes in ascending order; picking one is left to the caller.
+func ArpeggioPattern(notes []int, mode string, octaves int) []int {
+	if octaves < 1 {
+		octaves = 1
+	}
+	ordered := make([]int, len(notes))
+	copy(ordered, notes)
+	if mode != "as_played" {
+		sort.Ints(ordered)
+	}
+	result := []int{}
+	for o := 0; o < octaves; o++ {
+		for _, note := range ordered {
+			result = append(result, note+12*o)
+		}
+	}
+	if mode == "down" {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	} else if mode == "updown" && len(result) > 2 {
+		for i := len(result) - 2; i > 0; i-- {
+			result = append(result, result[i])
+		}
+	}
+	return result
+}
+
+// Arpeggio plays the notes produced by notesF one at a time every `rate`
+// ticks. The notes automation is evaluated with the arpeggiator's step
+// counter. If latch is set, the last notes are held when notesF returns an
+// empty array.
+func Arpeggio(channel int, rate, duration uint, mode string, octaves int, latch bool, notesF IntArrayAutomation, velocityF IntAutomation) Sequence {
+	latched := []int{}
+	return func(status *Status, counter, t uint, s Sink) {
+		if t%rate != 0 {
+			return
+		}
+		step := t / rate
+		notes := notesF(status, step, t)
+		if len(notes) == 0 && latch {
+			notes = latched
+		} else if latch {
+			latched = notes
+		}
+		pattern := ArpeggioPattern(notes, mode, octaves)
+		if len(pattern) == 0 {
+			return
+		}
+		var note int
+		if mode == "random" {
+			note = pattern[status.Random.Intn(len(pattern))]
+		} else {
+			note = pattern[step%uint(len(pattern))]
+		}
+		if note < 0 || note > 127 {
+			return
+		}
+		s(synth.NewEvent(synth.NoteOn, channel, []int{note, velocityF(status, step, t)}))
+		status.ScheduleEvent(duration, synth.NewEvent(synth.NoteOff, channel, []int{note}))
+	}
+}
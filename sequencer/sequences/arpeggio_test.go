@@ -0,0 +1,66 @@
+package sequences
+
+import (
+	"testing"
+
+	. "github.com/bspaans/bleep/sequencer/automations"
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/synth"
+)
+
+func Test_ArpeggioPattern(t *testing.T) {
+	cases := []struct {
+		Mode     string
+		Octaves  int
+		Expected []int
+	}{
+		{"up", 1, []int{60, 64, 67}},
+		{"down", 1, []int{67, 64, 60}},
+		{"updown", 1, []int{60, 64, 67, 64}},
+		{"as_played", 1, []int{64, 60, 67}},
+		{"up", 2, []int{60, 64, 67, 72, 76, 79}},
+		{"updown", 2, []int{60, 64, 67, 72, 76, 79, 76, 72, 67, 64}},
+	}
+	for _, c := range cases {
+		unit := ArpeggioPattern([]int{64, 60, 67}, c.Mode, c.Octaves)
+		if len(unit) != len(c.Expected) {
+			t.Errorf("Expecting %v for %s, got %v", c.Expected, c.Mode, unit)
+			continue
+		}
+		for i, e := range c.Expected {
+			if unit[i] != e {
+				t.Errorf("Expecting %v for %s, got %v", c.Expected, c.Mode, unit)
+				break
+			}
+		}
+	}
+}
+
+func Test_Arpeggio_latch(t *testing.T) {
+	status := NewStatus(120, 4)
+	notesF := func(s *Status, counter, t uint) []int {
+		if counter < 2 {
+			return []int{60, 64}
+		}
+		return []int{}
+	}
+	unit := Arpeggio(1, 2, 1, "up", 1, true, notesF, IntIdAutomation(100))
+	events := playNotes(unit, &status, 8)
+	expected := []int{60, 64, 60, 64}
+	if len(events) != len(expected) {
+		t.Fatalf("Expecting %d notes, got %d", len(expected), len(events))
+	}
+	for i, e := range expected {
+		if events[i].Type != synth.NoteOn || events[i].Values[0] != e {
+			t.Errorf("Expecting note %d, got %d", e, events[i].Values[0])
+		}
+	}
+	if len(status.ScheduledEvents) != 4 {
+		t.Errorf("Expecting note offs to be scheduled")
+	}
+
+	unit = Arpeggio(1, 2, 1, "up", 1, false, notesF, IntIdAutomation(100))
+	if events := playNotes(unit, &status, 8); len(events) != 2 {
+		t.Errorf("Expecting 2 notes without latch, got %d", len(events))
+	}
+}
@@ -0,0 +1,210 @@
+package sequences
+
+import (
+	"sort"
+
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/synth"
+)
+
+// Gate plays seq as normal when the condition holds. When it doesn't only the
+// note off events are passed on, so that notes that are already playing still
+// get stopped. Note that register changes are not affected.
+func Gate(condition func(status *Status, t uint) bool, seq Sequence) Sequence {
+	return func(status *Status, counter, t uint, s Sink) {
+		if condition(status, t) {
+			seq(status, counter, t, s)
+		} else {
+			mute(seq, status, counter, t, s)
+		}
+	}
+}
+
+// mute runs seq, but only passes on its note off events.
+func mute(seq Sequence, status *Status, counter, t uint, s Sink) {
+	interceptEvents(seq, func(ev *synth.Event) bool {
+		return ev.Type == synth.NoteOff
+	}, status, counter, t, s)
+}
+
+// If plays then when the condition holds and otherwise (which can be nil)
+// when it doesn't. The branch that isn't selected isn't played at all; when
+// a branch gets deselected the notes it left sounding are stopped.
+func If(condition func(status *Status, t uint) bool, then, otherwise Sequence) Sequence {
+	thenBranch, otherwiseBranch := newBranch(then), newBranch(otherwise)
+	return func(status *Status, counter, t uint, s Sink) {
+		if condition(status, t) {
+			otherwiseBranch.stop(status, s)
+			thenBranch.play(status, counter, t, s)
+		} else {
+			thenBranch.stop(status, s)
+			otherwiseBranch.play(status, counter, t, s)
+		}
+	}
+}
+
+// SwitchOnRegister plays cases[i] while the int register holds values[i].
+// The default sequence (which can be nil) is played when none of the values
+// match. Like in If, only the selected sequence is played.
+func SwitchOnRegister(register int, values []int, cases []Sequence, def Sequence) Sequence {
+	branches := make([]*branch, len(cases)+1)
+	for i, seq := range cases {
+		branches[i] = newBranch(seq)
+	}
+	branches[len(cases)] = newBranch(def)
+	return func(status *Status, counter, t uint, s Sink) {
+		value := status.IntRegisters[register]
+		selected := len(cases)
+		for i, v := range values {
+			if v == value {
+				selected = i
+				break
+			}
+		}
+		for i, b := range branches {
+			if i != selected {
+				b.stop(status, s)
+			}
+		}
+		branches[selected].play(status, counter, t, s)
+	}
+}
+
+// A branch keeps track of the notes that a sequence leaves sounding, so that
+// they can be stopped when the sequence isn't selected anymore. A note is
+// sounding from its note on until its note off is played, or until the note
+// off that the sequence scheduled is due.
+type branch struct {
+	seq      Sequence
+	active   bool
+	time     uint
+	sounding map[[2]int]bool
+	noteOffs []*ScheduledEvent
+}
+
+func newBranch(seq Sequence) *branch {
+	return &branch{
+		seq:      seq,
+		sounding: map[[2]int]bool{},
+	}
+}
+
+func (b *branch) play(status *Status, counter, t uint, s Sink) {
+	b.update(status)
+	b.active = true
+	if b.seq == nil {
+		return
+	}
+	scheduled := len(status.ScheduledEvents)
+	b.seq(status, counter, t, func(ev *synth.Event) {
+		if ev.Type == synth.NoteOn {
+			b.sounding[noteKey(ev)] = true
+		} else if ev.Type == synth.NoteOff {
+			delete(b.sounding, noteKey(ev))
+		}
+		s(ev)
+	})
+	for _, ev := range status.ScheduledEvents[scheduled:] {
+		if ev.Event.Type == synth.NoteOff {
+			b.noteOffs = append(b.noteOffs, ev)
+		}
+	}
+}
+
+// stop sends note offs for the notes that are still sounding, and cancels
+// the note offs the branch scheduled for them, so that they can't stop
+// notes that are played later on.
+func (b *branch) stop(status *Status, s Sink) {
+	if !b.active {
+		return
+	}
+	b.update(status)
+	b.active = false
+	keys := [][2]int{}
+	for key := range b.sounding {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || (keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1])
+	})
+	for _, key := range keys {
+		s(synth.NewEvent(synth.NoteOff, key[0], []int{key[1]}))
+	}
+	cancelled := map[*ScheduledEvent]bool{}
+	for _, ev := range b.noteOffs {
+		cancelled[ev] = true
+	}
+	remaining := []*ScheduledEvent{}
+	for _, ev := range status.ScheduledEvents {
+		if !cancelled[ev] {
+			remaining = append(remaining, ev)
+		}
+	}
+	status.ScheduledEvents = remaining
+	b.reset()
+}
+
+// update forgets the notes whose scheduled note offs have been played. When
+// the time goes back the sequencer has silenced all the channels, so
+// everything is forgotten.
+func (b *branch) update(status *Status) {
+	if status.Time < b.time {
+		b.reset()
+	}
+	b.time = status.Time
+	pending := []*ScheduledEvent{}
+	for _, ev := range b.noteOffs {
+		if ev.When <= status.Time {
+			delete(b.sounding, noteKey(ev.Event))
+		} else {
+			pending = append(pending, ev)
+		}
+	}
+	b.noteOffs = pending
+}
+
+func (b *branch) reset() {
+	b.sounding = map[[2]int]bool{}
+	b.noteOffs = nil
+}
+
+func noteKey(ev *synth.Event) [2]int {
+	note := 0
+	if len(ev.Values) > 0 {
+		note = ev.Values[0]
+	}
+	return [2]int{ev.Channel, note}
+}
+
+// Chance plays the events of seq with probability p.
+func Chance(p float64, seq Sequence) Sequence {
+	return Gate(func(status *Status, t uint) bool {
+		return status.Random.Float64() < p
+	}, seq)
+}
+
+// EveryNOfM plays seq in the nth out of every m cycles, like the conditional
+// trigs on Elektron machines (e.g. "1:4"). The first cycle is 1.
+func EveryNOfM(n, m int, cycle uint, seq Sequence) Sequence {
+	return Gate(func(status *Status, t uint) bool {
+		return int((t/cycle)%uint(m)) == n-1
+	}, seq)
+}
+
+// Fill only plays seq in the last `length` ticks of every section.
+func Fill(section, length uint, seq Sequence) Sequence {
+	return Gate(func(status *Status, t uint) bool {
+		return t%section >= section-length
+	}, seq)
+}
+
+// Humanize moves the events in seq up to timing ticks away from the grid and
+// changes their velocities by up to velocity. The changes are random, but
+// the same every time the sequence is played back with the same seed.
+func Humanize(stepLength uint, timing float64, velocity int, seq Sequence) Sequence {
+	return GrooveSequence(&Groove{
+		StepLength:     stepLength,
+		TimingJitter:   timing,
+		VelocityJitter: velocity,
+	}, seq)
+}
@@ -0,0 +1,208 @@
+package sequences
+
+import (
+	"testing"
+
+	. "github.com/bspaans/bleep/sequencer/automations"
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/synth"
+)
+
+func playNotes(unit Sequence, status *Status, ticks uint) []*synth.Event {
+	result := []*synth.Event{}
+	for i := uint(0); i < ticks; i++ {
+		unit(status, i, i, func(ev *synth.Event) {
+			result = append(result, ev)
+		})
+	}
+	return result
+}
+
+func Test_Gate_passes_note_offs(t *testing.T) {
+	unit := Gate(func(status *Status, t uint) bool {
+		return false
+	}, Combine(NoteOn(1, 60, 100), NoteOff(1, 60)))
+	events := playNotes(unit, nil, 4)
+	if len(events) != 4 {
+		t.Fatalf("Expecting 4 events, got %d", len(events))
+	}
+	for _, ev := range events {
+		if ev.Type != synth.NoteOff {
+			t.Errorf("Expecting only note off events")
+		}
+	}
+}
+
+func Test_Chance_is_reproducible(t *testing.T) {
+	status := NewStatus(120, 4)
+	status.SetSeed(42)
+	unit := Chance(0.5, NoteOn(1, 60, 100))
+	first := playNotes(unit, &status, 1000)
+	if len(first) < 400 || len(first) > 600 {
+		t.Errorf("Expecting roughly half of the notes to be played, got %d", len(first))
+	}
+	status.ResetTime()
+	second := playNotes(unit, &status, 1000)
+	if len(first) != len(second) {
+		t.Errorf("Expecting the same number of notes after a reset, got %d and %d", len(first), len(second))
+	}
+}
+
+func Test_EveryNOfM(t *testing.T) {
+	unit := EveryNOfM(2, 3, 4, Every(4, NoteOn(1, 60, 100)))
+	events := playNotes(unit, nil, 24)
+	if len(events) != 2 {
+		t.Errorf("Expecting 2 events, got %d", len(events))
+	}
+}
+
+func Test_Fill(t *testing.T) {
+	f := func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewEvent(synth.NoteOn, 1, []int{int(t), 100}))
+	}
+	events := playNotes(Fill(16, 4, f), nil, 32)
+	expected := []int{12, 13, 14, 15, 28, 29, 30, 31}
+	if len(events) != len(expected) {
+		t.Fatalf("Expecting %d events, got %d", len(expected), len(events))
+	}
+	for i, e := range expected {
+		if events[i].Values[0] != e {
+			t.Errorf("Expecting event at t=%d, got t=%d", e, events[i].Values[0])
+		}
+	}
+}
+
+func Test_Humanize(t *testing.T) {
+	status := NewStatus(120, 16)
+	status.SetSeed(1)
+	unit := Humanize(4, 1.9, 10, Every(2, NoteOn(1, 60, 100)))
+	first := playNotes(unit, &status, 200)
+	second := playNotes(unit, &status, 200)
+	if len(first) < 95 || len(first) != len(second) {
+		t.Fatalf("Expecting the same events every time, got %d and %d", len(first), len(second))
+	}
+	changed := false
+	for i, ev := range first {
+		if ev.Values[1] != second[i].Values[1] {
+			t.Errorf("Expecting the same velocities every time")
+		}
+		if ev.Values[1] < 90 || ev.Values[1] > 110 {
+			t.Errorf("Velocity %d out of range", ev.Values[1])
+		}
+		if ev.Values[1] != 100 {
+			changed = true
+		}
+	}
+	if !changed {
+		t.Errorf("Expecting velocities to be humanized")
+	}
+}
+
+func noteOnNotes(events []*synth.Event) []int {
+	result := []int{}
+	for _, ev := range events {
+		if ev.Type == synth.NoteOn {
+			result = append(result, ev.Values[0])
+		}
+	}
+	return result
+}
+
+func Test_If(t *testing.T) {
+	status := NewStatus(120, 4)
+	unit := Combine(
+		SetIntRegisterAutomation(0, func(s *Status, counter, t uint) int {
+			if t >= 2 {
+				return 1
+			}
+			return 0
+		}),
+		If(func(status *Status, t uint) bool {
+			return status.IntRegisters[0] == 1
+		}, NoteOn(1, 72, 100), NoteOn(1, 48, 100)),
+	)
+	got := noteOnNotes(playNotes(unit, &status, 4))
+	expected := []int{48, 48, 72, 72}
+	if len(got) != len(expected) {
+		t.Fatalf("Expecting %v, got %v", expected, got)
+	}
+	for i, e := range expected {
+		if got[i] != e {
+			t.Errorf("Expecting %v, got %v", expected, got)
+		}
+	}
+}
+
+func Test_SwitchOnRegister(t *testing.T) {
+	status := NewStatus(120, 4)
+	unit := SwitchOnRegister(3, []int{0, 1}, []Sequence{NoteOn(1, 60, 100), NoteOn(1, 62, 100)}, Combine(NoteOn(1, 64, 100), NoteOff(1, 64)))
+	for _, c := range []struct{ value, note int }{{0, 60}, {1, 62}, {5, 64}} {
+		status.IntRegisters[3] = c.value
+		got := noteOnNotes(playNotes(unit, &status, 1))
+		if len(got) != 1 || got[0] != c.note {
+			t.Errorf("Expecting [%d] for register value %d, got %v", c.note, c.value, got)
+		}
+	}
+	// The default case stops its own note, so switching back to the first
+	// case has nothing else to stop.
+	status.IntRegisters[3] = 0
+	events := playNotes(unit, &status, 1)
+	if len(events) != 1 || events[0].Type != synth.NoteOn || events[0].Values[0] != 60 {
+		t.Errorf("Expecting only the selected case to be played, got %v", events)
+	}
+}
+
+// playTicks plays unit like the sequencer does, including the scheduled
+// events.
+func playTicks(unit Sequence, status *Status, ticks uint) []*synth.Event {
+	result := []*synth.Event{}
+	sink := func(ev *synth.Event) {
+		result = append(result, ev)
+	}
+	for i := uint(0); i < ticks; i++ {
+		for _, ev := range status.GetScheduledEvents(status.Time) {
+			sink(ev.Event)
+		}
+		unit(status, status.Time, status.Time, sink)
+		status.IncrementTime()
+	}
+	return result
+}
+
+func Test_If_doesnt_play_the_other_branch(t *testing.T) {
+	status := NewStatus(120, 4)
+	unit := If(func(status *Status, t uint) bool {
+		return true
+	}, NoteOn(1, 60, 100), SetIntRegisterAutomation(1, IntIdAutomation(5)))
+	playTicks(unit, &status, 4)
+	if status.IntRegisters[1] != 0 {
+		t.Errorf("Expecting the else branch not to change the register, got %d", status.IntRegisters[1])
+	}
+}
+
+func Test_If_stops_the_notes_of_the_deselected_branch(t *testing.T) {
+	status := NewStatus(120, 4)
+	unit := If(func(status *Status, t uint) bool {
+		return !(t >= 3 && t < 6) && t < 9
+	}, Every(4, PlayNote(2, 1, 60, 100)), Every(4, NoteOn(1, 48, 100)))
+	events := playTicks(unit, &status, 12)
+	expected := []struct {
+		Type synth.EventType
+		Note int
+	}{
+		{synth.NoteOn, 60},
+		{synth.NoteOff, 60},
+		{synth.NoteOn, 48},
+		{synth.NoteOff, 48},
+		{synth.NoteOn, 60},
+		{synth.NoteOff, 60},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expecting %d events, got %d", len(expected), len(events))
+	}
+	for i, e := range expected {
+		if events[i].Type != e.Type || events[i].Values[0] != e.Note {
+			t.Errorf("Expecting event %d to be %v on %d, got %v on %d", i, e.Type, e.Note, events[i].Type, events[i].Values[0])
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package sequences
+
+import (
+	"math"
+
+	"github.com/bspaans/bleep/midi"
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/synth"
+	"gitlab.com/gomidi/midi/midimessage/channel"
+)
+
+// A Groove describes how far each step in a repeating pattern of steps should
+// be moved away from the grid (in ticks), and how much the velocity of the
+// notes starting in that step should change. Ticks in between steps are
+// moved proportionally, so the order of events is preserved.
+type Groove struct {
+	StepLength uint
+	Timing     []float64
+	Velocity   []int
+
+	// Random offsets of up to TimingJitter ticks and VelocityJitter are added
+	// to each step. They are derived from the sequencer's seed, so they are
+	// the same every time the sequence is played back. TimingJitter should
+	// be less than half a step to keep the events in order.
+	TimingJitter   float64
+	VelocityJitter int
+}
+
+// NewSwingGroove delays every second step. A swing of 0.5 is straight time,
+// 2/3 is triplet swing.
+func NewSwingGroove(stepLength uint, swing float64) *Groove {
+	return &Groove{
+		StepLength: stepLength,
+		Timing:     []float64{0.0, (swing - 0.5) * 2 * float64(stepLength)},
+		Velocity:   []int{},
+	}
+}
+
+// GrooveFromMIDI extracts a groove template from the note on events in a MIDI
+// file by comparing each note to the nearest step on the grid. The template
+// is averaged over a pattern of `steps` steps.
+func GrooveFromMIDI(mid *midi.MIDISequences, granularity int, stepLength uint, steps int) *Groove {
+	timing := make([]float64, steps)
+	velocities := make([]float64, steps)
+	counts := make([]int, steps)
+	totalVelocity, total := 0.0, 0
+
+	ticksPerMIDITick := float64(granularity) / float64(mid.TimeFormat)
+	for _, ch := range mid.Channels {
+		if ch == nil {
+			continue
+		}
+		for _, ev := range ch.Events {
+			noteOn, ok := ev.Message.(channel.NoteOn)
+			if !ok || noteOn.Velocity() == 0 {
+				continue
+			}
+			t := float64(ev.Offset) * ticksPerMIDITick
+			step := int(math.Round(t / float64(stepLength)))
+			slot := step % steps
+			timing[slot] += t - float64(step)*float64(stepLength)
+			velocities[slot] += float64(noteOn.Velocity())
+			counts[slot]++
+			totalVelocity += float64(noteOn.Velocity())
+			total++
+		}
+	}
+	groove := &Groove{
+		StepLength: stepLength,
+		Timing:     make([]float64, steps),
+		Velocity:   make([]int, steps),
+	}
+	if total == 0 {
+		return groove
+	}
+	average := totalVelocity / float64(total)
+	for i := 0; i < steps; i++ {
+		if counts[i] > 0 {
+			groove.Timing[i] = timing[i] / float64(counts[i])
+			groove.Velocity[i] = int(math.Round(velocities[i]/float64(counts[i]) - average))
+		}
+	}
+	return groove
+}
+
+// Add the timing and velocity offsets of another groove with the same step
+// length to this one.
+func (g *Groove) Add(other *Groove) *Groove {
+	l := len(g.Timing)
+	if len(other.Timing) > l {
+		l = len(other.Timing)
+	}
+	if len(g.Velocity) > l {
+		l = len(g.Velocity)
+	}
+	if len(other.Velocity) > l {
+		l = len(other.Velocity)
+	}
+	result := &Groove{
+		StepLength:     g.StepLength,
+		Timing:         make([]float64, l),
+		Velocity:       make([]int, l),
+		TimingJitter:   g.TimingJitter + other.TimingJitter,
+		VelocityJitter: g.VelocityJitter + other.VelocityJitter,
+	}
+	for i := 0; i < l; i++ {
+		result.Timing[i] = g.timingForStep(i) + other.timingForStep(i)
+		result.Velocity[i] = g.velocityForStep(i) + other.velocityForStep(i)
+	}
+	return result
+}
+
+func (g *Groove) timingForStep(step int) float64 {
+	if len(g.Timing) == 0 {
+		return 0.0
+	}
+	return g.Timing[step%len(g.Timing)]
+}
+
+func (g *Groove) velocityForStep(step int) int {
+	if len(g.Velocity) == 0 {
+		return 0
+	}
+	return g.Velocity[step%len(g.Velocity)]
+}
+
+func (g *Groove) timing(step int, seed int64) float64 {
+	result := g.timingForStep(step)
+	if g.TimingJitter != 0.0 {
+		result += g.TimingJitter * noise(seed, step, 0)
+	}
+	return result
+}
+
+func (g *Groove) velocity(step int, seed int64) int {
+	result := g.velocityForStep(step)
+	if g.VelocityJitter != 0 {
+		result += int(math.Round(float64(g.VelocityJitter) * noise(seed, step, 1)))
+	}
+	return result
+}
+
+// Returns the time at which an event that would have happened at t should be
+// played. Events that would be moved before the start are played at 0.
+func (g *Groove) apply(t uint, seed int64) int {
+	step := int(t / g.StepLength)
+	progress := float64(t%g.StepLength) / float64(g.StepLength)
+	from := g.timing(step, seed)
+	to := g.timing(step+1, seed)
+	return int(math.Max(0, math.Round(float64(t)+from+progress*(to-from))))
+}
+
+func (g *Groove) maxShift() int {
+	result := 0.0
+	for _, offset := range g.Timing {
+		result = math.Max(result, math.Abs(offset))
+	}
+	return int(math.Ceil(result+math.Abs(g.TimingJitter))) + 1
+}
+
+// noise returns a pseudo random number between -1.0 and 1.0 that only depends
+// on its arguments (using the splitmix64 finalizer).
+func noise(seed int64, step int, stream uint64) float64 {
+	x := uint64(seed) ^ (uint64(step) * 0x9E3779B97F4A7C15) ^ (stream << 56)
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	x ^= x >> 31
+	return float64(x>>11)/float64(1<<53)*2 - 1
+}
+
+// GrooveSequence plays seq with the timing and velocity of the groove. Events
+// that should be played before they would normally happen are found by
+// looking ahead, so the wrapped sequence should only depend on its time
+// arguments.
+func GrooveSequence(groove *Groove, seq Sequence) Sequence {
+	if groove.StepLength == 0 {
+		return seq
+	}
+	shift := groove.maxShift()
+	return func(status *Status, counter, t uint, s Sink) {
+		seed := int64(0)
+		if status != nil {
+			seed = status.Seed
+		}
+		from := int(t) - shift
+		if from < 0 {
+			from = 0
+		}
+		for straight := from; straight <= int(t)+shift; straight++ {
+			if groove.apply(uint(straight), seed) != int(t) {
+				continue
+			}
+			velocity := groove.velocity(straight/int(groove.StepLength), seed)
+			if velocity == 0 {
+				seq(status, uint(straight), uint(straight), s)
+			} else {
+				interceptEvents(seq, func(ev *synth.Event) bool {
+					if ev.Type == synth.NoteOn && len(ev.Values) > 1 {
+						ev.Values[1] = clampVelocity(ev.Values[1] + velocity)
+					}
+					return true
+				}, status, uint(straight), uint(straight), s)
+			}
+		}
+	}
+}
+
+func clampVelocity(velocity int) int {
+	if velocity < 1 {
+		return 1
+	} else if velocity > 127 {
+		return 127
+	}
+	return velocity
+}
+
+// interceptEvents runs seq with a sink that lets f modify the events before
+// they are passed on to s. Events are dropped if f returns false.
+func interceptEvents(seq Sequence, f func(ev *synth.Event) bool, status *Status, counter, t uint, s Sink) {
+	seq(status, counter, t, func(ev *synth.Event) {
+		if f(ev) {
+			s(ev)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package sequences
+
+import (
+	"testing"
+
+	. "github.com/bspaans/bleep/sequencer/status"
+)
+
+func Test_GrooveSequence_swing(t *testing.T) {
+	calledAt := []uint{}
+	f := func(status *Status, counter, t uint, s Sink) {
+		if t%8 == 0 {
+			calledAt = append(calledAt, t)
+		}
+	}
+	unit := GrooveSequence(NewSwingGroove(8, 0.75), f)
+	playedAt := []uint{}
+	for i := uint(0); i < 32; i++ {
+		before := len(calledAt)
+		unit(nil, i, i, nil)
+		if len(calledAt) != before {
+			playedAt = append(playedAt, i)
+		}
+	}
+	expected := []uint{0, 12, 16, 28}
+	if len(playedAt) != len(expected) {
+		t.Fatalf("Expecting events at %v, got %v", expected, playedAt)
+	}
+	for i, e := range expected {
+		if playedAt[i] != e {
+			t.Errorf("Expecting event %d at t=%d, got t=%d", i, e, playedAt[i])
+		}
+	}
+}
+
+func Test_GrooveSequence_every_tick_is_played_once(t *testing.T) {
+	called := map[uint]int{}
+	f := func(status *Status, counter, t uint, s Sink) {
+		called[t]++
+	}
+	groove := &Groove{StepLength: 4, Timing: []float64{0, 1.5, -1, 2}}
+	unit := GrooveSequence(groove, f)
+	for i := uint(0); i < 100; i++ {
+		unit(nil, i, i, nil)
+	}
+	for i := uint(0); i < 90; i++ {
+		if called[i] != 1 {
+			t.Errorf("Expecting t=%d to be played once, got %d", i, called[i])
+		}
+	}
+}
+
+func Test_GrooveSequence_velocity(t *testing.T) {
+	groove := &Groove{StepLength: 4, Timing: []float64{}, Velocity: []int{10, -20}}
+	unit := GrooveSequence(groove, Every(4, NoteOn(1, 60, 100)))
+	events := playNotes(unit, nil, 8)
+	if len(events) != 2 {
+		t.Fatalf("Expecting two events, got %d", len(events))
+	}
+	for i, expected := range []int{110, 80} {
+		if events[i].Values[1] != expected {
+			t.Errorf("Expecting velocity %d, got %d", expected, events[i].Values[1])
+		}
+	}
+}
+
+func Test_GrooveSequence_clamps_to_the_start(t *testing.T) {
+	groove := &Groove{StepLength: 4, Timing: []float64{-2}, Velocity: []int{10}}
+	unit := GrooveSequence(groove, Every(4, NoteOn(1, 60, 100)))
+	events := playNotes(unit, nil, 4)
+	if len(events) != 2 {
+		t.Fatalf("Expecting the first note to be played at the start, got %d events", len(events))
+	}
+	if events[0].Values[1] != 110 {
+		t.Errorf("Expecting velocity 110, got %d", events[0].Values[1])
+	}
+}
@@ -6,32 +6,47 @@ import (
 	"github.com/bspaans/bleep/midi"
 	. "github.com/bspaans/bleep/sequencer/status"
 	"github.com/bspaans/bleep/synth"
-	"github.com/gomidi/midi/midimessage/channel"
+	"gitlab.com/gomidi/midi/midimessage/channel"
+	"gitlab.com/gomidi/midi/midimessage/meta"
 )
 
-func MidiSequence(mid *midi.MIDISequences, inputChannels, outputChannels []int, speed float64, loop bool) Sequence {
+// MidiSequence plays back the events in a MIDI file. If followTempo is set the
+// tempo changes in the file are applied to the sequencer. The speed is
+// applied on top of the tempo, because it already changes how fast the file
+// is read.
+func MidiSequence(mid *midi.MIDISequences, inputChannels, outputChannels []int, speed float64, loop, followTempo bool) Sequence {
 	inputCh := map[int]bool{}
 	for _, i := range inputChannels {
 		inputCh[i] = true
 	}
-	sendEvent := func(s chan *synth.Event, fromChannel int, ty synth.EventType, params []int) {
+	sendEvent := func(s Sink, fromChannel int, ty synth.EventType, params []int) {
 		if len(outputChannels) == 0 {
-			s <- synth.NewEvent(ty, fromChannel, params)
+			s(synth.NewEvent(ty, fromChannel, params))
 		} else {
 			for _, o := range outputChannels {
-				s <- synth.NewEvent(ty, o, params)
+				s(synth.NewEvent(ty, o, params))
 			}
 		}
 	}
 	if speed == 0.0 {
 		speed = 1.0
 	}
-	return func(sequencer *Status, counter, t uint, s chan *synth.Event) {
+	return func(sequencer *Status, counter, t uint, s Sink) {
 
 		tickRatio := float64(mid.TimeFormat) / float64(sequencer.Granularity)
 		length := mid.Length
 
 		timeInTrack := int(float64(t) * tickRatio * speed)
+		isDue := func(ev *midi.MidiEvent) bool {
+			return ev.Offset == timeInTrack || (loop && timeInTrack >= length && ((timeInTrack%length) == ev.Offset || ((timeInTrack%length) == 0 && ev.Offset == length)))
+		}
+		if followTempo {
+			for _, ev := range mid.GlobalEvents {
+				if tempo, ok := ev.Message.(meta.Tempo); ok && isDue(ev) {
+					sequencer.BPM = tempo.FractionalBPM()
+				}
+			}
+		}
 		for channelNr, ch := range mid.Channels {
 			if ch == nil {
 				continue
@@ -40,7 +55,7 @@ func MidiSequence(mid *midi.MIDISequences, inputChannels, outputChannels []int,
 				continue
 			}
 			for _, ev := range ch.Events {
-				if ev.Offset == timeInTrack || (loop && timeInTrack >= length && ((timeInTrack%length) == ev.Offset || ((timeInTrack%length) == 0 && ev.Offset == length))) {
+				if isDue(ev) {
 					switch ev.Message.(type) {
 					case channel.NoteOn:
 						n := ev.Message.(channel.NoteOn)
@@ -51,6 +66,17 @@ func MidiSequence(mid *midi.MIDISequences, inputChannels, outputChannels []int,
 					case channel.NoteOffVelocity:
 						n := ev.Message.(channel.NoteOffVelocity)
 						sendEvent(s, channelNr, synth.NoteOff, []int{int(n.Key()), int(n.Velocity())})
+					case channel.ControlChange:
+						n := ev.Message.(channel.ControlChange)
+						if cc := midi.ControlChangeEvent(channelNr, int(n.Controller()), int(n.Value())); cc != nil {
+							sendEvent(s, channelNr, cc.Type, cc.Values)
+						}
+					case channel.Aftertouch:
+						n := ev.Message.(channel.Aftertouch)
+						sendEvent(s, channelNr, synth.ChannelPressure, []int{int(n.Pressure())})
+					case channel.PolyAftertouch:
+						n := ev.Message.(channel.PolyAftertouch)
+						sendEvent(s, channelNr, synth.Aftertouch, []int{int(n.Key()), int(n.Pressure())})
 					default:
 						fmt.Println("Do something", ev.Message)
 					}
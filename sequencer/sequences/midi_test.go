@@ -0,0 +1,34 @@
+package sequences
+
+import (
+	"testing"
+
+	"github.com/bspaans/bleep/midi"
+	. "github.com/bspaans/bleep/sequencer/status"
+	"github.com/bspaans/bleep/synth"
+	"gitlab.com/gomidi/midi/midimessage/channel"
+	"gitlab.com/gomidi/midi/midimessage/meta"
+)
+
+func Test_MidiSequence_follow_tempo_with_speed(t *testing.T) {
+	mid := midi.NewMIDISequences()
+	mid.TimeFormat = 96
+	mid.Length = 192
+	mid.AddGlobalEvent(0, meta.FractionalBPM(100))
+	mid.AddChannelEvent(96, 0, channel.Channel0.NoteOn(60, 100))
+
+	status := NewStatus(120, 16)
+	s := make(chan *synth.Event, 10)
+	unit := MidiSequence(mid, nil, nil, 2.0, false, true)
+	for i := uint(0); i < 16; i++ {
+		unit(&status, i, i, ChannelSink(s))
+	}
+	if status.BPM != 100 {
+		t.Errorf("Expecting the tempo of the file, got %f", status.BPM)
+	}
+	// At double speed the note on the second beat plays halfway through the
+	// first one
+	if len(s) != 1 {
+		t.Fatalf("Expecting the note to be played after half a beat, got %d events", len(s))
+	}
+}
@@ -7,10 +7,20 @@ import (
 	"github.com/bspaans/bleep/theory"
 )
 
-type Sequence func(seq *Status, counter, t uint, s chan *synth.Event)
+// A Sink receives the events played by a sequence.
+type Sink func(ev *synth.Event)
+
+// ChannelSink returns a Sink that sends the events to s.
+func ChannelSink(s chan *synth.Event) Sink {
+	return func(ev *synth.Event) {
+		s <- ev
+	}
+}
+
+type Sequence func(seq *Status, counter, t uint, s Sink)
 
 func Every(n uint, seq Sequence) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		if t%n == 0 {
 			seq(status, t/n, t, s)
 		}
@@ -18,7 +28,7 @@ func Every(n uint, seq Sequence) Sequence {
 }
 
 func Switch(n uint, seq Sequence) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		v := t % (2 * n)
 		if v < n {
 			seq(status, counter/2, t, s)
@@ -29,7 +39,7 @@ func Switch(n uint, seq Sequence) Sequence {
 func EuclidianRhythm(n, over int, tickDuration uint, seq Sequence) Sequence {
 	rhythm := theory.EuclidianRhythm(n, over)
 	//length := uint(over) * tickDuration
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		ix := (t / tickDuration) % uint(over)
 		//ix := (t % length) / tickDuration
 		//remainder := (t % length) % tickDuration
@@ -41,7 +51,7 @@ func EuclidianRhythm(n, over int, tickDuration uint, seq Sequence) Sequence {
 }
 
 func EveryWithOffset(n, offset uint, seq Sequence) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		if t < offset {
 			return
 		}
@@ -52,7 +62,7 @@ func EveryWithOffset(n, offset uint, seq Sequence) Sequence {
 }
 
 func Combine(seqs ...Sequence) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		for _, seq := range seqs {
 			seq(status, counter, t, s)
 		}
@@ -60,7 +70,7 @@ func Combine(seqs ...Sequence) Sequence {
 }
 
 func Offset(offset uint, seq Sequence) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		if t >= offset {
 			seq(status, t-offset, t-offset, s)
 		}
@@ -68,7 +78,7 @@ func Offset(offset uint, seq Sequence) Sequence {
 }
 
 func After(a uint, seq Sequence) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		if t >= a {
 			seq(status, t-a, t-a, s)
 		}
@@ -76,7 +86,7 @@ func After(a uint, seq Sequence) Sequence {
 }
 
 func Before(b uint, seq Sequence) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		if t < b {
 			seq(status, counter, t, s)
 		}
@@ -92,7 +102,7 @@ func NoteOn(channel, note, velocity int) Sequence {
 }
 
 func NotesOnAutomation(channel int, noteF IntArrayAutomation, velocityF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		notes := noteF(status, counter, t)
 		velocity := velocityF(status, counter, t)
 		for i := 0; i < len(notes); i++ {
@@ -102,7 +112,7 @@ func NotesOnAutomation(channel int, noteF IntArrayAutomation, velocityF IntAutom
 	}
 }
 func NotesOffAutomation(channel int, noteF IntArrayAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		notes := noteF(status, counter, t)
 		for i := 0; i < len(notes); i++ {
 			note := notes[i]
@@ -119,14 +129,14 @@ func NoteOff(channel, note int) Sequence {
 }
 
 func PlayNote(duration uint, channel, note, velocity int) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		NoteOn(channel, note, velocity)(status, counter, t, s)
 		ev := synth.NewEvent(synth.NoteOff, channel, []int{note})
 		status.ScheduleEvent(duration, ev)
 	}
 }
 func PlayNoteAutomation(duration uint, channel int, noteF IntAutomation, velocityF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		note := noteF(status, counter, t)
 		vel := velocityF(status, counter, t)
 		NoteOn(channel, note, vel)(status, counter, t, s)
@@ -135,7 +145,7 @@ func PlayNoteAutomation(duration uint, channel int, noteF IntAutomation, velocit
 	}
 }
 func PlayNotesAutomation(duration uint, channel int, noteF IntArrayAutomation, velocityF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		notes := noteF(status, counter, t)
 		velocity := velocityF(status, counter, t)
 		for i := 0; i < len(notes); i++ {
@@ -177,95 +187,136 @@ func PlayNotesEveryAutomation(n uint, duration uint, channel int, noteF IntArray
 }
 
 func NoteOnAutomation(channel int, noteF, velocityF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewEvent(synth.NoteOn, channel, []int{noteF(status, counter, t), velocityF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewEvent(synth.NoteOn, channel, []int{noteF(status, counter, t), velocityF(status, counter, t)}))
 	}
 }
 
 func NoteOffAutomation(channel int, noteF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewEvent(synth.NoteOff, channel, []int{noteF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewEvent(synth.NoteOff, channel, []int{noteF(status, counter, t)}))
 	}
 }
 
 func PanningAutomation(channel int, panningF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewEvent(synth.SetChannelPanning, channel, []int{panningF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewEvent(synth.SetChannelPanning, channel, []int{panningF(status, counter, t)}))
 	}
 }
 
 func ReverbAutomation(channel int, reverbF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewEvent(synth.SetReverb, channel, []int{reverbF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewEvent(synth.SetReverb, channel, []int{reverbF(status, counter, t)}))
 	}
 }
 
 func ReverbTimeAutomation(channel int, reverbF FloatAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewFloatEvent(synth.SetReverbTime, channel, []float64{reverbF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewFloatEvent(synth.SetReverbTime, channel, []float64{reverbF(status, counter, t)}))
 	}
 }
 
 func LPF_CutoffAutomation(channel int, cutoffF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewEvent(synth.SetLPFCutoff, channel, []int{cutoffF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewEvent(synth.SetLPFCutoff, channel, []int{cutoffF(status, counter, t)}))
 	}
 }
 
 func HPF_CutoffAutomation(channel int, cutoffF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewEvent(synth.SetHPFCutoff, channel, []int{cutoffF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewEvent(synth.SetHPFCutoff, channel, []int{cutoffF(status, counter, t)}))
 	}
 }
 
 func ChannelVolumeAutomation(channel int, volumeF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewEvent(synth.SetChannelVolume, channel, []int{volumeF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewEvent(synth.SetChannelVolume, channel, []int{volumeF(status, counter, t)}))
+	}
+}
+
+// MuteAutomation mutes the channel when the automation returns a non-zero
+// value, and unmutes it otherwise.
+func MuteAutomation(channel int, muteF IntAutomation) Sequence {
+	return func(status *Status, counter, t uint, s Sink) {
+		mute := 0
+		if muteF(status, counter, t) != 0 {
+			mute = 1
+		}
+		s(synth.NewEvent(synth.SetMute, channel, []int{mute}))
 	}
 }
 
 func TremeloAutomation(channel int, tremeloF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewEvent(synth.SetTremelo, channel, []int{tremeloF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewEvent(synth.SetTremelo, channel, []int{tremeloF(status, counter, t)}))
 	}
 }
 
 func GrainSizeAutomation(channel int, sizeF FloatAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewFloatEvent(synth.SetGrainSize, channel, []float64{sizeF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewFloatEvent(synth.SetGrainSize, channel, []float64{sizeF(status, counter, t)}))
 	}
 }
 
 func GrainBirthRateAutomation(channel int, sizeF FloatAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewFloatEvent(synth.SetGrainBirthRate, channel, []float64{sizeF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewFloatEvent(synth.SetGrainBirthRate, channel, []float64{sizeF(status, counter, t)}))
 	}
 }
 
 func GrainSpreadAutomation(channel int, sizeF FloatAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewFloatEvent(synth.SetGrainSpread, channel, []float64{sizeF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewFloatEvent(synth.SetGrainSpread, channel, []float64{sizeF(status, counter, t)}))
 	}
 }
 
 func GrainSpeedAutomation(channel int, sizeF FloatAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
-		s <- synth.NewFloatEvent(synth.SetGrainSpeed, channel, []float64{sizeF(status, counter, t)})
+	return func(status *Status, counter, t uint, s Sink) {
+		s(synth.NewFloatEvent(synth.SetGrainSpeed, channel, []float64{sizeF(status, counter, t)}))
 	}
 }
 
 func SetIntRegisterAutomation(register int, valueF IntAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		status.IntRegisters[register] = valueF(status, counter, t)
 	}
 }
 func SetFloatRegisterAutomation(register int, valueF FloatAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		status.FloatRegisters[register] = valueF(status, counter, t)
 	}
 }
 func SetIntArrayRegisterAutomation(register int, valueF IntArrayAutomation) Sequence {
-	return func(status *Status, counter, t uint, s chan *synth.Event) {
+	return func(status *Status, counter, t uint, s Sink) {
 		status.IntArrayRegisters[register] = valueF(status, counter, t)
 	}
 }
+
+// TempoAutomation sets the BPM when the automation's value changes, so that
+// live BPM changes last until the next tempo change. The tempo is set again
+// when the time goes back, e.g. after a rewind.
+func TempoAutomation(bpmF FloatAutomation) Sequence {
+	last, lastT, started := 0.0, uint(0), false
+	return func(status *Status, counter, t uint, s Sink) {
+		bpm := bpmF(status, counter, t)
+		if started && t < lastT {
+			started = false
+		}
+		lastT = t
+		if bpm <= 0 || (started && bpm == last) {
+			return
+		}
+		status.BPM = bpm
+		last, started = bpm, true
+	}
+}
+
+// KeyAutomation sets the current key, and the scale if it's not empty.
+func KeyAutomation(keyF IntAutomation, scale string) Sequence {
+	return func(status *Status, counter, t uint, s Sink) {
+		status.Key = keyF(status, counter, t)
+		if scale != "" {
+			status.Scale = scale
+		}
+	}
+}
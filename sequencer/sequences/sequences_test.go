@@ -10,7 +10,7 @@ import (
 func Test_Every(t *testing.T) {
 	called := 0
 	counterValue := uint(0)
-	f := func(status *Status, counter, t uint, s chan *synth.Event) {
+	f := func(status *Status, counter, t uint, s Sink) {
 		called += 1
 		counterValue = counter
 	}
@@ -47,7 +47,7 @@ func Test_Every(t *testing.T) {
 
 func Test_Switch(t *testing.T) {
 	called := 0
-	f := func(status *Status, counter, t uint, s chan *synth.Event) {
+	f := func(status *Status, counter, t uint, s Sink) {
 		called += 1
 	}
 	unit := Switch(4, f)
@@ -74,7 +74,7 @@ func Test_Switch(t *testing.T) {
 func Test_EveryWithOffset(t *testing.T) {
 	called := 0
 	counterValue := uint(0)
-	f := func(status *Status, counter, t uint, s chan *synth.Event) {
+	f := func(status *Status, counter, t uint, s Sink) {
 		called += 1
 		counterValue = counter
 	}
@@ -109,3 +109,68 @@ func Test_EveryWithOffset(t *testing.T) {
 		}
 	}
 }
+
+func Test_TempoAutomation(t *testing.T) {
+	status := NewStatus(120, 64)
+	noteOff := synth.NewEvent(synth.NoteOff, 0, []int{60})
+	status.ScheduleEvent(64, noteOff)
+
+	unit := TempoAutomation(func(s *Status, counter, t uint) float64 {
+		return 60.0
+	})
+	unit(&status, 0, 0, nil)
+	if status.BPM != 60.0 {
+		t.Errorf("Expecting BPM to be 60, got %f", status.BPM)
+	}
+	if len(status.GetScheduledEvents(63)) != 0 {
+		t.Errorf("Scheduled events should not move when the tempo changes")
+	}
+	if len(status.GetScheduledEvents(64)) != 1 {
+		t.Errorf("Expecting scheduled event to be due after a quarter note")
+	}
+
+	unit = TempoAutomation(func(s *Status, counter, t uint) float64 {
+		return 0.0
+	})
+	unit(&status, 0, 0, nil)
+	if status.BPM != 60.0 {
+		t.Errorf("Expecting invalid BPM to be ignored, got %f", status.BPM)
+	}
+}
+
+func Test_TempoAutomation_keeps_live_changes(t *testing.T) {
+	status := NewStatus(120, 64)
+	bpm := 90.0
+	unit := TempoAutomation(func(s *Status, counter, t uint) float64 {
+		return bpm
+	})
+	unit(&status, 0, 0, nil)
+	status.BPM = 100.0
+	unit(&status, 1, 1, nil)
+	if status.BPM != 100.0 {
+		t.Errorf("Expecting the live BPM to be kept, got %f", status.BPM)
+	}
+	bpm = 80.0
+	unit(&status, 2, 2, nil)
+	if status.BPM != 80.0 {
+		t.Errorf("Expecting the tempo change to be applied, got %f", status.BPM)
+	}
+	status.BPM = 100.0
+	unit(&status, 0, 0, nil)
+	if status.BPM != 80.0 {
+		t.Errorf("Expecting the tempo to be set again after a rewind, got %f", status.BPM)
+	}
+}
+
+func Test_MuteAutomation(t *testing.T) {
+	s := make(chan *synth.Event, 1)
+	values := []int{0, 3}
+	unit := MuteAutomation(4, func(status *Status, counter, t uint) int { return values[counter] })
+	for counter, expected := range []int{0, 1} {
+		unit(nil, uint(counter), 0, ChannelSink(s))
+		ev := <-s
+		if ev.Type != synth.SetMute || ev.Channel != 4 || ev.Values[0] != expected {
+			t.Errorf("Expecting mute %d on channel 4, got %v", expected, ev)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package status
 
 import (
+	"math/rand"
+	"time"
+
 	"github.com/bspaans/bleep/synth"
 )
 
@@ -13,9 +16,21 @@ type Status struct {
 	IntArrayRegisters [][]int
 	FloatRegisters    []float64
 	ScheduledEvents   []*ScheduledEvent
+
+	// The current key and scale. Scale degree automations are resolved
+	// against these.
+	Key   int
+	Scale string
+
+	// Random should be used for all randomness in sequences and automations,
+	// so that playback is reproducible for a given Seed. The generator is
+	// reseeded whenever the time is reset.
+	Seed   int64
+	Random *rand.Rand
 }
 
 func NewStatus(bpm float64, granularity int) Status {
+	seed := time.Now().UnixNano()
 	return Status{
 		BPM:               bpm,
 		Granularity:       granularity,
@@ -24,9 +39,18 @@ func NewStatus(bpm float64, granularity int) Status {
 		FloatRegisters:    make([]float64, 128),
 		ScheduledEvents:   []*ScheduledEvent{},
 		Time:              0,
+		Key:               60,
+		Scale:             "ionian",
+		Seed:              seed,
+		Random:            rand.New(rand.NewSource(seed)),
 	}
 }
 
+func (s *Status) SetSeed(seed int64) {
+	s.Seed = seed
+	s.Random = rand.New(rand.NewSource(seed))
+}
+
 func (s *Status) ScheduleEvent(duration uint, ev *synth.Event) {
 	when := s.Time + duration
 	event := NewScheduledEvent(when, ev)
@@ -35,6 +59,7 @@ func (s *Status) ScheduleEvent(duration uint, ev *synth.Event) {
 
 func (s *Status) ResetTime() {
 	s.Time = 0
+	s.Random = rand.New(rand.NewSource(s.Seed))
 }
 func (s *Status) IncrementTime() {
 	s.Time++
@@ -0,0 +1,63 @@
+package synth
+
+import (
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/filters"
+)
+
+// ChannelDynamics is a compressor, expander or gate on a mixer channel. It
+// follows the level of the channel itself, or of the Source channel if
+// it's sidechained; e.g. a compressor on a pad that follows the kick drum.
+// The sidechain takes the source before its fader, mute and dynamics, so
+// that a muted channel can still drive the filter.
+type ChannelDynamics struct {
+	Filter filters.Filter
+	// The channel to follow, or -1 for the channel itself.
+	Source int
+
+	sidechain *filters.Sidechain
+}
+
+func NewChannelDynamics(filter filters.Filter) *ChannelDynamics {
+	return &ChannelDynamics{
+		Filter: filter,
+		Source: -1,
+	}
+}
+
+func NewSidechainedChannelDynamics(filter filters.SidechainFilter, source int) *ChannelDynamics {
+	sidechain := &filters.Sidechain{}
+	filter.SetSidechain(sidechain)
+	return &ChannelDynamics{
+		Filter:    filter,
+		Source:    source,
+		sidechain: sidechain,
+	}
+}
+
+// applyDynamics runs the dynamics of every channel over its samples. The
+// sidechains get the samples of their source from before this call.
+func (m *Mixer) applyDynamics(cfg *audio.AudioConfig, channelSamples [][]float64) {
+	var sources map[int][]float64
+	for _, dynamics := range m.Dynamics {
+		for _, d := range dynamics {
+			if d.sidechain == nil || d.Source < 0 || d.Source >= len(channelSamples) {
+				continue
+			}
+			if sources == nil {
+				sources = map[int][]float64{}
+			}
+			if _, ok := sources[d.Source]; !ok {
+				sources[d.Source] = append([]float64{}, channelSamples[d.Source]...)
+			}
+		}
+	}
+	for ch, dynamics := range m.Dynamics {
+		for _, d := range dynamics {
+			if d.sidechain != nil {
+				d.sidechain.Samples = sources[d.Source]
+			}
+			channelSamples[ch] = d.Filter.Filter(cfg, channelSamples[ch])
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package synth
+
+import (
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/filters"
+)
+
+func Test_Mixer_sidechain_from_a_muted_channel(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	peak := func(kick bool) float64 {
+		m := NewMixer()
+		m.Meter = NewMeter(cfg, len(m.Channels))
+		compressor := filters.NewCompressorFilter(-40, 10, 0, 0.001, 0.1, 0)
+		m.SetDynamics(0, []*ChannelDynamics{NewSidechainedChannelDynamics(compressor, 1)})
+		m.SetMute(1, true)
+		m.NoteOn(0, 60, 1.0)
+		if kick {
+			m.NoteOn(1, 36, 1.0)
+		}
+		m.GetSamples(cfg, 4410)
+		m.Meter.Snapshot()
+		m.GetSamples(cfg, 4410)
+		meters := m.Meter.Snapshot()
+		if meters.Channels[1].Peak != 0 {
+			t.Errorf("Expecting the sidechain channel to stay muted")
+		}
+		return meters.Channels[0].Peak
+	}
+	withoutKick, withKick := peak(false), peak(true)
+	if withoutKick == 0 {
+		t.Fatalf("Expecting the first channel to be heard")
+	}
+	if filters.GainToDecibels(withKick) > filters.GainToDecibels(withoutKick)-20 {
+		t.Errorf("Expecting the kick to turn the first channel down, got %f and %f", withKick, withoutKick)
+	}
+}
+
+func Test_Mixer_dynamics_without_sidechain(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.Stereo = false
+	m := NewMixer()
+	m.SetDynamics(0, []*ChannelDynamics{NewChannelDynamics(filters.NewGateFilter(0, 0, 0.001, 0.001, 0))})
+	m.NoteOn(0, 60, 1.0)
+	m.GetSamples(cfg, 4410)
+	silence := 1 << uint(cfg.BitDepth-1)
+	for _, v := range m.GetSamples(cfg, 4410) {
+		if v < silence-1 || v > silence+1 {
+			t.Fatalf("Expecting the gate to stay closed, got %d", v)
+		}
+	}
+}
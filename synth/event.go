@@ -1,6 +1,9 @@
 package synth
 
-import "github.com/bspaans/bleep/instruments"
+import (
+	"github.com/bspaans/bleep/instruments"
+	"github.com/bspaans/bleep/tuning"
+)
 
 type EventType int
 
@@ -38,6 +41,33 @@ const (
 
 	SetMasterGain EventType = iota
 	ForceUIReload EventType = iota
+
+	SetTuning       EventType = iota
+	SetGlobalTuning EventType = iota
+
+	ChannelPressure   EventType = iota
+	SetTimbre         EventType = iota
+	SetPitchbendRange EventType = iota
+	SetMPEZone        EventType = iota
+
+	SetSustain  EventType = iota
+	SetModWheel EventType = iota
+	Aftertouch  EventType = iota
+
+	SetChannelOutput EventType = iota
+
+	// Values[0] is 0 (off) or 1 (on)
+	SetMute           EventType = iota
+	SetSolo           EventType = iota
+	SetSoloSafe       EventType = iota
+	ToggleMuteChannel EventType = iota
+
+	// Requires a MasterBus; nil switches the master processing off.
+	SetMasterBus EventType = iota
+
+	// Requires Dynamics; replaces the compressors, expanders and gates on
+	// the channel.
+	SetChannelDynamics EventType = iota
 )
 
 type Event struct {
@@ -47,6 +77,9 @@ type Event struct {
 	Values      []int
 	FloatValues []float64
 	Instrument  instruments.Instrument
+	Tuning      *tuning.Tuning
+	MasterBus   *MasterBus
+	Dynamics    []*ChannelDynamics
 }
 
 func NewEvent(ty EventType, channel int, values []int) *Event {
@@ -80,3 +113,26 @@ func NewInstrumentEvent(ty EventType, channel int, value instruments.Instrument)
 		Instrument: value,
 	}
 }
+
+func NewTuningEvent(ty EventType, channel int, value *tuning.Tuning) *Event {
+	return &Event{
+		Type:    ty,
+		Channel: channel,
+		Tuning:  value,
+	}
+}
+
+func NewMasterBusEvent(bus *MasterBus) *Event {
+	return &Event{
+		Type:      SetMasterBus,
+		MasterBus: bus,
+	}
+}
+
+func NewDynamicsEvent(channel int, dynamics []*ChannelDynamics) *Event {
+	return &Event{
+		Type:     SetChannelDynamics,
+		Channel:  channel,
+		Dynamics: dynamics,
+	}
+}
@@ -0,0 +1,98 @@
+package synth
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/filters"
+)
+
+// The MasterBus processes the mixed output: the optional bus compressor
+// glues the mix together, the limiter keeps it below the ceiling, and the
+// dither hides the quantization when it's converted to the output bit
+// depth. Each of them can be nil.
+type MasterBus struct {
+	Compressor *filters.CompressorFilter
+	Limiter    *filters.LimiterFilter
+	Dither     *Dither
+}
+
+func (b *MasterBus) Filter(cfg *audio.AudioConfig, samples []float64) []float64 {
+	if b.Compressor != nil {
+		samples = b.Compressor.Filter(cfg, samples)
+	}
+	if b.Limiter != nil {
+		samples = b.Limiter.Filter(cfg, samples)
+	}
+	return samples
+}
+
+// Quantize converts samples between -1.0 and 1.0 to unsigned integers with
+// the bit depth in the config.
+func (b *MasterBus) Quantize(cfg *audio.AudioConfig, samples []float64) []int {
+	if b == nil || b.Dither == nil {
+		return quantize(cfg, samples)
+	}
+	return b.Dither.Quantize(cfg, samples)
+}
+
+func quantize(cfg *audio.AudioConfig, samples []float64) []int {
+	result := make([]int, len(samples))
+	maxValue := math.Pow(2, float64(cfg.BitDepth))
+	for i, sample := range samples {
+		scaled := (sample + 1) * (maxValue / 2)
+		maxClipped := math.Max(0, math.Ceil(scaled))
+		result[i] = int(math.Min(maxClipped, maxValue-1))
+	}
+	return result
+}
+
+// Dither adds triangular (TPDF) noise of one step either way before
+// rounding, which turns the quantization distortion into a constant noise
+// floor. With noise shaping the rounding error of every sample is
+// subtracted from the next one, which moves the noise up to the high
+// frequencies where it's harder to hear.
+type Dither struct {
+	NoiseShaping bool
+
+	// The rounding error of the previous sample, per channel
+	errors []float64
+	random *rand.Rand
+}
+
+func NewDither(noiseShaping bool) *Dither {
+	return &Dither{
+		NoiseShaping: noiseShaping,
+		random:       rand.New(rand.NewSource(1)),
+	}
+}
+
+func (d *Dither) Quantize(cfg *audio.AudioConfig, samples []float64) []int {
+	channels := cfg.GetNumberOfChannels()
+	if len(d.errors) != channels {
+		d.errors = make([]float64, channels)
+	}
+	result := make([]int, len(samples))
+	maxValue := math.Pow(2, float64(cfg.BitDepth))
+	for i, sample := range samples {
+		c := i % channels
+		// Offset by half a step, so that it rounds like the conversion
+		// without dither.
+		scaled := (sample+1)*(maxValue/2) + 0.5
+		if d.NoiseShaping {
+			scaled -= d.errors[c]
+		}
+		noise := d.random.Float64() - d.random.Float64()
+		quantized := math.Round(scaled + noise)
+		if quantized < 0 || quantized > maxValue-1 {
+			// Don't feed the clipping back, or it keeps going.
+			quantized = math.Max(0, math.Min(maxValue-1, quantized))
+			d.errors[c] = 0
+		} else {
+			d.errors[c] = quantized - scaled
+		}
+		result[i] = int(quantized)
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package synth
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/filters"
+)
+
+func Test_MasterBus_Quantize_without_dither_matches_the_mixer(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	var bus *MasterBus
+	result := bus.Quantize(cfg, []float64{-2.0, -1.0, 0.0, 0.5, 1.0})
+	expected := []int{0, 0, 32768, 49152, 65535}
+	for i, v := range expected {
+		if result[i] != v {
+			t.Errorf("Expecting %v, got %v", expected, result)
+			break
+		}
+	}
+}
+
+func Test_Dither_averages_out(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.BitDepth = 8
+	cfg.Stereo = false
+	for _, noiseShaping := range []bool{false, true} {
+		d := NewDither(noiseShaping)
+		// A quarter of a step above the middle can't be represented
+		// without dither.
+		step := 2.0 / 256
+		samples := make([]float64, 20000)
+		for i := range samples {
+			samples[i] = step*0.25 - step/2
+		}
+		sum, distinct := 0.0, map[int]bool{}
+		for _, v := range d.Quantize(cfg, samples) {
+			sum += float64(v)
+			distinct[v] = true
+		}
+		mean := sum / float64(len(samples))
+		if math.Abs(mean-128.25) > 0.02 || len(distinct) < 2 {
+			t.Errorf("Expecting the dithered values to average 128.25, got %f (noise shaping %v)", mean, noiseShaping)
+		}
+	}
+}
+
+func Test_Dither_noise_shaping_moves_the_noise_up(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.BitDepth = 8
+	cfg.Stereo = false
+	samples := sine(100, 0.5, cfg.SampleRate, 8192)
+	lowFrequencyNoise := func(noiseShaping bool) float64 {
+		result := NewDither(noiseShaping).Quantize(cfg, samples)
+		// The error, smoothed with a moving average, which leaves the
+		// lower frequencies.
+		sum, total := 0.0, 0.0
+		errors := make([]float64, len(samples))
+		for i, v := range result {
+			errors[i] = float64(v) - ((samples[i]+1)*128 + 0.5)
+		}
+		for i := 16; i < len(errors); i++ {
+			sum += errors[i] - errors[i-16]
+			total += (sum / 16) * (sum / 16)
+		}
+		return total
+	}
+	flat, shaped := lowFrequencyNoise(false), lowFrequencyNoise(true)
+	if shaped > flat/4 {
+		t.Errorf("Expecting less low frequency noise with noise shaping, got %f vs %f", shaped, flat)
+	}
+}
+
+func Test_Mixer_Master_limits_the_output(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	m := NewMixer()
+	m.Master = &MasterBus{Limiter: filters.NewLimiterFilter(-6, 0.001, 0.05)}
+	for ch := 0; ch < 16; ch++ {
+		if ch != 9 {
+			m.SetChannelVolume(ch, 127)
+			m.NoteOn(ch, 60, 1.0)
+		}
+	}
+	m.SetMasterGain(4.0)
+	limit := 32768 * (1 + filters.DecibelsToGain(-6))
+	for i := 0; i < 10; i++ {
+		for _, v := range m.GetSamples(cfg, 1024) {
+			if float64(v) > limit+1 || float64(v) < 65536-limit-1 {
+				t.Fatalf("Expecting the output to stay below -6dB, got %d", v)
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package synth
+
+import (
+	"math"
+	"sync"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/ui"
+)
+
+// The Meter measures the peak and RMS levels of the mixer channels and the
+// master output. Process is called from the audio callback and only does the
+// bookkeeping; Snapshot is called at a fixed rate by the Synth and does the
+// expensive work, like the spectrum analysis.
+type Meter struct {
+	mux      sync.Mutex
+	channels []levelAccumulator
+	master   levelAccumulator
+	spectrum *Spectrum
+	stereo   bool
+}
+
+func NewMeter(cfg *audio.AudioConfig, channels int) *Meter {
+	m := &Meter{
+		channels: make([]levelAccumulator, channels),
+		stereo:   cfg.Stereo,
+	}
+	if cfg.Spectrum {
+		m.spectrum = NewSpectrum(cfg.SampleRate, SpectrumSize, SpectrumBands)
+	}
+	return m
+}
+
+// Process the channel samples and the master samples. Channels that are nil
+// (e.g. muted) count as silent.
+func (m *Meter) Process(channelValues [][]float64, master []float64) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for ch, values := range channelValues {
+		if ch >= len(m.channels) {
+			break
+		}
+		if values == nil {
+			m.channels[ch].addSilence(len(master))
+		}
+		for _, v := range values {
+			m.channels[ch].add(v)
+		}
+	}
+	for i, v := range master {
+		m.master.add(v)
+		if m.spectrum == nil {
+			continue
+		}
+		if !m.stereo {
+			m.spectrum.Add(v)
+		} else if i%2 == 1 {
+			m.spectrum.Add((master[i-1] + v) / 2)
+		}
+	}
+}
+
+// Snapshot returns the levels since the previous snapshot.
+func (m *Meter) Snapshot() *ui.Meters {
+	m.mux.Lock()
+	result := &ui.Meters{
+		Channels: make([]ui.Levels, len(m.channels)),
+		Master:   m.master.levels(),
+	}
+	for i := range m.channels {
+		result.Channels[i] = m.channels[i].levels()
+	}
+	var window []float64
+	if m.spectrum != nil {
+		window = m.spectrum.Window()
+	}
+	m.mux.Unlock()
+
+	if m.spectrum != nil {
+		result.Spectrum = m.spectrum.Analyse(window)
+		result.SpectrumFrequencies = m.spectrum.Frequencies
+	}
+	return result
+}
+
+type levelAccumulator struct {
+	peak       float64
+	sumSquares float64
+	n          int
+	clipped    bool
+}
+
+func (l *levelAccumulator) add(v float64) {
+	abs := math.Abs(v)
+	if abs > l.peak {
+		l.peak = abs
+	}
+	if abs > 1.0 {
+		l.clipped = true
+	}
+	l.sumSquares += v * v
+	l.n++
+}
+
+func (l *levelAccumulator) addSilence(n int) {
+	l.n += n
+}
+
+// Return the levels and start measuring again.
+func (l *levelAccumulator) levels() ui.Levels {
+	result := ui.Levels{
+		Peak:    l.peak,
+		Clipped: l.clipped,
+	}
+	if l.n > 0 {
+		result.RMS = math.Sqrt(l.sumSquares / float64(l.n))
+	}
+	*l = levelAccumulator{}
+	return result
+}
@@ -0,0 +1,107 @@
+package synth
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/ui"
+)
+
+func sine(freq, amplitude float64, sampleRate, n int) []float64 {
+	result := make([]float64, n)
+	for i := range result {
+		result[i] = amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate))
+	}
+	return result
+}
+
+func Test_Meter_levels(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.Stereo = false
+	m := NewMeter(cfg, 2)
+	channel := sine(441, 1.0, 44100, 4410)
+	master := sine(441, 2.0, 44100, 4410)
+	m.Process([][]float64{channel, nil}, master)
+
+	meters := m.Snapshot()
+	if math.Abs(meters.Channels[0].Peak-1.0) > 1e-6 || math.Abs(meters.Channels[0].RMS-math.Sqrt(0.5)) > 1e-3 {
+		t.Errorf("Expecting the levels of a full scale sine, got %v", meters.Channels[0])
+	}
+	if meters.Channels[0].Clipped || meters.Channels[1] != (ui.Levels{}) {
+		t.Errorf("Expecting only the first channel to have levels, got %v", meters.Channels)
+	}
+	if !meters.Master.Clipped || math.Abs(meters.Master.Peak-2.0) > 1e-6 {
+		t.Errorf("Expecting the master output to clip, got %v", meters.Master)
+	}
+	if meters.Spectrum != nil {
+		t.Errorf("Expecting the spectrum to be disabled by default")
+	}
+
+	meters = m.Snapshot()
+	if meters.Master != (ui.Levels{}) {
+		t.Errorf("Expecting the levels to reset after a snapshot, got %v", meters.Master)
+	}
+}
+
+func Test_Meter_spectrum(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.Spectrum = true
+	m := NewMeter(cfg, 1)
+	mono := sine(1000, 0.5, cfg.SampleRate, SpectrumSize)
+	stereo := make([]float64, len(mono)*2)
+	for i, v := range mono {
+		stereo[i*2], stereo[i*2+1] = v, v
+	}
+	m.Process([][]float64{stereo}, stereo)
+
+	meters := m.Snapshot()
+	if len(meters.Spectrum) != SpectrumBands || len(meters.SpectrumFrequencies) != SpectrumBands {
+		t.Fatalf("Expecting %d bands, got %v", SpectrumBands, meters.Spectrum)
+	}
+	loudest := 0
+	for i, level := range meters.Spectrum {
+		if level > meters.Spectrum[loudest] {
+			loudest = i
+		}
+	}
+	lower, upper := meters.SpectrumFrequencies[loudest], 20000.0
+	if loudest+1 < SpectrumBands {
+		upper = meters.SpectrumFrequencies[loudest+1]
+	}
+	if lower > 1000 || upper < 1000 {
+		t.Errorf("Expecting the 1000Hz band to be the loudest, got %f-%fHz", lower, upper)
+	}
+	if math.Abs(meters.Spectrum[loudest]-ui.ToDecibels(0.5)) > 1.5 {
+		t.Errorf("Expecting the sine to read about -6dB, got %f", meters.Spectrum[loudest])
+	}
+}
+
+func Test_FFT(t *testing.T) {
+	x := []complex128{1, 0, 0, 0, 0, 0, 0, 0}
+	FFT(x)
+	for i, v := range x {
+		if v != 1 {
+			t.Errorf("Expecting a flat spectrum for an impulse, got %v at %d", v, i)
+		}
+	}
+	x = make([]complex128, 8)
+	for i := range x {
+		x[i] = complex(math.Cos(2*math.Pi*float64(i)/8), 0)
+	}
+	FFT(x)
+	if math.Abs(real(x[1])-4) > 1e-9 || math.Abs(real(x[7])-4) > 1e-9 || math.Abs(real(x[0])) > 1e-9 {
+		t.Errorf("Expecting energy in bins 1 and 7, got %v", x)
+	}
+}
+
+func Test_Synth_PublishMeters_does_not_block(t *testing.T) {
+	s := NewSynth(audio.NewAudioConfig())
+	for i := 0; i < cap(s.Outputs)+1; i++ {
+		s.PublishMeters()
+	}
+	ev := <-s.Outputs
+	if ev.Type != ui.ChannelsOutputEvent || ev.Meters == nil || len(ev.Meters.Channels) != 16 {
+		t.Errorf("Expecting meters for 16 channels, got %v", ev)
+	}
+}
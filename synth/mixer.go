@@ -5,18 +5,45 @@ import (
 
 	"github.com/bspaans/bleep/audio"
 	"github.com/bspaans/bleep/channels"
+	"github.com/bspaans/bleep/filters"
 	"github.com/bspaans/bleep/generators"
 	"github.com/bspaans/bleep/generators/derived"
 	"github.com/bspaans/bleep/instruments"
+	"github.com/bspaans/bleep/tuning"
 )
 
+// The time in seconds it takes to fade a channel in or out when it gets
+// (un)muted or (un)soloed.
+const SwitchRampTime = 0.005
+
 type Mixer struct {
 	Channels         []channels.Channel
 	Gain             []float64
 	ExpressionVolume []float64
 	Panning          []float64
-	Solo             []bool
 	MasterGain       float64
+
+	// When any channel is soloed, only the soloed and the solo safe channels
+	// can be heard. Muted channels are never heard.
+	Solo     []bool
+	SoloSafe []bool
+	Mute     []bool
+
+	// Ramps between 0.0 and 1.0 when channels get switched on and off.
+	switchGain []float64
+
+	// The gains and panning follow the values above smoothly.
+	smoothed   []channelSmoothing
+	masterGain filters.SmoothedValue
+
+	// The compressors, expanders and gates per channel, in order.
+	Dynamics [][]*ChannelDynamics
+
+	// Master processes the mixed output when set.
+	Master *MasterBus
+
+	// Meter measures the output levels when set.
+	Meter *Meter
 }
 
 func NewMixer() *Mixer {
@@ -26,6 +53,7 @@ func NewMixer() *Mixer {
 		ExpressionVolume: []float64{},
 		Panning:          []float64{},
 		MasterGain:       1.0,
+		masterGain:       filters.NewSmoothedValue(1.0),
 	}
 	for i := 0; i < 16; i++ {
 		ch := channels.NewPolyphonicChannel()
@@ -43,9 +71,24 @@ func NewMixer() *Mixer {
 func (m *Mixer) AddChannel(ch channels.Channel) {
 	m.Channels = append(m.Channels, ch)
 	m.Solo = append(m.Solo, false)
+	m.Dynamics = append(m.Dynamics, nil)
+	m.SoloSafe = append(m.SoloSafe, false)
+	m.Mute = append(m.Mute, false)
+	m.switchGain = append(m.switchGain, 1.0)
 	m.Gain = append(m.Gain, 0.15)
 	m.ExpressionVolume = append(m.ExpressionVolume, 1.0)
 	m.Panning = append(m.Panning, 0.5)
+	m.smoothed = append(m.smoothed, channelSmoothing{
+		gain:       filters.NewSmoothedValue(0.15),
+		expression: filters.NewSmoothedValue(1.0),
+		panning:    filters.NewSmoothedValue(0.5),
+	})
+}
+
+type channelSmoothing struct {
+	gain       filters.SmoothedValue
+	expression filters.SmoothedValue
+	panning    filters.SmoothedValue
 }
 
 func (m *Mixer) NoteOn(channel, note int, velocity float64) {
@@ -66,6 +109,62 @@ func (m *Mixer) SetPitchbend(channel int, pitchbendFactor float64) {
 	}
 }
 
+func (m *Mixer) SetNotePitchbend(channel, note int, pitchbendFactor float64) {
+	if channel < len(m.Channels) {
+		if ch, ok := m.Channels[channel].(channels.ExpressiveChannel); ok {
+			ch.SetNotePitchbend(note, pitchbendFactor)
+		}
+	}
+}
+
+func (m *Mixer) SetNotePressure(channel, note int, pressure float64) {
+	if channel < len(m.Channels) {
+		if ch, ok := m.Channels[channel].(channels.ExpressiveChannel); ok {
+			ch.SetNotePressure(note, pressure)
+		}
+	}
+}
+
+func (m *Mixer) SetNoteTimbre(channel, note int, timbre float64) {
+	if channel < len(m.Channels) {
+		if ch, ok := m.Channels[channel].(channels.ExpressiveChannel); ok {
+			ch.SetNoteTimbre(note, timbre)
+		}
+	}
+}
+
+func (m *Mixer) SetSustain(channel int, on bool) {
+	if channel < len(m.Channels) {
+		if ch, ok := m.Channels[channel].(channels.PerformanceChannel); ok {
+			ch.SetSustain(on)
+		}
+	}
+}
+
+func (m *Mixer) SetModWheel(channel int, value float64) {
+	if channel < len(m.Channels) {
+		if ch, ok := m.Channels[channel].(channels.PerformanceChannel); ok {
+			ch.SetModWheel(value)
+		}
+	}
+}
+
+func (m *Mixer) SetChannelPressure(channel int, value float64) {
+	if channel < len(m.Channels) {
+		if ch, ok := m.Channels[channel].(channels.PerformanceChannel); ok {
+			ch.SetChannelPressure(value)
+		}
+	}
+}
+
+func (m *Mixer) SetAftertouch(channel, note int, value float64) {
+	if channel < len(m.Channels) {
+		if ch, ok := m.Channels[channel].(channels.PerformanceChannel); ok {
+			ch.SetAftertouch(note, value)
+		}
+	}
+}
+
 func (m *Mixer) SetReverb(channel, reverb int) {
 	if channel < len(m.Channels) {
 		m.Channels[channel].SetFX(channels.Reverb, float64(reverb)/127.0-0.01)
@@ -122,33 +221,69 @@ func (m *Mixer) SetInstrument(cfg *audio.AudioConfig, channel int, instr instrum
 	}
 }
 
+// Set the tuning for a channel. A nil tuning resets the channel to twelve
+// tone equal temperament.
+func (m *Mixer) SetTuning(channel int, t *tuning.Tuning) {
+	if channel < len(m.Channels) {
+		m.Channels[channel].SetTuning(t)
+	}
+}
+
+// Set the tuning for all channels, except for the percussion channel: its
+// notes select drum sounds, so a keyboard mapping that leaves keys unmapped
+// would silence them. It can still be tuned with SetTuning.
+func (m *Mixer) SetGlobalTuning(t *tuning.Tuning) {
+	for _, ch := range m.Channels {
+		if _, ok := ch.(*channels.PercussionChannel); ok {
+			continue
+		}
+		ch.SetTuning(t)
+	}
+}
+
 func (m *Mixer) GetSamples(cfg *audio.AudioConfig, n int) []int {
 	samples := generators.GetEmptySampleArray(cfg, n)
 	channelValues := make([][]float64, len(m.Channels))
 	solo := m.HasSolo()
+	rampStep := 1.0 / (SwitchRampTime * float64(cfg.SampleRate))
 
-	latestValues := make([]float64, len(m.Channels))
+	// All the channels are rendered first, so that any channel can be used
+	// as a sidechain.
+	allSamples := make([][]float64, len(m.Channels))
 	for channelNr, ch := range m.Channels {
+		allSamples[channelNr] = ch.GetSamples(cfg, n)
+	}
+	m.applyDynamics(cfg, allSamples)
 
-		chSamples := ch.GetSamples(cfg, n)
-		if solo && !m.Solo[channelNr] {
+	for channelNr, chSamples := range allSamples {
+		target := 0.0
+		if m.isAudible(channelNr, solo) {
+			target = 1.0
+		}
+		switchGain := m.switchGain[channelNr]
+		if switchGain == 0.0 && target == 0.0 {
 			continue
 		}
+		smoothed := &m.smoothed[channelNr]
+		smoothed.gain.Set(m.Gain[channelNr])
+		smoothed.expression.Set(m.ExpressionVolume[channelNr])
+		smoothed.panning.Set(m.Panning[channelNr])
 		channelValues[channelNr] = make([]float64, len(chSamples))
 		for i := 0; i < n; i++ {
+			switchGain = rampTowards(switchGain, target, rampStep)
+			gain := smoothed.gain.Next(cfg) * smoothed.expression.Next(cfg) * switchGain * 0.15
+			panning := smoothed.panning.Next(cfg)
 			if cfg.Stereo {
-				left := chSamples[i*2] * m.Gain[channelNr] * m.ExpressionVolume[channelNr] * 0.15
-				right := chSamples[i*2+1] * m.Gain[channelNr] * m.ExpressionVolume[channelNr] * 0.15
-				left, right = derived.SinusoidalPanning(left, right, m.Panning[channelNr])
+				left := chSamples[i*2] * gain
+				right := chSamples[i*2+1] * gain
+				left, right = derived.SinusoidalPanning(left, right, panning)
 				channelValues[channelNr][i*2] = left
 				channelValues[channelNr][i*2+1] = right
-				latestValues[channelNr] = (left + right) / 2
 			} else {
-				v := chSamples[i] * m.Gain[channelNr] * m.ExpressionVolume[channelNr] * 0.15
-				channelValues[channelNr][i] = v
-				latestValues[channelNr] = v
+				channelValues[channelNr][i] = chSamples[i] * gain
 			}
 		}
+		m.switchGain[channelNr] = switchGain
 	}
 	for _, channelSamples := range channelValues {
 		for i, s := range channelSamples {
@@ -156,18 +291,40 @@ func (m *Mixer) GetSamples(cfg *audio.AudioConfig, n int) []int {
 		}
 	}
 
-	//ev := ui.NewUIEvent(ui.ChannelsOutputEvent)
-	//ev.Values = latestValues
-	//outputEvents <- ev
+	m.masterGain.Set(m.MasterGain)
+	channelCount := cfg.GetNumberOfChannels()
+	for i := 0; i < n; i++ {
+		gain := m.masterGain.Next(cfg)
+		for c := 0; c < channelCount; c++ {
+			samples[i*channelCount+c] *= gain
+		}
+	}
 
-	result := make([]int, len(samples))
-	maxValue := math.Pow(2, float64(cfg.BitDepth))
-	for i, sample := range samples {
-		scaled := (sample*m.MasterGain + 1) * (maxValue / 2)
-		maxClipped := math.Max(0, math.Ceil(scaled))
-		result[i] = int(math.Min(maxClipped, maxValue-1))
+	master := m.Master
+	if master != nil {
+		samples = master.Filter(cfg, samples)
 	}
-	return result
+	if m.Meter != nil {
+		m.Meter.Process(channelValues, samples)
+	}
+	return master.Quantize(cfg, samples)
+}
+
+func rampTowards(value, target, step float64) float64 {
+	if value < target {
+		return math.Min(target, value+step)
+	}
+	return math.Max(target, value-step)
+}
+
+func (m *Mixer) isAudible(ch int, solo bool) bool {
+	return !m.Mute[ch] && (!solo || m.Solo[ch] || m.SoloSafe[ch])
+}
+
+// IsAudible returns whether the channel can be heard, taking mute and solo
+// into account.
+func (m *Mixer) IsAudible(ch int) bool {
+	return ch < len(m.Channels) && m.isAudible(ch, m.HasSolo())
 }
 
 func (m *Mixer) HasSolo() bool {
@@ -181,6 +338,7 @@ func (m *Mixer) HasSolo() bool {
 
 func (m *Mixer) SilenceChannel(ch int) {
 	if ch < len(m.Channels) {
+		m.SetSustain(ch, false)
 		for i := 0; i <= 128; i++ {
 			m.Channels[ch].NoteOff(i)
 		}
@@ -188,7 +346,8 @@ func (m *Mixer) SilenceChannel(ch int) {
 }
 
 func (m *Mixer) SilenceAllChannels() {
-	for _, ch := range m.Channels {
+	for c, ch := range m.Channels {
+		m.SetSustain(c, false)
 		for i := 0; i <= 128; i++ {
 			ch.NoteOff(i)
 		}
@@ -222,3 +381,35 @@ func (m *Mixer) ToggleSoloChannel(ch int) {
 		m.Solo[ch] = !m.Solo[ch]
 	}
 }
+
+func (m *Mixer) SetSolo(ch int, solo bool) {
+	if ch < len(m.Channels) {
+		m.Solo[ch] = solo
+	}
+}
+
+// Solo safe channels can still be heard when other channels are soloed.
+func (m *Mixer) SetSoloSafe(ch int, safe bool) {
+	if ch < len(m.Channels) {
+		m.SoloSafe[ch] = safe
+	}
+}
+
+func (m *Mixer) ToggleMuteChannel(ch int) {
+	if ch < len(m.Channels) {
+		m.Mute[ch] = !m.Mute[ch]
+	}
+}
+
+// SetDynamics replaces the compressors, expanders and gates on the channel.
+func (m *Mixer) SetDynamics(ch int, dynamics []*ChannelDynamics) {
+	if ch < len(m.Channels) {
+		m.Dynamics[ch] = dynamics
+	}
+}
+
+func (m *Mixer) SetMute(ch int, mute bool) {
+	if ch < len(m.Channels) {
+		m.Mute[ch] = mute
+	}
+}
@@ -0,0 +1,88 @@
+package synth
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/channels"
+	"github.com/bspaans/bleep/tuning"
+)
+
+func Test_Mixer_mute_and_solo(t *testing.T) {
+	m := NewMixer()
+	m.SetSolo(1, true)
+	m.SetSoloSafe(2, true)
+	m.SetMute(3, true)
+	m.ToggleMuteChannel(1)
+	audible := []bool{false, false, true, false}
+	for ch, expected := range audible {
+		if m.IsAudible(ch) != expected {
+			t.Errorf("Expecting channel %d audible to be %v", ch, expected)
+		}
+	}
+	m.SetSolo(1, false)
+	if !m.IsAudible(0) || m.IsAudible(1) || m.IsAudible(3) {
+		t.Errorf("Expecting all unmuted channels to be audible without solo")
+	}
+}
+
+func Test_Mixer_SetGlobalTuning_skips_percussion(t *testing.T) {
+	m := NewMixer()
+	tun := &tuning.Tuning{}
+	m.SetGlobalTuning(tun)
+	if m.Channels[0].(*channels.PolyphonicChannel).Tuning != tun {
+		t.Errorf("Expecting the tuning to be set on channel 0")
+	}
+	if m.Channels[9].(*channels.PercussionChannel).Tuning != nil {
+		t.Errorf("Expecting the percussion channel to keep its tuning")
+	}
+	m.SetTuning(9, tun)
+	if m.Channels[9].(*channels.PercussionChannel).Tuning != tun {
+		t.Errorf("Expecting the percussion channel to be tuned explicitly")
+	}
+}
+
+func Test_Mixer_mute_ramps_the_gain(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.Stereo = false
+	m := NewMixer()
+	m.NoteOn(0, 69, 1.0)
+	m.GetSamples(cfg, 1000)
+
+	m.SetMute(0, true)
+	samples := m.GetSamples(cfg, 1000)
+	silence := int(math.Pow(2, float64(cfg.BitDepth)) / 2)
+	rampLength := int(SwitchRampTime * float64(cfg.SampleRate))
+	sounding := false
+	for _, s := range samples[:rampLength/2] {
+		sounding = sounding || s != silence
+	}
+	if !sounding {
+		t.Errorf("Expecting the channel to fade out instead of stopping immediately")
+	}
+	for i, s := range samples[rampLength+1:] {
+		if s != silence {
+			t.Fatalf("Expecting silence after the ramp, got %d at %d", s, rampLength+1+i)
+		}
+	}
+}
+
+func Test_Mixer_smooths_volume_changes(t *testing.T) {
+	cfg := audio.NewAudioConfig()
+	cfg.Stereo = false
+	m := NewMixer()
+	m.GetSamples(cfg, 10)
+
+	m.SetChannelVolume(0, 127)
+	m.SetMasterGain(0.5)
+	m.GetSamples(cfg, 10)
+	gain := m.smoothed[0].gain.Current
+	if gain <= 0.15 || gain >= 1.0 || m.masterGain.Current >= 1.0 || m.masterGain.Current <= 0.5 {
+		t.Errorf("Expecting the gains to be ramping, got %f and %f", gain, m.masterGain.Current)
+	}
+	m.GetSamples(cfg, int(cfg.SmoothingTime*float64(cfg.SampleRate)))
+	if m.smoothed[0].gain.Current != 1.0 || m.masterGain.Current != 0.5 {
+		t.Errorf("Expecting the gains to reach their targets, got %f and %f", m.smoothed[0].gain.Current, m.masterGain.Current)
+	}
+}
@@ -0,0 +1,226 @@
+package synth
+
+import "math"
+
+// Pitch bend ranges in semitones. Channels outside of MPE zones use
+// DefaultPitchbendRange until it's changed with RPN 0.
+const (
+	DefaultPitchbendRange       = 12.0
+	DefaultMasterPitchbendRange = 2.0
+	DefaultMemberPitchbendRange = 48.0
+)
+
+// MPEZone is a group of member channels whose notes are played by the
+// instrument on the zone's master channel. The lower zone's master is
+// channel 0 and its members count up from channel 1; the upper zone's master
+// is channel 15 and its members count down from channel 14.
+type MPEZone struct {
+	Master  int
+	Members int
+}
+
+func (z *MPEZone) IsMember(ch int) bool {
+	if z == nil || z.Members == 0 {
+		return false
+	}
+	if z.Master == 0 {
+		return ch >= 1 && ch <= z.Members
+	}
+	return ch < z.Master && ch >= z.Master-z.Members
+}
+
+// memberChannel keeps track of the notes on an MPE member channel and of
+// the channel's current expression, which also applies to new notes.
+type memberChannel struct {
+	Notes     []int
+	Pitchbend float64
+	Pressure  float64
+	Timbre    float64
+}
+
+func newMemberChannel() *memberChannel {
+	return &memberChannel{
+		Notes:     []int{},
+		Pitchbend: 1.0,
+		Timbre:    1.0,
+	}
+}
+
+func (m *memberChannel) removeNote(note int) {
+	for i, n := range m.Notes {
+		if n == note {
+			m.Notes = append(m.Notes[:i], m.Notes[i+1:]...)
+			return
+		}
+	}
+}
+
+type MPE struct {
+	Lower          *MPEZone
+	Upper          *MPEZone
+	PitchbendRange []float64
+	members        []*memberChannel
+}
+
+func NewMPE() *MPE {
+	m := &MPE{
+		PitchbendRange: make([]float64, 16),
+		members:        make([]*memberChannel, 16),
+	}
+	for ch := 0; ch < 16; ch++ {
+		m.PitchbendRange[ch] = DefaultPitchbendRange
+		m.members[ch] = newMemberChannel()
+	}
+	return m
+}
+
+// Zone returns the zone if the channel is one of its member channels, or nil
+// otherwise.
+func (m *MPE) Zone(ch int) *MPEZone {
+	if m.Lower.IsMember(ch) {
+		return m.Lower
+	}
+	if m.Upper.IsMember(ch) {
+		return m.Upper
+	}
+	return nil
+}
+
+// SetZone configures the zone on master channel 0 or 15, like an MPE
+// Configuration Message would. Zero members disables the zone. The other
+// zone shrinks if the two would overlap, and the pitch bend ranges of the
+// zone's channels are reset to their defaults.
+func (m *MPE) SetZone(master, members int) {
+	if master != 0 && master != 15 {
+		return
+	}
+	if members < 0 {
+		members = 0
+	} else if members > 15 {
+		members = 15
+	}
+	zone := &MPEZone{Master: master, Members: members}
+	other := m.Upper
+	if master == 0 {
+		m.Lower = zone
+	} else {
+		m.Upper = zone
+		other = m.Lower
+	}
+	if other != nil && other.Members > 14-members {
+		other.Members = 14 - members
+		if other.Members < 0 {
+			other.Members = 0
+		}
+	}
+	if members == 0 {
+		m.PitchbendRange[master] = DefaultPitchbendRange
+	} else {
+		m.PitchbendRange[master] = DefaultMasterPitchbendRange
+	}
+	for ch := 0; ch < 16; ch++ {
+		if zone.IsMember(ch) {
+			m.PitchbendRange[ch] = DefaultMemberPitchbendRange
+		}
+	}
+}
+
+// SetPitchbendRange sets the range in semitones. Setting the range on a
+// member channel sets it for all the members in its zone.
+func (m *MPE) SetPitchbendRange(ch int, semitones float64) {
+	if ch < 0 || ch >= 16 {
+		return
+	}
+	zone := m.Zone(ch)
+	if zone == nil {
+		m.PitchbendRange[ch] = semitones
+		return
+	}
+	for member := 0; member < 16; member++ {
+		if zone.IsMember(member) {
+			m.PitchbendRange[member] = semitones
+		}
+	}
+}
+
+func (m *MPE) GetPitchbendRange(ch int) float64 {
+	if ch < 0 || ch >= 16 {
+		return DefaultPitchbendRange
+	}
+	return m.PitchbendRange[ch]
+}
+
+// PitchbendFactor turns a 7 bit ([msb]) or 14 bit ([msb, lsb]) pitch bend
+// value into a factor for the pitch.
+func PitchbendFactor(values []int, semitones float64) float64 {
+	bend := float64(values[0]-64) / 64.0 // -1.0 <-> 1.0
+	if len(values) > 1 {
+		bend = float64((values[0]<<7|values[1])-8192) / 8192.0
+	}
+	return math.Pow(2, bend*semitones/12)
+}
+
+// dispatchMemberEvent plays the notes and per note expression of an MPE
+// member channel on the zone's master channel. Other events on member
+// channels are ignored.
+func (s *Synth) dispatchMemberEvent(zone *MPEZone, ev *Event) {
+	ch := ev.Channel
+	member := s.MPE.members[ch]
+	values := ev.Values
+	if ev.Type == NoteOn {
+		note := values[0]
+		velocity := float64(int(values[1])) / 127
+		s.Mixer.NoteOn(zone.Master, note, velocity)
+		s.Mixer.SetNotePitchbend(zone.Master, note, member.Pitchbend)
+		if member.Pressure > 0.0 {
+			s.Mixer.SetNotePressure(zone.Master, note, member.Pressure)
+		}
+		if member.Timbre < 1.0 {
+			s.Mixer.SetNoteTimbre(zone.Master, note, member.Timbre)
+		}
+		member.removeNote(note)
+		member.Notes = append(member.Notes, note)
+	} else if ev.Type == NoteOff {
+		s.Mixer.NoteOff(zone.Master, values[0])
+		member.removeNote(values[0])
+	} else if ev.Type == PitchBend {
+		member.Pitchbend = PitchbendFactor(values, s.MPE.GetPitchbendRange(ch))
+		for _, note := range member.Notes {
+			s.Mixer.SetNotePitchbend(zone.Master, note, member.Pitchbend)
+		}
+	} else if ev.Type == ChannelPressure {
+		member.Pressure = float64(values[0]) / 127
+		for _, note := range member.Notes {
+			s.Mixer.SetNotePressure(zone.Master, note, member.Pressure)
+		}
+	} else if ev.Type == SetTimbre {
+		member.Timbre = float64(values[0]) / 127
+		for _, note := range member.Notes {
+			s.Mixer.SetNoteTimbre(zone.Master, note, member.Timbre)
+		}
+	} else if ev.Type == SetPitchbendRange {
+		s.MPE.SetPitchbendRange(ch, ev.FloatValues[0])
+	}
+}
+
+// setMPEZone releases the notes on the current member channels before
+// reconfiguring the zones. Nothing is released if the zone doesn't change.
+func (s *Synth) setMPEZone(master, members int) {
+	current := s.MPE.Lower
+	if master == 15 {
+		current = s.MPE.Upper
+	}
+	if current != nil && current.Members == members {
+		s.MPE.SetZone(master, members)
+		return
+	}
+	for ch, member := range s.MPE.members {
+		if zone := s.MPE.Zone(ch); zone != nil {
+			for _, note := range member.Notes {
+				s.Mixer.NoteOff(zone.Master, note)
+			}
+		}
+		s.MPE.members[ch] = newMemberChannel()
+	}
+	s.MPE.SetZone(master, members)
+}
@@ -0,0 +1,106 @@
+package synth
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/channels"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.0001
+}
+
+func Test_MPEZone_IsMember(t *testing.T) {
+	lower := &MPEZone{Master: 0, Members: 3}
+	upper := &MPEZone{Master: 15, Members: 2}
+	for ch := 0; ch < 16; ch++ {
+		if lower.IsMember(ch) != (ch >= 1 && ch <= 3) {
+			t.Errorf("Unexpected lower zone membership for channel %d", ch)
+		}
+		if upper.IsMember(ch) != (ch == 13 || ch == 14) {
+			t.Errorf("Unexpected upper zone membership for channel %d", ch)
+		}
+	}
+}
+
+func Test_MPE_SetZone(t *testing.T) {
+	m := NewMPE()
+	m.SetZone(15, 5)
+	m.SetZone(0, 12)
+	if m.Upper.Members != 2 {
+		t.Errorf("Expecting the upper zone to shrink to 2 members, got %d", m.Upper.Members)
+	}
+	if m.PitchbendRange[0] != DefaultMasterPitchbendRange {
+		t.Errorf("Expecting master pitch bend range, got %f", m.PitchbendRange[0])
+	}
+	if m.PitchbendRange[5] != DefaultMemberPitchbendRange {
+		t.Errorf("Expecting member pitch bend range, got %f", m.PitchbendRange[5])
+	}
+	m.SetPitchbendRange(3, 24.0)
+	for ch := 1; ch <= 12; ch++ {
+		if m.PitchbendRange[ch] != 24.0 {
+			t.Errorf("Expecting range 24.0 on member channel %d, got %f", ch, m.PitchbendRange[ch])
+		}
+	}
+	if m.Zone(13) != m.Upper || m.Zone(0) != nil || m.Zone(15) != nil {
+		t.Errorf("Unexpected zones")
+	}
+}
+
+func Test_PitchbendFactor(t *testing.T) {
+	if !almostEqual(PitchbendFactor([]int{64}, 12.0), 1.0) {
+		t.Errorf("Expecting no bend in the center")
+	}
+	if !almostEqual(PitchbendFactor([]int{0, 0}, 12.0), 0.5) {
+		t.Errorf("Expecting an octave down, got %f", PitchbendFactor([]int{0, 0}, 12.0))
+	}
+	if !almostEqual(PitchbendFactor([]int{96, 0}, 48.0), 4.0) {
+		t.Errorf("Expecting two octaves up, got %f", PitchbendFactor([]int{96, 0}, 48.0))
+	}
+}
+
+func Test_Synth_MPE_member_channels(t *testing.T) {
+	s := NewSynth(audio.NewAudioConfig())
+	s.dispatchEvent(NewEvent(SetMPEZone, 0, []int{15}))
+	s.dispatchEvent(NewFloatEvent(SetPitchbendRange, 1, []float64{12.0}))
+
+	// Expression sent before the note applies to the new note.
+	s.dispatchEvent(NewEvent(PitchBend, 1, []int{96, 0}))
+	s.dispatchEvent(NewEvent(NoteOn, 1, []int{60, 127}))
+	s.dispatchEvent(NewEvent(NoteOn, 2, []int{64, 64}))
+	s.dispatchEvent(NewEvent(ChannelPressure, 2, []int{127}))
+	s.dispatchEvent(NewEvent(SetTimbre, 2, []int{0}))
+
+	ch := s.Mixer.Channels[0].(*channels.PolyphonicChannel)
+	if _, ok := ch.On.Load(60); !ok {
+		t.Errorf("Expecting note 60 to be played on the master channel")
+	}
+	if _, ok := ch.On.Load(64); !ok {
+		t.Errorf("Expecting note 64 to be played on the master channel")
+	}
+	if !almostEqual(ch.Expression[60].Pitchbend, math.Sqrt(2)) {
+		t.Errorf("Expecting a half octave bend on note 60, got %f", ch.Expression[60].Pitchbend)
+	}
+	if !almostEqual(ch.Expression[64].Pitchbend, 1.0) {
+		t.Errorf("Expecting no bend on note 64, got %f", ch.Expression[64].Pitchbend)
+	}
+	if !almostEqual(ch.Expression[64].Gain(), 1.0) {
+		t.Errorf("Expecting full gain on note 64, got %f", ch.Expression[64].Gain())
+	}
+	if ch.Expression[64].Timbre == nil || ch.Expression[60].Timbre != nil {
+		t.Errorf("Expecting only note 64 to be filtered")
+	}
+
+	s.dispatchEvent(NewEvent(NoteOff, 2, []int{64}))
+	if _, ok := ch.On.Load(64); ok {
+		t.Errorf("Expecting note 64 to be released")
+	}
+
+	// Disabling the zone releases the notes on its member channels.
+	s.dispatchEvent(NewEvent(SetMPEZone, 0, []int{0}))
+	if _, ok := ch.On.Load(60); ok {
+		t.Errorf("Expecting note 60 to be released")
+	}
+}
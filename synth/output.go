@@ -0,0 +1,106 @@
+package synth
+
+import "fmt"
+
+// EventOutput sends events somewhere other than the mixer, for example to a
+// MIDI output port (see midi.Output).
+type EventOutput interface {
+	SendEvent(ev *Event) error
+}
+
+// ChannelOutput determines where the events on a channel go.
+type ChannelOutput int
+
+const (
+	MixerOutput            ChannelOutput = iota
+	ExternalOutput         ChannelOutput = iota
+	MixerAndExternalOutput ChannelOutput = iota
+)
+
+// ParseChannelOutput parses the output options in channel definitions.
+func ParseChannelOutput(output string) (ChannelOutput, error) {
+	switch output {
+	case "", "synth":
+		return MixerOutput, nil
+	case "midi":
+		return ExternalOutput, nil
+	case "both":
+		return MixerAndExternalOutput, nil
+	}
+	return MixerOutput, fmt.Errorf("unknown output '%s' [expecting synth, midi or both]", output)
+}
+
+// The events that are scoped to a single channel and can be sent to an
+// external output.
+var channelEvents = map[EventType]bool{
+	NoteOn:                     true,
+	NoteOff:                    true,
+	SetChannelVolume:           true,
+	SetChannelExpressionVolume: true,
+	SetChannelPanning:          true,
+	SetReverb:                  true,
+	SetTremelo:                 true,
+	SetChorus:                  true,
+	SetDetuneEffect:            true,
+	SetPhaser:                  true,
+	ProgramChange:              true,
+	SilenceChannel:             true,
+	PitchBend:                  true,
+	ChannelPressure:            true,
+	SetTimbre:                  true,
+	SetPitchbendRange:          true,
+	SetSustain:                 true,
+	SetModWheel:                true,
+	Aftertouch:                 true,
+	SetReverbTime:              true,
+	SetReverbFeedback:          true,
+	SetLPFCutoff:               true,
+	SetHPFCutoff:               true,
+	SetGrain:                   true,
+	SetGrainGain:               true,
+	SetGrainSize:               true,
+	SetGrainBirthRate:          true,
+	SetGrainDensity:            true,
+	SetGrainSpread:             true,
+	SetGrainSpeed:              true,
+	SetInstrument:              true,
+	SetTuning:                  true,
+}
+
+func (s *Synth) getChannelOutput(ch int) ChannelOutput {
+	if ch < 0 || ch >= len(s.ChannelOutputs) {
+		return MixerOutput
+	}
+	return s.ChannelOutputs[ch]
+}
+
+// routeEvent sends the event to the external output if its channel is
+// routed there. Returns false if the event shouldn't reach the mixer.
+func (s *Synth) routeEvent(ev *Event) bool {
+	if ev.Type == SilenceAllChannels {
+		for ch := range s.ChannelOutputs {
+			if s.getChannelOutput(ch) != MixerOutput {
+				s.sendToOutput(NewEvent(SilenceChannel, ch, nil))
+			}
+		}
+		return true
+	}
+	if !channelEvents[ev.Type] {
+		return true
+	}
+	output := s.getChannelOutput(ev.Channel)
+	if output == MixerOutput {
+		return true
+	}
+	s.sendToOutput(ev)
+	return output == MixerAndExternalOutput
+}
+
+func (s *Synth) sendToOutput(ev *Event) {
+	if s.EventOutput == nil {
+		return
+	}
+	if err := s.EventOutput.SendEvent(ev); err != nil {
+		fmt.Println("Failed to send event to output:", err.Error())
+	}
+}
@@ -0,0 +1,67 @@
+package synth
+
+import (
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/channels"
+)
+
+type testOutput struct {
+	Events []*Event
+}
+
+func (t *testOutput) SendEvent(ev *Event) error {
+	t.Events = append(t.Events, ev)
+	return nil
+}
+
+func Test_Synth_channel_outputs(t *testing.T) {
+	s := NewSynth(audio.NewAudioConfig())
+	out := &testOutput{}
+	s.EventOutput = out
+	s.dispatchEvent(NewEvent(SetChannelOutput, 1, []int{int(ExternalOutput)}))
+	s.dispatchEvent(NewEvent(SetChannelOutput, 2, []int{int(MixerAndExternalOutput)}))
+
+	s.dispatchEvent(NewEvent(NoteOn, 0, []int{60, 100}))
+	s.dispatchEvent(NewEvent(NoteOn, 1, []int{61, 100}))
+	s.dispatchEvent(NewEvent(NoteOn, 2, []int{62, 100}))
+	s.dispatchEvent(NewFloatEvent(SetMasterGain, 1, []float64{0.5}))
+
+	isOn := func(ch, note int) bool {
+		_, on := s.Mixer.Channels[ch].(*channels.PolyphonicChannel).On.Load(note)
+		return on
+	}
+	if !isOn(0, 60) || isOn(1, 61) || !isOn(2, 62) {
+		t.Errorf("Expecting notes on channels 0 and 2 to reach the mixer")
+	}
+	if len(out.Events) != 2 || out.Events[0].Channel != 1 || out.Events[1].Channel != 2 {
+		t.Fatalf("Expecting note events for channels 1 and 2, got %v", out.Events)
+	}
+
+	out.Events = nil
+	s.dispatchEvent(NewEvent(SilenceAllChannels, 0, nil))
+	if len(out.Events) != 2 || out.Events[0].Type != SilenceChannel || out.Events[1].Type != SilenceChannel {
+		t.Errorf("Expecting the external channels to be silenced, got %v", out.Events)
+	}
+	if isOn(2, 62) {
+		t.Errorf("Expecting the mixer to be silenced")
+	}
+}
+
+func Test_ParseChannelOutput(t *testing.T) {
+	cases := map[string]ChannelOutput{
+		"":      MixerOutput,
+		"synth": MixerOutput,
+		"midi":  ExternalOutput,
+		"both":  MixerAndExternalOutput,
+	}
+	for s, expected := range cases {
+		if output, err := ParseChannelOutput(s); err != nil || output != expected {
+			t.Errorf("Expecting %v for '%s', got %v", expected, s, output)
+		}
+	}
+	if _, err := ParseChannelOutput("osc"); err == nil {
+		t.Errorf("Expecting error for unknown output")
+	}
+}
@@ -0,0 +1,115 @@
+package synth
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// ChannelParameter is a channel setting that remote controls (OSC, the
+// websocket) can change by name.
+type ChannelParameter struct {
+	EventType EventType
+	Float     bool
+}
+
+var ChannelParameters = map[string]ChannelParameter{
+	"volume":           {SetChannelVolume, false},
+	"panning":          {SetChannelPanning, false},
+	"expression":       {SetChannelExpressionVolume, false},
+	"program":          {ProgramChange, false},
+	"reverb":           {SetReverb, false},
+	"reverb_time":      {SetReverbTime, true},
+	"reverb_feedback":  {SetReverbFeedback, true},
+	"tremelo":          {SetTremelo, false},
+	"lpf":              {SetLPFCutoff, false},
+	"hpf":              {SetHPFCutoff, false},
+	"sustain":          {SetSustain, false},
+	"mod_wheel":        {SetModWheel, false},
+	"grain_gain":       {SetGrainGain, true},
+	"grain_size":       {SetGrainSize, true},
+	"grain_birth_rate": {SetGrainBirthRate, true},
+	"grain_density":    {SetGrainDensity, false},
+	"grain_spread":     {SetGrainSpread, true},
+	"grain_speed":      {SetGrainSpeed, true},
+	"mute":             {SetMute, false},
+	"solo":             {SetSolo, false},
+	"solo_safe":        {SetSoloSafe, false},
+}
+
+// NewChannelParameterEvent returns the event that sets a channel parameter.
+// Values for integer parameters are rounded.
+func NewChannelParameterEvent(param string, channel int, value float64) (*Event, error) {
+	p, ok := ChannelParameters[param]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel parameter '%s'", param)
+	}
+	if channel < 0 || channel > 15 {
+		return nil, fmt.Errorf("invalid channel %d", channel)
+	}
+	if p.Float {
+		return NewFloatEvent(p.EventType, channel, []float64{value}), nil
+	}
+	return NewEvent(p.EventType, channel, []int{int(math.Round(value))}), nil
+}
+
+// The values of the parameters before they've been set.
+var channelParameterDefaults = map[string]float64{
+	"volume":     19,
+	"panning":    64,
+	"expression": 127,
+}
+
+// ChannelParameterValues remembers the last value of every channel parameter,
+// so that UIs can show and edit them.
+type ChannelParameterValues struct {
+	mux    sync.Mutex
+	values []map[string]float64
+}
+
+func NewChannelParameterValues(channels int) *ChannelParameterValues {
+	result := &ChannelParameterValues{
+		values: make([]map[string]float64, channels),
+	}
+	for i := range result.values {
+		result.values[i] = map[string]float64{}
+		for param, value := range channelParameterDefaults {
+			result.values[i][param] = value
+		}
+	}
+	return result
+}
+
+// Record the value if the event sets a channel parameter.
+func (c *ChannelParameterValues) Record(ev *Event) {
+	for param, p := range ChannelParameters {
+		if p.EventType != ev.Type {
+			continue
+		}
+		if p.Float && len(ev.FloatValues) > 0 {
+			c.Set(ev.Channel, param, ev.FloatValues[0])
+		} else if !p.Float && len(ev.Values) > 0 {
+			c.Set(ev.Channel, param, float64(ev.Values[0]))
+		}
+		return
+	}
+}
+
+func (c *ChannelParameterValues) Set(ch int, param string, value float64) {
+	if ch < 0 || ch >= len(c.values) {
+		return
+	}
+	c.mux.Lock()
+	c.values[ch][param] = value
+	c.mux.Unlock()
+}
+
+// Get returns the last value of the parameter; zero when it was never set.
+func (c *ChannelParameterValues) Get(ch int, param string) float64 {
+	if ch < 0 || ch >= len(c.values) {
+		return 0.0
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.values[ch][param]
+}
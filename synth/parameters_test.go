@@ -0,0 +1,26 @@
+package synth
+
+import (
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+)
+
+func Test_Synth_records_channel_parameters(t *testing.T) {
+	s := NewSynth(audio.NewAudioConfig())
+	if v := s.Parameters.Get(3, "volume"); v != 19 {
+		t.Errorf("Expecting the default volume, got %f", v)
+	}
+	s.dispatchEvent(NewEvent(SetLPFCutoff, 3, []int{1200}))
+	s.dispatchEvent(NewFloatEvent(SetReverbTime, 3, []float64{0.5}))
+	s.dispatchEvent(NewEvent(NoteOn, 3, []int{60, 100}))
+	if v := s.Parameters.Get(3, "lpf"); v != 1200 {
+		t.Errorf("Expecting lpf 1200, got %f", v)
+	}
+	if v := s.Parameters.Get(3, "reverb_time"); v != 0.5 {
+		t.Errorf("Expecting reverb_time 0.5, got %f", v)
+	}
+	if v := s.Parameters.Get(4, "lpf"); v != 0 {
+		t.Errorf("Expecting other channels to be unchanged, got %f", v)
+	}
+}
@@ -0,0 +1,123 @@
+package synth
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/bspaans/bleep/generators"
+	"github.com/bspaans/bleep/ui"
+)
+
+// The number of samples in the spectrum analysis. Must be a power of two.
+const SpectrumSize = 2048
+
+// The number of logarithmically spaced frequency bands in the spectrum.
+const SpectrumBands = 24
+
+// The lowest frequency in the spectrum.
+const SpectrumMinFrequency = 20.0
+
+// The Spectrum keeps the most recent (mono) samples in a ring buffer and
+// turns them into the levels of a number of frequency bands.
+type Spectrum struct {
+	SampleRate int
+	// The lower bound of every band in Hz.
+	Frequencies []float64
+
+	buffer []float64
+	pos    int
+	window []float64
+}
+
+func NewSpectrum(sampleRate, size, bands int) *Spectrum {
+	s := &Spectrum{
+		SampleRate:  sampleRate,
+		Frequencies: make([]float64, bands),
+		buffer:      make([]float64, size),
+		window:      generators.HannWindowFunction(size),
+	}
+	maxFreq := math.Min(20000.0, float64(sampleRate)/2)
+	ratio := math.Pow(maxFreq/SpectrumMinFrequency, 1.0/float64(bands))
+	for i := range s.Frequencies {
+		s.Frequencies[i] = SpectrumMinFrequency * math.Pow(ratio, float64(i))
+	}
+	return s
+}
+
+func (s *Spectrum) Add(v float64) {
+	s.buffer[s.pos] = v
+	s.pos = (s.pos + 1) % len(s.buffer)
+}
+
+// Window returns a copy of the buffer, oldest sample first.
+func (s *Spectrum) Window() []float64 {
+	result := make([]float64, 0, len(s.buffer))
+	result = append(result, s.buffer[s.pos:]...)
+	return append(result, s.buffer[:s.pos]...)
+}
+
+// Analyse returns the level in dBFS of every band. A full scale sine wave
+// reads (close to) 0dB.
+func (s *Spectrum) Analyse(samples []float64) []float64 {
+	n := len(samples)
+	x := make([]complex128, n)
+	windowSum := 0.0
+	for i, v := range samples {
+		x[i] = complex(v*s.window[i], 0)
+		windowSum += s.window[i]
+	}
+	FFT(x)
+
+	binWidth := float64(s.SampleRate) / float64(n)
+	maxFreq := math.Min(20000.0, float64(s.SampleRate)/2)
+	result := make([]float64, len(s.Frequencies))
+	for i, lower := range s.Frequencies {
+		upper := maxFreq
+		if i+1 < len(s.Frequencies) {
+			upper = s.Frequencies[i+1]
+		}
+		lowerBin := int(math.Round(lower / binWidth))
+		upperBin := int(math.Round(upper / binWidth))
+		if upperBin <= lowerBin {
+			upperBin = lowerBin + 1
+		}
+		if upperBin > n/2 {
+			upperBin = n / 2
+		}
+		peak := 0.0
+		for bin := lowerBin; bin < upperBin; bin++ {
+			peak = math.Max(peak, 2*cmplx.Abs(x[bin])/windowSum)
+		}
+		result[i] = ui.ToDecibels(peak)
+	}
+	return result
+}
+
+// FFT does an in-place radix-2 fast fourier transform. The length of x must
+// be a power of two.
+func FFT(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a := x[start+k]
+				b := x[start+k+size/2] * w
+				x[start+k] = a + b
+				x[start+k+size/2] = a - b
+				w *= step
+			}
+		}
+	}
+}
@@ -2,7 +2,6 @@ package synth
 
 import (
 	"fmt"
-	"math"
 	"time"
 
 	"github.com/bspaans/bleep/audio"
@@ -20,6 +19,15 @@ type Synth struct {
 	Outputs  chan *ui.UIEvent
 	Debug    bool
 	Recorder *sinks.WavSink
+	MPE      *MPE
+
+	// EventOutput receives the events on channels that are routed to an
+	// external output (see SetChannelOutput).
+	EventOutput    EventOutput
+	ChannelOutputs []ChannelOutput
+
+	// Parameters holds the last value of the channel parameters.
+	Parameters *ChannelParameterValues
 }
 
 func NewSynth(cfg *audio.AudioConfig) *Synth {
@@ -30,6 +38,13 @@ func NewSynth(cfg *audio.AudioConfig) *Synth {
 		Inputs:  make(chan *Event, cfg.MidiEventInputBufferSize),
 		Outputs: make(chan *ui.UIEvent, 128),
 		Debug:   cfg.Debug,
+		MPE:     NewMPE(),
+
+		ChannelOutputs: make([]ChannelOutput, 16),
+		Parameters:     NewChannelParameterValues(16),
+	}
+	if cfg.MetersPerSecond > 0 {
+		s.Mixer.Meter = NewMeter(cfg, len(s.Mixer.Channels))
 	}
 	return s
 }
@@ -67,7 +82,14 @@ func (s *Synth) Start() {
 		sink.Start(s.Mixer.GetSamples)
 	}
 
-	for {
+	meterSteps := 0
+	if s.Config.MetersPerSecond > 0 {
+		meterSteps = s.Config.HandleEventsPerSecond / s.Config.MetersPerSecond
+		if meterSteps < 1 {
+			meterSteps = 1
+		}
+	}
+	for step := 1; ; step++ {
 		start := time.Now()
 		//s.writeSamples(s.Config.StepSize)
 		canRead := true
@@ -79,6 +101,9 @@ func (s *Synth) Start() {
 				canRead = false
 			}
 		}
+		if meterSteps > 0 && step%meterSteps == 0 {
+			s.PublishMeters()
+		}
 		elapsed := time.Now().Sub(start)
 		if elapsed > stepDuration {
 			fmt.Println("Warning: synthesizer underrun")
@@ -89,11 +114,53 @@ func (s *Synth) Start() {
 	}
 }
 
+// PublishMeters sends the levels since the previous call to the Outputs.
+// The meters are dropped when nobody is keeping up with the Outputs.
+func (s *Synth) PublishMeters() {
+	if s.Mixer.Meter == nil {
+		return
+	}
+	ev := ui.NewUIEvent(ui.ChannelsOutputEvent)
+	ev.Meters = s.Mixer.Meter.Snapshot()
+	select {
+	case s.Outputs <- ev:
+	default:
+	}
+}
+
+// DispatchEvent handles an event immediately. Use Inputs instead once the
+// synthesizer has been started; this is for offline rendering.
+func (s *Synth) DispatchEvent(ev *Event) {
+	s.dispatchEvent(ev)
+}
+
 func (s *Synth) dispatchEvent(ev *Event) {
 	et := ev.Type
 	ch := ev.Channel
 	values := ev.Values
-	if et == NoteOn {
+
+	if s.Debug {
+		if len(values) == 0 {
+			fmt.Println(et, "on channel", ch)
+		} else {
+			fmt.Println(et, "on channel", ch, values)
+		}
+	}
+
+	if et == SetChannelOutput {
+		if ch >= 0 && ch < len(s.ChannelOutputs) {
+			s.ChannelOutputs[ch] = ChannelOutput(values[0])
+		}
+		return
+	}
+	s.Parameters.Record(ev)
+	if !s.routeEvent(ev) {
+		return
+	}
+
+	if zone := s.MPE.Zone(ch); zone != nil {
+		s.dispatchMemberEvent(zone, ev)
+	} else if et == NoteOn {
 		velocity := float64(int(values[1])) / 127
 		s.Mixer.NoteOn(ch, values[0], velocity)
 	} else if et == NoteOff {
@@ -114,6 +181,10 @@ func (s *Synth) dispatchEvent(ev *Event) {
 		s.Mixer.ChangeInstrument(s.Config, ch, values[0])
 	} else if et == SetInstrument {
 		s.Mixer.SetInstrument(s.Config, ch, ev.Instrument)
+	} else if et == SetTuning {
+		s.Mixer.SetTuning(ch, ev.Tuning)
+	} else if et == SetGlobalTuning {
+		s.Mixer.SetGlobalTuning(ev.Tuning)
 	} else if et == SilenceChannel {
 		s.Mixer.SilenceChannel(ch)
 	} else if et == SetChannelVolume {
@@ -140,26 +211,38 @@ func (s *Synth) dispatchEvent(ev *Event) {
 		s.Mixer.SilenceAllChannels()
 	} else if et == ToggleSoloChannel {
 		s.Mixer.ToggleSoloChannel(ch)
+	} else if et == ToggleMuteChannel {
+		s.Mixer.ToggleMuteChannel(ch)
+	} else if et == SetSolo {
+		s.Mixer.SetSolo(ch, values[0] != 0)
+	} else if et == SetSoloSafe {
+		s.Mixer.SetSoloSafe(ch, values[0] != 0)
+	} else if et == SetMute {
+		s.Mixer.SetMute(ch, values[0] != 0)
 	} else if et == SetMasterGain {
 		s.Mixer.SetMasterGain(ev.FloatValues[0])
+	} else if et == SetMasterBus {
+		s.Mixer.Master = ev.MasterBus
+	} else if et == SetChannelDynamics {
+		s.Mixer.SetDynamics(ch, ev.Dynamics)
 	} else if et == PitchBend {
-		semitones := float64(values[0]-64) / 64.0 // -1.0 <-> 1.0
-		semitones *= (64 / 5)
-		pitchbendFactor := math.Pow(2, semitones/12)
-		s.Mixer.SetPitchbend(ch, pitchbendFactor)
+		s.Mixer.SetPitchbend(ch, PitchbendFactor(values, s.MPE.GetPitchbendRange(ch)))
+	} else if et == SetSustain {
+		s.Mixer.SetSustain(ch, values[0] >= 64)
+	} else if et == SetModWheel {
+		s.Mixer.SetModWheel(ch, float64(values[0])/127)
+	} else if et == ChannelPressure {
+		s.Mixer.SetChannelPressure(ch, float64(values[0])/127)
+	} else if et == Aftertouch {
+		s.Mixer.SetAftertouch(ch, values[0], float64(values[1])/127)
+	} else if et == SetPitchbendRange {
+		s.MPE.SetPitchbendRange(ch, ev.FloatValues[0])
+	} else if et == SetMPEZone {
+		s.setMPEZone(ch, values[0])
 	} else if et == ForceUIReload {
 		s.Outputs <- ui.NewUIEvent(ui.ForceReloadEvent)
 	} else {
 	}
-
-	if s.Debug {
-		if len(values) == 0 {
-			fmt.Println(et, "on channel", ch)
-		} else {
-			fmt.Println(et, "on channel", ch, values)
-		}
-	}
-
 }
 func (s *Synth) SetMasterGain(v float64) {
 	s.Inputs <- NewFloatEvent(SetMasterGain, 0, []float64{v})
@@ -180,6 +263,10 @@ func (s *Synth) LoadInstrumentBank(file string) error {
 	if err != nil {
 		return err
 	}
+	return s.LoadInstrumentBankDef(bankDef)
+}
+
+func (s *Synth) LoadInstrumentBankDef(bankDef *instruments.BankDef) error {
 	if err := bankDef.Validate(s.Config); err != nil {
 		return err
 	}
@@ -192,6 +279,10 @@ func (s *Synth) LoadPercussionBank(file string) error {
 	if err != nil {
 		return err
 	}
+	return s.LoadPercussionBankDef(bankDef)
+}
+
+func (s *Synth) LoadPercussionBankDef(bankDef *instruments.BankDef) error {
 	if err := bankDef.Validate(s.Config); err != nil {
 		return err
 	}
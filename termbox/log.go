@@ -0,0 +1,120 @@
+package termbox
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The EventLog keeps the most recent lines that were written to it. While the
+// UI is running everything that's printed to stdout ends up in the log,
+// instead of messing up the screen.
+type EventLog struct {
+	Size int
+
+	mux       sync.Mutex
+	lines     []string
+	partial   string
+	lastError string
+}
+
+func NewEventLog(size int) *EventLog {
+	return &EventLog{
+		Size:  size,
+		lines: []string{},
+	}
+}
+
+func (l *EventLog) Write(p []byte) (int, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	lines := strings.Split(l.partial+string(p), "\n")
+	l.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		l.add(line)
+	}
+	return len(p), nil
+}
+
+func (l *EventLog) add(line string) {
+	line = strings.TrimRight(line, "\r")
+	if line == "" {
+		return
+	}
+	if isErrorLine(line) {
+		l.lastError = line
+	}
+	l.lines = append(l.lines, line)
+	if len(l.lines) > l.Size {
+		l.lines = append([]string{}, l.lines[len(l.lines)-l.Size:]...)
+	}
+}
+
+func (l *EventLog) Println(a ...interface{}) {
+	fmt.Fprintln(l, a...)
+}
+
+func (l *EventLog) Error(err error) {
+	l.Println("Error:", err.Error())
+}
+
+// Tail returns the last n lines.
+func (l *EventLog) Tail(n int) []string {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if n > len(l.lines) {
+		n = len(l.lines)
+	}
+	if n <= 0 {
+		return []string{}
+	}
+	return append([]string{}, l.lines[len(l.lines)-n:]...)
+}
+
+// LastError returns the most recent line that looks like an error, until
+// it's cleared.
+func (l *EventLog) LastError() string {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.lastError
+}
+
+func (l *EventLog) ClearError() {
+	l.mux.Lock()
+	l.lastError = ""
+	l.mux.Unlock()
+}
+
+// Capture redirects stdout to the log, until restore is called.
+func (l *EventLog) Capture() (restore func(), err error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan bool)
+	go func() {
+		io.Copy(l, r)
+		close(done)
+	}()
+	return func() {
+		os.Stdout = stdout
+		w.Close()
+		<-done
+		r.Close()
+	}, nil
+}
+
+// The components report errors by printing them; these are the prefixes
+// they use.
+func isErrorLine(line string) bool {
+	for _, prefix := range []string{"Error", "Failed", "Invalid", "Oh no"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package termbox
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_EventLog_lines(t *testing.T) {
+	l := NewEventLog(3)
+	fmt.Fprint(l, "Starting seq")
+	fmt.Fprint(l, "uencer\nLoading [1] piano\n\n")
+	if tail := l.Tail(5); len(tail) != 2 || tail[0] != "Starting sequencer" || tail[1] != "Loading [1] piano" {
+		t.Errorf("Expecting two complete lines, got %v", tail)
+	}
+	l.Println("Failed to load sequencer: oops")
+	l.Println("t = 16")
+	if tail := l.Tail(5); len(tail) != 3 || tail[0] != "Loading [1] piano" {
+		t.Errorf("Expecting only the last three lines, got %v", tail)
+	}
+	if l.LastError() != "Failed to load sequencer: oops" {
+		t.Errorf("Expecting the failure to be the last error, got '%s'", l.LastError())
+	}
+	l.ClearError()
+	if l.LastError() != "" {
+		t.Errorf("Expecting the error to be cleared")
+	}
+}
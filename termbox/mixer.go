@@ -0,0 +1,189 @@
+package termbox
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bspaans/bleep/controller"
+	"github.com/bspaans/bleep/ui"
+	termui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// The lowest cutoff frequency that can be set from the mixer.
+const minFrequency = 20.0
+
+// A stripParameter is a column in the mixer that can be edited with the
+// keyboard.
+type stripParameter struct {
+	Name  string // see synth.ChannelParameters
+	Label string
+	Max   float64
+	// The step size, or the factor for frequencies.
+	Step      float64
+	Frequency bool
+}
+
+var stripParameters = []stripParameter{
+	{"program", "Prog", 127, 1, false},
+	{"volume", "Vol", 127, 4, false},
+	{"panning", "Pan", 127, 4, false},
+	{"expression", "Expr", 127, 4, false},
+	{"reverb", "Rev", 127, 4, false},
+	{"tremelo", "Trem", 127, 4, false},
+	{"lpf", "LPF", 20000, math.Pow(2, 0.25), true},
+	{"hpf", "HPF", 20000, math.Pow(2, 0.25), true},
+}
+
+// change returns the value after n steps up, or down for a negative n.
+//
+// Filters are switched off with a zero cutoff. The low pass filter is off
+// above the maximum frequency and the high pass filter below the minimum.
+func (p stripParameter) change(value float64, n int) float64 {
+	if !p.Frequency {
+		return math.Max(0, math.Min(p.Max, value+p.Step*float64(n)))
+	}
+	lowPass := p.Name == "lpf"
+	if value == 0 && lowPass {
+		value = p.Max * p.Step
+	} else if value == 0 {
+		value = minFrequency / p.Step
+	}
+	value = math.Round(value * math.Pow(p.Step, float64(n)))
+	if lowPass && value > p.Max || !lowPass && value < minFrequency {
+		return 0
+	}
+	return math.Max(minFrequency, math.Min(p.Max, value))
+}
+
+func (p stripParameter) format(value float64) string {
+	if p.Frequency {
+		if value == 0 {
+			return "off"
+		} else if value >= 1000 {
+			return strconv.FormatFloat(value/1000, 'f', 1, 64) + "k"
+		}
+		return strconv.Itoa(int(value))
+	} else if p.Name == "panning" {
+		if value < 64 {
+			return "L" + strconv.Itoa(64-int(value))
+		} else if value > 64 {
+			return "R" + strconv.Itoa(int(value)-64)
+		}
+		return "C"
+	}
+	return strconv.Itoa(int(value))
+}
+
+// The mixer has a row per channel, followed by the master.
+func (t *TermBox) mixerTable(ctrl *controller.Controller, meters *ui.Meters, clippedAt []time.Time, width int) *widgets.Table {
+	table := widgets.NewTable()
+	table.Title = "Mixer"
+	table.RowSeparator = false
+	table.TextAlignment = termui.AlignLeft
+
+	header := []string{"Ch"}
+	widths := []int{4}
+	for _, p := range stripParameters {
+		header = append(header, p.Label)
+		widths = append(widths, 6)
+	}
+	header = append(header, "M/S", "Level")
+	widths = append(widths, 5, 0)
+	used := 0
+	for _, w := range widths {
+		used += w
+	}
+	widths[len(widths)-1] = max(width-used-2, 8)
+	table.ColumnWidths = widths
+	table.Rows = [][]string{header}
+	table.RowStyles[0] = termui.NewStyle(termui.ColorWhite, termui.ColorClear, termui.ModifierBold)
+
+	now := time.Now()
+	meterWidth := widths[len(widths)-1] - 7
+	mixer := ctrl.Synth.Mixer
+	for ch := 0; ch < len(mixer.Channels); ch++ {
+		row := []string{strconv.Itoa(ch)}
+		for i, p := range stripParameters {
+			value := p.format(ctrl.Synth.Parameters.Get(ch, p.Name))
+			if ch == t.Channel && i == t.Parameter {
+				value = "[" + value + "](fg:black,bg:yellow)"
+			}
+			row = append(row, value)
+		}
+		row = append(row, switches(mixer.Mute[ch], mixer.Solo[ch], mixer.SoloSafe[ch]), "")
+		if meters != nil && ch < len(meters.Channels) {
+			row[len(row)-1] = meterBar(meters.Channels[ch], isHeld(clippedAt, ch, now), meterWidth)
+		}
+		table.Rows = append(table.Rows, row)
+		if ch == t.Channel {
+			table.RowStyles[len(table.Rows)-1] = termui.NewStyle(termui.ColorCyan, termui.ColorClear, termui.ModifierBold)
+		}
+	}
+	master := []string{"M"}
+	for range stripParameters {
+		master = append(master, "")
+	}
+	master[2] = strconv.FormatFloat(mixer.MasterGain, 'f', 2, 64)
+	master = append(master, "", "")
+	if meters != nil {
+		master[len(master)-1] = meterBar(meters.Master, isHeld(clippedAt, len(meters.Channels), now), meterWidth)
+	}
+	table.Rows = append(table.Rows, master)
+	return table
+}
+
+// switches shows M when the channel is muted, S when it's soloed, and s
+// when it's solo safe.
+func switches(mute, solo, soloSafe bool) string {
+	result := ""
+	if mute {
+		result += "[M](fg:red)"
+	}
+	if solo {
+		result += "[S](fg:yellow)"
+	} else if soloSafe {
+		result += "s"
+	}
+	return result
+}
+
+func isHeld(clippedAt []time.Time, i int, now time.Time) bool {
+	return i < len(clippedAt) && now.Sub(clippedAt[i]) < clipHold
+}
+
+// meterBar shows the peak level between -60dB and 0dB, followed by the level
+// in dB.
+func meterBar(levels ui.Levels, clipped bool, width int) string {
+	if width < 1 {
+		width = 1
+	}
+	db := levels.PeakDecibels()
+	filled := int(math.Round(meterHeight(db) / meterRange * float64(width)))
+	color := "green"
+	if clipped {
+		color = "red"
+	} else if db > -6 {
+		color = "yellow"
+	}
+	bar := "[" + strings.Repeat("|", filled) + "](fg:" + color + ")" + strings.Repeat(" ", width-filled)
+	if db <= -meterRange {
+		return bar + "   -inf"
+	}
+	return bar + fmt.Sprintf(" %6.1f", db)
+}
+
+// meterHeight maps -60dB to 0dB onto 0 to 60.
+func meterHeight(db float64) float64 {
+	return math.Max(0, math.Min(meterRange, db+meterRange))
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,54 @@
+package termbox
+
+import (
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/controller"
+	"github.com/bspaans/bleep/synth"
+)
+
+func Test_stripParameter_change(t *testing.T) {
+	volume, lpf, hpf := stripParameters[1], stripParameters[6], stripParameters[7]
+	if v := volume.change(19, 1); v != 23 {
+		t.Errorf("Expecting volume 23, got %f", v)
+	}
+	if v := volume.change(125, 2); v != 127 {
+		t.Errorf("Expecting the volume to stop at 127, got %f", v)
+	}
+	if v := lpf.change(0, -1); v != 20000 {
+		t.Errorf("Expecting the low pass filter to start at the top, got %f", v)
+	}
+	if v := lpf.change(20000, 1); v != 0 {
+		t.Errorf("Expecting the low pass filter to switch off, got %f", v)
+	}
+	if v := hpf.change(0, 1); v != 20 {
+		t.Errorf("Expecting the high pass filter to start at the bottom, got %f", v)
+	}
+	if v := hpf.change(20, -1); v != 0 {
+		t.Errorf("Expecting the high pass filter to switch off, got %f", v)
+	}
+	if v := hpf.change(1000, 4); v != 2000 {
+		t.Errorf("Expecting four steps to be an octave, got %f", v)
+	}
+}
+
+func Test_TermBox_edit_channel_parameters(t *testing.T) {
+	ctrl := controller.NewController(audio.NewAudioConfig())
+	tb := NewTermBox()
+	for _, key := range []string{"[", "[", "}", "+", "+"} {
+		tb.handleKey(ctrl, key)
+	}
+	if tb.Channel != 14 || stripParameters[tb.Parameter].Name != "panning" {
+		t.Fatalf("Expecting panning on channel 14 to be selected, got %d %d", tb.Channel, tb.Parameter)
+	}
+	for _, expected := range []int{68, 72} {
+		ev := <-ctrl.Synth.Inputs
+		if ev.Type != synth.SetChannelPanning || ev.Channel != 14 || ev.Values[0] != expected {
+			t.Errorf("Expecting panning %d on channel 14, got %v", expected, ev)
+		}
+	}
+	if v := ctrl.Synth.Parameters.Get(14, "panning"); v != 72 {
+		t.Errorf("Expecting the panning to be recorded, got %f", v)
+	}
+}
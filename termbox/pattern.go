@@ -0,0 +1,82 @@
+package termbox
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bspaans/bleep/sequencer"
+	"github.com/gizak/termui/v3/widgets"
+)
+
+// The pattern view shows the current bar in sixteenth notes.
+const stepsPerBeat = 4
+
+// The pattern view has a row per channel that starts notes in the current
+// bar, and a column per step. The step that's playing is highlighted.
+func patternView(seq *sequencer.Sequencer) *widgets.Paragraph {
+	view := widgets.NewParagraph()
+	view.Title = "Pattern"
+	if seq == nil || seq.Status.Granularity <= 0 {
+		view.Text = "[no sequencer](fg:yellow)"
+		return view
+	}
+	ticksPerStep := uint(max(seq.Status.Granularity/stepsPerBeat, 1))
+	ticksPerBar := uint(seq.Status.Granularity * beatsPerBar)
+	bar := seq.Status.Time / ticksPerBar
+	start := bar * ticksPerBar
+	// Time is the next tick, so the last played one might be in the previous
+	// bar.
+	playing := -1
+	if seq.Status.Time > start {
+		playing = int((seq.Status.Time - 1 - start) / ticksPerStep)
+	}
+	view.Title = fmt.Sprintf("Pattern: bar %d", bar+1)
+	rows := patternRows(seq.Pattern.Notes(start, start+ticksPerBar), ticksPerStep, seq.Status.Granularity, playing)
+	if len(rows) == 0 {
+		rows = []string{"(no notes in this bar)"}
+	}
+	view.Text = strings.Join(rows, "\n")
+	return view
+}
+
+// patternRows returns the steps of every channel that starts notes. Notes
+// holds the played channels per tick; a step is filled when any of its ticks
+// has a note. The playing step is highlighted.
+func patternRows(notes []uint32, ticksPerStep uint, granularity int, playing int) []string {
+	steps := []uint32{}
+	for i, n := range notes {
+		if uint(i)%ticksPerStep == 0 {
+			steps = append(steps, 0)
+		}
+		steps[len(steps)-1] |= n
+	}
+	all := uint32(0)
+	for _, s := range steps {
+		all |= s
+	}
+	stepsPerBeat := max(granularity/int(ticksPerStep), 1)
+	rows := []string{}
+	for ch := uint(0); ch < 32; ch++ {
+		if all&(1<<ch) == 0 {
+			continue
+		}
+		row := fmt.Sprintf("%2d ", ch)
+		for i, s := range steps {
+			if i > 0 && i%stepsPerBeat == 0 {
+				row += " "
+			}
+			cell := "·"
+			if s&(1<<ch) != 0 {
+				cell = "●"
+			}
+			if i == playing {
+				cell = "[" + cell + "](fg:black,bg:yellow)"
+			} else if cell == "●" {
+				cell = "[" + cell + "](fg:green)"
+			}
+			row += cell
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
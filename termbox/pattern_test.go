@@ -0,0 +1,27 @@
+package termbox
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_patternRows(t *testing.T) {
+	// Two beats of eight ticks, in steps of two ticks. Channel 1 plays on the
+	// second tick of the first step, channel 9 on the offbeats. The third step
+	// is playing.
+	notes := make([]uint32, 16)
+	notes[1] = 1 << 1
+	notes[4] = 1 << 9
+	notes[12] = 1 << 9
+	rows := patternRows(notes, 2, 8, 2)
+	expected := []string{
+		" 1 [●](fg:green)·[·](fg:black,bg:yellow)· ····",
+		" 9 ··[●](fg:black,bg:yellow)· ··[●](fg:green)·",
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Expecting %q, got %q", expected, rows)
+	}
+	if rows := patternRows(make([]uint32, 16), 2, 8, 0); len(rows) != 0 {
+		t.Errorf("Expecting no rows without notes, got %q", rows)
+	}
+}
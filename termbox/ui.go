@@ -3,25 +3,64 @@ package termbox
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/bspaans/bleep/controller"
+	"github.com/bspaans/bleep/midi/mapping"
+	"github.com/bspaans/bleep/synth"
 	"github.com/bspaans/bleep/ui"
 	termui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
 )
 
+// The range of the level meters in decibels.
+const meterRange = 60.0
+
+// How long clip indicators stay on.
+const clipHold = 2 * time.Second
+
+// The number of lines kept in the event log.
+const logSize = 500
+
+// The sequencer position is shown in bars of four beats.
+const beatsPerBar = 4
+
+const help = `[space] play/pause  [r] rewind  [left/right] seek  [up/down] bpm
+[ and ] select channel  { and } select parameter  - and + change it
+[s] solo  [S] solo safe  [m] mute selected channel  [0-9] solo channel
+[ctrl-r] reload
+[t] learn target  [c] learn channel  [l] start/cancel MIDI learn
+[esc] clear error  [?] toggle help  [ctrl-c] quit`
+
 type TermBox struct {
-	Started        bool
-	mux            sync.Mutex
-	ChannelOutputs []float64
+	Started bool
+	mux     sync.Mutex
+	Meters  *ui.Meters
+	Log     *EventLog
+
+	// The selected mixer channel and parameter (see stripParameters).
+	Channel   int
+	Parameter int
+	ShowHelp  bool
+
+	// The parameter and channel that get mapped in MIDI learn mode.
+	LearnTarget  int
+	LearnChannel int
+	Learning     bool
+
+	// When the channels (and the master, which comes last) last clipped.
+	clippedAt []time.Time
 }
 
 func NewTermBox() *TermBox {
 	return &TermBox{
-		Started: false,
+		Started:   false,
+		Log:       NewEventLog(logSize),
+		Parameter: 1,
 	}
 }
 
@@ -38,9 +77,117 @@ func (t *TermBox) Start(ctrl *controller.Controller) *TermBox {
 func (t *TermBox) HandleEvent(ev *ui.UIEvent) {
 	if ev.Type == ui.ChannelsOutputEvent {
 		t.mux.Lock()
-		t.ChannelOutputs = ev.Values
+		t.Meters = ev.Meters
+		t.holdClips(time.Now())
 		t.mux.Unlock()
+	} else if ev.Type == ui.MIDILearnEvent {
+		t.mux.Lock()
+		t.Learning = false
+		t.mux.Unlock()
+	} else if ev.Type == ui.ForceReloadEvent {
+		t.Log.Println("Sequencer reloaded")
+	}
+}
+
+// Keys: 't' selects the parameter to learn, 'c' the channel, and 'l' starts
+// (or cancels) MIDI learn mode. The next knob that's turned gets mapped.
+func (t *TermBox) handleLearnKey(ctrl *controller.Controller, key string) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	targets := mapping.Targets()
+	if key == "t" {
+		t.LearnTarget = (t.LearnTarget + 1) % len(targets)
+	} else if key == "c" {
+		t.LearnChannel = (t.LearnChannel + 1) % 16
+	} else if key == "l" && t.Learning {
+		ctrl.CancelMIDILearn()
+		t.Learning = false
+		t.Log.Println("Cancelled MIDI learn")
+		return
+	} else if key == "l" {
+		def := &mapping.MappingDef{
+			Target:  string(targets[t.LearnTarget]),
+			Channel: t.LearnChannel,
+		}
+		if err := ctrl.StartMIDILearn(def); err != nil {
+			t.Log.Error(err)
+			return
+		}
+		t.Learning = true
+		return
 	}
+	t.Log.Println("MIDI learn target:", targets[t.LearnTarget], "on channel", t.LearnChannel)
+}
+
+// handleKey handles every key except for ctrl-c.
+func (t *TermBox) handleKey(ctrl *controller.Controller, key string) {
+	channels := len(ctrl.Synth.Mixer.Channels)
+	if key == "<C-r>" {
+		if err := ctrl.ReloadInstrumentBank(); err != nil {
+			t.Log.Error(err)
+		}
+		if err := ctrl.ReloadPercussionBank(); err != nil {
+			t.Log.Error(err)
+		}
+		ctrl.ReloadSequencer()
+	} else if key >= "0" && key <= "9" {
+		i, _ := strconv.Atoi(key)
+		ctrl.ToggleSoloChannel(i)
+	} else if key == "s" {
+		ctrl.ToggleSoloChannel(t.Channel)
+	} else if key == "S" {
+		ctrl.SetSoloSafe(t.Channel, !ctrl.Synth.Mixer.SoloSafe[t.Channel])
+	} else if key == "m" {
+		ctrl.ToggleMuteChannel(t.Channel)
+	} else if key == "<Space>" {
+		ctrl.ToggleSequencerPlaying()
+	} else if key == "r" {
+		ctrl.RewindSequencer()
+	} else if key == "<Right>" {
+		ctrl.MoveSequencerForward()
+	} else if key == "<Left>" {
+		ctrl.MoveSequencerBackward()
+	} else if key == "<Up>" {
+		ctrl.IncreaseSequencerBPM()
+	} else if key == "<Down>" {
+		ctrl.DecreaseSequencerBPM()
+	} else if key == "[" {
+		t.Channel = (t.Channel + channels - 1) % channels
+	} else if key == "]" {
+		t.Channel = (t.Channel + 1) % channels
+	} else if key == "{" {
+		t.Parameter = (t.Parameter + len(stripParameters) - 1) % len(stripParameters)
+	} else if key == "}" {
+		t.Parameter = (t.Parameter + 1) % len(stripParameters)
+	} else if key == "-" {
+		t.changeParameter(ctrl, -1)
+	} else if key == "+" || key == "=" {
+		t.changeParameter(ctrl, 1)
+	} else if key == "<Escape>" {
+		t.Log.ClearError()
+	} else if key == "?" {
+		t.ShowHelp = !t.ShowHelp
+	} else if key == "t" || key == "c" || key == "l" {
+		t.handleLearnKey(ctrl, key)
+	} else {
+		t.Log.Println("No binding for key", key)
+	}
+}
+
+// changeParameter changes the selected parameter on the selected channel by
+// n steps.
+func (t *TermBox) changeParameter(ctrl *controller.Controller, n int) {
+	p := stripParameters[t.Parameter]
+	value := p.change(ctrl.Synth.Parameters.Get(t.Channel, p.Name), n)
+	ev, err := synth.NewChannelParameterEvent(p.Name, t.Channel, value)
+	if err != nil {
+		t.Log.Error(err)
+		return
+	}
+	// Record the value right away, so that quick key presses add up before
+	// the synth gets to the event.
+	ctrl.Synth.Parameters.Set(t.Channel, p.Name, value)
+	ctrl.SendSynthEvent(ev)
 }
 
 func (t *TermBox) start(ctrl *controller.Controller) {
@@ -50,9 +197,15 @@ func (t *TermBox) start(ctrl *controller.Controller) {
 		panic(err)
 	}
 	defer termui.Close()
+	restore, err := t.Log.Capture()
+	if err != nil {
+		t.Log.Error(err)
+	} else {
+		defer restore()
+	}
 
 	events := termui.PollEvents()
-	timer := time.Tick(1000 * time.Millisecond)
+	timer := time.Tick(100 * time.Millisecond)
 
 	for {
 		select {
@@ -60,55 +213,142 @@ func (t *TermBox) start(ctrl *controller.Controller) {
 			switch ev.Type {
 			case termui.KeyboardEvent:
 				if ev.ID == "<C-c>" {
-					fmt.Println("Goodbye!")
+					if restore != nil {
+						restore()
+					}
 					termui.Close()
+					fmt.Println("Goodbye!")
 					ctrl.Quit()
 					os.Exit(0)
-
-				} else if ev.ID == "<C-r>" {
-					if err := ctrl.ReloadInstrumentBank(); err != nil {
-						fmt.Println("Error:", err.Error())
-					}
-					if err := ctrl.ReloadPercussionBank(); err != nil {
-						fmt.Println("Error:", err.Error())
-					}
-					ctrl.ReloadSequencer()
-				} else if ev.ID >= "0" && ev.ID <= "9" {
-					i, _ := strconv.Atoi(ev.ID)
-					ctrl.ToggleSoloChannel(i)
-				} else if ev.ID == "<Right>" {
-					ctrl.MoveSequencerForward()
-				} else if ev.ID == "<Left>" {
-					ctrl.MoveSequencerBackward()
-				} else if ev.ID == "<Up>" {
-					ctrl.IncreaseSequencerBPM()
-				} else if ev.ID == "<Down>" {
-					ctrl.DecreaseSequencerBPM()
-				} else {
-					fmt.Println("No binding for key", ev.ID)
 				}
-			default:
-				fmt.Println("Unknown event", ev)
+				t.handleKey(ctrl, ev.ID)
+			case termui.ResizeEvent:
+				termui.Clear()
 			}
+			t.draw(ctrl)
 		case <-timer:
-			//go t.draw()
+			t.draw(ctrl)
 		}
 	}
 }
 
-func (t *TermBox) draw() {
-	termui.TerminalDimensions()
-	termui.Clear()
-	bc := widgets.NewBarChart()
-	bc.Labels = []string{}
-	bc.Title = "Channels"
-	bc.SetRect(0, 0, len(t.ChannelOutputs)*4+3, 10)
-	bc.BarWidth = 3
-	t.mux.Lock()
-	bc.Data = t.ChannelOutputs
-	for i, _ := range t.ChannelOutputs {
-		bc.Labels = append(bc.Labels, strconv.Itoa(i))
+func (t *TermBox) holdClips(now time.Time) {
+	if t.Meters == nil {
+		return
+	}
+	levels := append(append([]ui.Levels{}, t.Meters.Channels...), t.Meters.Master)
+	if len(t.clippedAt) != len(levels) {
+		t.clippedAt = make([]time.Time, len(levels))
 	}
+	for i, l := range levels {
+		if l.Clipped {
+			t.clippedAt[i] = now
+		}
+	}
+}
+
+// transport returns the sequencer status, the current file and the last
+// error.
+func (t *TermBox) transport(ctrl *controller.Controller) string {
+	status := "[no sequencer](fg:yellow)"
+	if seq := ctrl.Sequencer; seq != nil {
+		state := "[■ stopped](fg:red)"
+		if seq.Status.Playing {
+			state = "[▶ playing](fg:green)"
+		} else if seq.Status.Time > 0 {
+			state = "[‖ paused](fg:yellow)"
+		}
+		position := ""
+		if seq.Status.Granularity > 0 {
+			beat := int(seq.Status.Time) / seq.Status.Granularity
+			position = fmt.Sprintf("bar %d beat %d", beat/beatsPerBar+1, beat%beatsPerBar+1)
+		}
+		file := seq.FromFile
+		if file == "" {
+			file = "(no file)"
+		}
+		status = fmt.Sprintf("%s  %.1f bpm  %s  %s", state, seq.Status.BPM, position, filepath.Base(file))
+	}
+	if t.Learning {
+		status += "  [learning MIDI](fg:magenta)"
+	}
+	if err := t.Log.LastError(); err != "" {
+		status += "\n[" + escape(err) + "](fg:red)"
+	} else {
+		status += "\n[?] help"
+	}
+	return status
+}
+
+func (t *TermBox) draw(ctrl *controller.Controller) {
+	t.mux.Lock()
+	meters := t.Meters
+	clippedAt := append([]time.Time{}, t.clippedAt...)
 	t.mux.Unlock()
-	termui.Render(bc)
+
+	width, height := termui.TerminalDimensions()
+
+	transport := widgets.NewParagraph()
+	transport.Title = "Transport"
+	transport.Text = t.transport(ctrl)
+	transport.SetRect(0, 0, width, 4)
+	items := []termui.Drawable{transport}
+
+	mixer := t.mixerTable(ctrl, meters, clippedAt, width)
+	y := 4 + len(mixer.Rows) + 2
+	mixer.SetRect(0, 4, width, y)
+	items = append(items, mixer)
+
+	pattern := patternView(ctrl.Sequencer)
+	rows := strings.Count(pattern.Text, "\n") + 1
+	pattern.SetRect(0, y, width, y+rows+2)
+	items = append(items, pattern)
+	y += rows + 2
+
+	if meters != nil && len(meters.Spectrum) > 0 {
+		line := widgets.NewSparkline()
+		line.MaxVal = meterRange
+		line.LineColor = termui.ColorGreen
+		for _, level := range meters.Spectrum {
+			line.Data = append(line.Data, meterHeight(level))
+		}
+		spectrum := widgets.NewSparklineGroup(line)
+		spectrum.Title = fmt.Sprintf("Spectrum %s-%sHz", formatFrequency(meters.SpectrumFrequencies[0]), formatFrequency(20000))
+		spectrum.SetRect(0, y, min(width, len(line.Data)+2), y+5)
+		items = append(items, spectrum)
+		y += 5
+	}
+
+	log := widgets.NewList()
+	log.Title = "Log"
+	if t.ShowHelp {
+		log.Title = "Help"
+		log.Rows = strings.Split(help, "\n")
+	} else {
+		for _, line := range t.Log.Tail(max(height-y-2, 1)) {
+			log.Rows = append(log.Rows, escape(line))
+		}
+	}
+	log.SetRect(0, y, width, max(height, y+3))
+	items = append(items, log)
+	termui.Render(items...)
+}
+
+func formatFrequency(f float64) string {
+	if f >= 1000 {
+		return strconv.Itoa(int(f/1000)) + "k"
+	}
+	return strconv.Itoa(int(f))
+}
+
+// Square brackets start termui style markup.
+func escape(s string) string {
+	return strings.NewReplacer("[", "(", "]", ")").Replace(s)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
 }
@@ -0,0 +1,100 @@
+package theory
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChordSymbol is a parsed chord symbol like "Cmaj9" or "F#m7b5/E".
+type ChordSymbol struct {
+	Symbol    string
+	Root      int // pitch class, 0 is C
+	Quality   string
+	Intervals []int
+	Bass      int // pitch class of the bass note, or -1
+}
+
+func ParseChordSymbol(symbol string) (*ChordSymbol, error) {
+	s := strings.TrimSpace(symbol)
+	root, rest, err := parsePitchClass(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid chord symbol '%s': %s", symbol, err.Error())
+	}
+	bass := -1
+	if ix := strings.LastIndex(rest, "/"); ix >= 0 {
+		b, after, err := parsePitchClass(rest[ix+1:])
+		if err == nil && after == "" {
+			bass = b
+			rest = rest[:ix]
+		}
+	}
+	intervals, err := ParseChordQuality(rest)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid chord symbol '%s': %s", symbol, err.Error())
+	}
+	return &ChordSymbol{
+		Symbol:    symbol,
+		Root:      root,
+		Quality:   rest,
+		Intervals: intervals,
+		Bass:      bass,
+	}, nil
+}
+
+// parsePitchClass parses a note name with accidentals at the start of s.
+func parsePitchClass(s string) (int, string, error) {
+	name, err := NewNoteNameFromString(s)
+	if err != nil {
+		return 0, "", err
+	}
+	if s[0] < 'A' || s[0] > 'G' {
+		return 0, "", fmt.Errorf("Invalid note name '%s'", s[0:1])
+	}
+	pc := name
+	rest := s[1:]
+	for len(rest) > 0 && (rest[0] == '#' || rest[0] == 'b') {
+		if rest[0] == '#' {
+			pc++
+		} else {
+			pc--
+		}
+		rest = rest[1:]
+	}
+	return ((pc % 12) + 12) % 12, rest, nil
+}
+
+// Notes returns the notes of the chord with the root in the given octave
+// (where C4 is 60). A bass note is placed below the root.
+func (c *ChordSymbol) Notes(octave int) []int {
+	root := 12*(octave+1) + c.Root
+	result := []int{}
+	if c.Bass >= 0 {
+		bass := root - ((root-c.Bass)%12+12)%12
+		if bass == root {
+			bass -= 12
+		}
+		result = append(result, bass)
+	}
+	for _, interval := range c.Intervals {
+		note := root + interval
+		if c.Bass >= 0 && note%12 == c.Bass%12 {
+			continue
+		}
+		result = append(result, note)
+	}
+	return result
+}
+
+// ParseChordSymbols parses the chord symbols and returns their notes in the
+// given octave.
+func ParseChordSymbols(symbols []string, octave int) ([][]int, error) {
+	result := make([][]int, len(symbols))
+	for i, symbol := range symbols {
+		c, err := ParseChordSymbol(symbol)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = c.Notes(octave)
+	}
+	return result, nil
+}
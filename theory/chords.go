@@ -1,5 +1,16 @@
 package theory
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Chords are the original chord names, given as the intervals between
+// successive notes and ending on the octave. They are also available as
+// ChordQualities, which is what ChordOnNoteInt uses, so like all the other
+// chords they're played without the octave.
 var Chords = map[string][]int{
 	"m":   []int{3, 4, 5},
 	"M":   []int{4, 3, 5},
@@ -9,15 +20,225 @@ var Chords = map[string][]int{
 	"7":   []int{4, 3, 3, 2},
 }
 
-func ChordOnNoteInt(note int, chord string) []int {
-	result := []int{note}
-	notes := Chords[chord]
-	current := note
-	for _, n := range notes {
-		current += n
-		result = append(result, current)
+// ChordQualities are given as the semitones above the root. Names that are
+// not in Chords can be any of these qualities followed by extensions and
+// alterations; see ParseChordQuality.
+var ChordQualities = map[string][]int{
+	"":      []int{0, 4, 7},
+	"maj":   []int{0, 4, 7},
+	"min":   []int{0, 3, 7},
+	"-":     []int{0, 3, 7},
+	"dim":   []int{0, 3, 6},
+	"°":     []int{0, 3, 6},
+	"o":     []int{0, 3, 6},
+	"aug":   []int{0, 4, 8},
+	"+":     []int{0, 4, 8},
+	"5":     []int{0, 7},
+	"6":     []int{0, 4, 7, 9},
+	"m6":    []int{0, 3, 7, 9},
+	"maj7":  []int{0, 4, 7, 11},
+	"Δ":     []int{0, 4, 7, 11},
+	"Δ7":    []int{0, 4, 7, 11},
+	"min7":  []int{0, 3, 7, 10},
+	"-7":    []int{0, 3, 7, 10},
+	"mM7":   []int{0, 3, 7, 11},
+	"mMaj7": []int{0, 3, 7, 11},
+	"dim7":  []int{0, 3, 6, 9},
+	"°7":    []int{0, 3, 6, 9},
+	"o7":    []int{0, 3, 6, 9},
+	"ø":     []int{0, 3, 6, 10},
+	"ø7":    []int{0, 3, 6, 10},
+	"aug7":  []int{0, 4, 8, 10},
+	"+7":    []int{0, 4, 8, 10},
+	"9":     []int{0, 4, 7, 10, 14},
+	"maj9":  []int{0, 4, 7, 11, 14},
+	"M9":    []int{0, 4, 7, 11, 14},
+	"m9":    []int{0, 3, 7, 10, 14},
+	"11":    []int{0, 4, 7, 10, 14, 17},
+	"maj11": []int{0, 4, 7, 11, 14, 17},
+	"m11":   []int{0, 3, 7, 10, 14, 17},
+	"13":    []int{0, 4, 7, 10, 14, 21},
+	"maj13": []int{0, 4, 7, 11, 14, 21},
+	"m13":   []int{0, 3, 7, 10, 14, 17, 21},
+	"6/9":   []int{0, 4, 7, 9, 14},
+	"m6/9":  []int{0, 3, 7, 9, 14},
+}
+
+func init() {
+	// The legacy chords, without the octave.
+	for name, intervals := range Chords {
+		if _, ok := ChordQualities[name]; ok {
+			continue
+		}
+		quality := []int{0}
+		current := 0
+		for _, interval := range intervals[:len(intervals)-1] {
+			current += interval
+			quality = append(quality, current)
+		}
+		ChordQualities[name] = quality
 	}
-	return result
+}
+
+// ChordOnNoteInt returns the notes of the chord on the given root. The chord
+// is a chord quality like "M", "maj9" or "m7b5"; see ParseChordQuality.
+func ChordOnNoteInt(note int, chord string) ([]int, error) {
+	quality, err := ParseChordQuality(chord)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]int, len(quality))
+	for i, interval := range quality {
+		result[i] = note + interval
+	}
+	return result, nil
+}
+
+// ParseChordQuality parses the part of a chord symbol that follows the root,
+// e.g. "m7b5", "7sus4", "add9" or "maj7#11", and returns the semitones above
+// the root in ascending order. The quality starts with one of the
+// ChordQualities, which can be followed by extensions (6, 7, 9, 11 or 13,
+// e.g. "min9" or "-7", which also add the seventh and the extensions below
+// them; "maj7" and "maj9" use a major seventh, as in "m(maj7)"), "sus",
+// "sus2" or "sus4", added degrees (e.g. "add9") and altered degrees (e.g.
+// "b5" or "#11"). Parentheses and commas are ignored. Other notation, like
+// "alt" or "no3", isn't supported.
+func ParseChordQuality(quality string) ([]int, error) {
+	base, rest := longestQualityPrefix(quality)
+	intervals := map[int]bool{}
+	for _, i := range ChordQualities[base] {
+		intervals[i] = true
+	}
+	for len(rest) > 0 {
+		if rest[0] == '(' || rest[0] == ')' || rest[0] == ',' || rest[0] == ' ' {
+			rest = rest[1:]
+		} else if strings.HasPrefix(rest, "sus") {
+			rest = rest[3:]
+			suspension := 5
+			if strings.HasPrefix(rest, "2") {
+				suspension = 2
+				rest = rest[1:]
+			} else if strings.HasPrefix(rest, "4") {
+				rest = rest[1:]
+			}
+			delete(intervals, 3)
+			delete(intervals, 4)
+			intervals[suspension] = true
+		} else if degree, n := leadingInt(rest); n > 0 {
+			if err := extend(intervals, degree, false); err != nil {
+				return nil, fmt.Errorf("Invalid chord '%s': %s", quality, err.Error())
+			}
+			rest = rest[n:]
+		} else if degree, n := leadingInt(strings.TrimPrefix(rest, "maj")); n > 0 && strings.HasPrefix(rest, "maj") {
+			if err := extend(intervals, degree, true); err != nil {
+				return nil, fmt.Errorf("Invalid chord '%s': %s", quality, err.Error())
+			}
+			rest = rest[3+n:]
+		} else if strings.HasPrefix(rest, "add") {
+			degree, n := leadingInt(rest[3:])
+			if n == 0 {
+				return nil, fmt.Errorf("Invalid chord '%s': missing degree after 'add'", quality)
+			}
+			interval, ok := extensionIntervals[degree]
+			if !ok {
+				return nil, fmt.Errorf("Invalid chord '%s': can't add %d", quality, degree)
+			}
+			intervals[interval] = true
+			rest = rest[3+n:]
+		} else if rest[0] == 'b' || rest[0] == '#' {
+			alteration := -1
+			if rest[0] == '#' {
+				alteration = 1
+			}
+			degree, n := leadingInt(rest[1:])
+			interval, ok := extensionIntervals[degree]
+			if n == 0 || !ok {
+				return nil, fmt.Errorf("Invalid chord '%s': can't alter '%s'", quality, rest)
+			}
+			delete(intervals, interval)
+			if degree == 5 {
+				delete(intervals, 7)
+			}
+			intervals[interval+alteration] = true
+			rest = rest[1+n:]
+		} else {
+			return nil, fmt.Errorf("Unknown chord '%s'", quality)
+		}
+	}
+	result := []int{}
+	for i := range intervals {
+		result = append(result, i)
+	}
+	sort.Ints(result)
+	return result, nil
+}
+
+// extend adds the extension and the ones below it to the chord; e.g. 9 adds
+// the seventh and the ninth. The seventh is major if majorSeventh is set,
+// or if the chord already has one.
+func extend(intervals map[int]bool, degree int, majorSeventh bool) error {
+	if degree == 6 {
+		intervals[9] = true
+		return nil
+	}
+	extensions := map[int][]int{
+		7:  {},
+		9:  {14},
+		11: {14, 17},
+		13: {14, 21},
+	}
+	added, ok := extensions[degree]
+	if !ok {
+		return fmt.Errorf("can't extend with %d", degree)
+	}
+	seventh := 10
+	if majorSeventh || intervals[11] {
+		seventh = 11
+	}
+	delete(intervals, 10)
+	delete(intervals, 11)
+	intervals[seventh] = true
+	for _, interval := range added {
+		intervals[interval] = true
+	}
+	if degree == 13 && intervals[3] {
+		// Minor thirteenths keep the eleventh, like "m13".
+		intervals[17] = true
+	}
+	return nil
+}
+
+// The semitones above the root for the degrees that can be added or altered.
+var extensionIntervals = map[int]int{
+	2:  2,
+	4:  5,
+	5:  7,
+	6:  9,
+	9:  14,
+	11: 17,
+	13: 21,
+}
+
+func longestQualityPrefix(quality string) (string, string) {
+	best := ""
+	for name := range ChordQualities {
+		if len(name) > len(best) && strings.HasPrefix(quality, name) {
+			best = name
+		}
+	}
+	return best, quality[len(best):]
+}
+
+func leadingInt(s string) (int, int) {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	v, _ := strconv.Atoi(s[:n])
+	return v, n
 }
 
 func InvertChord(chord []int, inversions int) []int {
@@ -33,13 +254,14 @@ func InvertChord(chord []int, inversions int) []int {
 	return result
 }
 
-func ChordOnNote(note *Note, chord string) Notes {
+func ChordOnNote(note *Note, chord string) (Notes, error) {
+	notes, err := ChordOnNoteInt(note.Int(), chord)
+	if err != nil {
+		return nil, err
+	}
 	result := []*Note{note}
-	notes := Chords[chord]
-	current := note.Int()
-	for _, n := range notes {
-		current += n
-		result = append(result, NoteFromInt(current))
+	for _, n := range notes[1:] {
+		result = append(result, NoteFromInt(n))
 	}
-	return result
+	return result, nil
 }
@@ -0,0 +1,127 @@
+package theory
+
+import "testing"
+
+func expectInts(t *testing.T, name string, expected, got []int) {
+	if len(expected) != len(got) {
+		t.Errorf("Expecting %v for %s, got %v", expected, name, got)
+		return
+	}
+	for i, e := range expected {
+		if got[i] != e {
+			t.Errorf("Expecting %v for %s, got %v", expected, name, got)
+			return
+		}
+	}
+}
+
+func Test_ChordOnNoteInt(t *testing.T) {
+	cases := map[string][]int{
+		"M":       []int{60, 64, 67},
+		"m7":      []int{60, 63, 67, 70},
+		"min9":    []int{60, 63, 67, 70, 74},
+		"-7":      []int{60, 63, 67, 70},
+		"m(maj7)": []int{60, 63, 67, 71},
+		"mmaj9":   []int{60, 63, 67, 71, 74},
+		"aug9":    []int{60, 64, 68, 70, 74},
+		"m6":      []int{60, 63, 67, 69},
+		"7b9#11":  []int{60, 64, 67, 70, 73, 78},
+		"maj9":    []int{60, 64, 67, 71, 74},
+		"m7b5":    []int{60, 63, 66, 70},
+		"sus4":    []int{60, 65, 67},
+		"7sus4":   []int{60, 65, 67, 70},
+		"sus2":    []int{60, 62, 67},
+		"add9":    []int{60, 64, 67, 74},
+		"madd9":   []int{60, 63, 67, 74},
+		"7#9":     []int{60, 64, 67, 70, 75},
+		"maj7#11": []int{60, 64, 67, 71, 78},
+		"dim7":    []int{60, 63, 66, 69},
+		"aug":     []int{60, 64, 68},
+		"7(b9)":   []int{60, 64, 67, 70, 73},
+	}
+	for chord, e := range cases {
+		got, err := ChordOnNoteInt(60, chord)
+		if err != nil {
+			t.Errorf("Unexpected error for '%s': %s", chord, err.Error())
+			continue
+		}
+		expectInts(t, chord, e, got)
+	}
+	for _, chord := range []string{"x", "maj8", "add", "b3", "7b", "m8", "mmaj"} {
+		if _, err := ChordOnNoteInt(60, chord); err == nil {
+			t.Errorf("Expecting an error for '%s'", chord)
+		}
+	}
+}
+
+func Test_ParseChordSymbol(t *testing.T) {
+	cases := map[string][]int{
+		"C":        []int{60, 64, 67},
+		"Cmaj9":    []int{60, 64, 67, 71, 74},
+		"Bbm7":     []int{70, 73, 77, 80},
+		"F#m7b5/E": []int{64, 66, 69, 72},
+		"C/E":      []int{52, 60, 67},
+		"Gsus4":    []int{67, 72, 74},
+		"D6/9":     []int{62, 66, 69, 71, 76},
+		"Cmin9":    []int{60, 63, 67, 70, 74},
+		"Cm(maj7)": []int{60, 63, 67, 71},
+		"Ebmaj7/G": []int{55, 63, 70, 74},
+	}
+	for symbol, e := range cases {
+		c, err := ParseChordSymbol(symbol)
+		if err != nil {
+			t.Errorf("Unexpected error for '%s': %s", symbol, err.Error())
+			continue
+		}
+		expectInts(t, symbol, e, c.Notes(4))
+	}
+	for _, symbol := range []string{"", "H7", "Cfoo", "c"} {
+		if _, err := ParseChordSymbol(symbol); err == nil {
+			t.Errorf("Expecting an error for '%s'", symbol)
+		}
+	}
+}
+
+func Test_ProgressionOnKey(t *testing.T) {
+	unit, err := ProgressionOnKey([]string{"ii7", "V7", "Imaj7", "viiø7", "bVII", "i", "viio7"}, C4, "ionian")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]int{
+		[]int{62, 65, 69, 72},
+		[]int{67, 71, 74, 77},
+		[]int{60, 64, 67, 71},
+		[]int{71, 74, 77, 81},
+		[]int{70, 74, 77},
+		[]int{60, 63, 67},
+		[]int{71, 74, 77, 80},
+	}
+	for i, e := range expected {
+		expectInts(t, "progression", e, unit[i])
+	}
+	secondary, err := ProgressionOnKey([]string{"V/V", "V7/ii", "viio7/V", "V/V/V", "I6/9"}, C4, "ionian")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectInts(t, "V/V", []int{74, 78, 81}, secondary[0])
+	expectInts(t, "V7/ii", []int{69, 73, 76, 79}, secondary[1])
+	expectInts(t, "viio7/V", []int{78, 81, 84, 87}, secondary[2])
+	expectInts(t, "V/V/V", []int{81, 85, 88}, secondary[3])
+	expectInts(t, "I6/9", []int{60, 64, 67, 69, 74}, secondary[4])
+	for _, numeral := range []string{"VIII", "Iv", "x", "Vfoo", "x/V", "V/"} {
+		if _, err := ParseRomanNumeral(numeral); err == nil {
+			t.Errorf("Expecting an error for '%s'", numeral)
+		}
+	}
+}
+
+func Test_VoiceLead(t *testing.T) {
+	// C major to F major: the closest voicing is the second inversion C F A.
+	expectInts(t, "C -> F", []int{60, 65, 69}, VoiceLead([]int{60, 64, 67}, []int{65, 69, 72}))
+	// C major to G major: B D G
+	expectInts(t, "C -> G", []int{59, 62, 67}, VoiceLead([]int{60, 64, 67}, []int{67, 71, 74}))
+	unit := VoiceLeadProgression([][]int{{60, 64, 67}, {65, 69, 72}, {67, 71, 74}})
+	expectInts(t, "progression", []int{60, 64, 67}, unit[0])
+	expectInts(t, "progression", []int{60, 65, 69}, unit[1])
+	expectInts(t, "progression", []int{59, 62, 67}, unit[2])
+}
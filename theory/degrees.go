@@ -0,0 +1,98 @@
+package theory
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Degree is a one-based scale degree with optional accidentals, e.g. "b7"
+// for a flat seventh. Degrees above the size of the scale continue into the
+// next octave, so that 8 is the tonic an octave up in a seven note scale.
+type Degree struct {
+	Degree      int
+	Accidentals int
+}
+
+func ParseDegree(s string) (Degree, error) {
+	d := Degree{}
+	rest := strings.TrimSpace(s)
+	for len(rest) > 0 && (rest[0] == 'b' || rest[0] == '#') {
+		if rest[0] == 'b' {
+			d.Accidentals--
+		} else {
+			d.Accidentals++
+		}
+		rest = rest[1:]
+	}
+	degree, err := strconv.Atoi(rest)
+	if err != nil || degree < 1 {
+		return d, fmt.Errorf("Invalid scale degree '%s'", s)
+	}
+	d.Degree = degree
+	return d, nil
+}
+
+// ParseDegrees parses a list of degrees. The degrees can be given as
+// integers or as strings.
+func ParseDegrees(degrees []interface{}) ([]Degree, error) {
+	result := make([]Degree, len(degrees))
+	for i, d := range degrees {
+		degree, err := DegreeFromValue(d)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = degree
+	}
+	return result, nil
+}
+
+// DegreeFromValue parses a degree that was given as either an integer or a
+// string, as happens when the degree comes from YAML.
+func DegreeFromValue(d interface{}) (Degree, error) {
+	switch v := d.(type) {
+	case int:
+		if v < 1 {
+			return Degree{}, fmt.Errorf("Invalid scale degree '%d'", v)
+		}
+		return Degree{Degree: v}, nil
+	case string:
+		return ParseDegree(v)
+	}
+	return Degree{}, fmt.Errorf("Invalid scale degree '%v'", d)
+}
+
+// OnScale returns the note for this degree of the scale on the given tonic.
+func (d Degree) OnScale(tonic int, scale string) int {
+	return ScaleDegreeToNoteInt(tonic, scale, d.Degree-1) + d.Accidentals
+}
+
+func (d Degree) String() string {
+	prefix := ""
+	for i := 0; i < d.Accidentals; i++ {
+		prefix += "#"
+	}
+	for i := 0; i > d.Accidentals; i-- {
+		prefix += "b"
+	}
+	return fmt.Sprintf("%s%d", prefix, d.Degree)
+}
+
+// ParseKey parses a key given as a note name (e.g. "F#" or "Bb3") or a MIDI
+// note number. Note names without an octave are placed in octave 4.
+func ParseKey(key interface{}) (int, error) {
+	switch v := key.(type) {
+	case int:
+		if v < 0 || v > 127 {
+			return 0, fmt.Errorf("Invalid key '%d'", v)
+		}
+		return v, nil
+	case string:
+		note, err := NoteFromString(v)
+		if err != nil {
+			return 0, err
+		}
+		return note.Int(), nil
+	}
+	return 0, fmt.Errorf("Invalid key '%v'", key)
+}
@@ -0,0 +1,53 @@
+package theory
+
+import "testing"
+
+func Test_ParseDegree(t *testing.T) {
+	cases := map[string]Degree{
+		"1":   Degree{1, 0},
+		"b7":  Degree{7, -1},
+		"#4":  Degree{4, 1},
+		"bb3": Degree{3, -2},
+		"9":   Degree{9, 0},
+	}
+	for s, e := range cases {
+		d, err := ParseDegree(s)
+		if err != nil {
+			t.Errorf("Failed to parse '%s': %s", s, err.Error())
+		} else if d != e {
+			t.Errorf("Expecting %v for '%s', got %v", e, s, d)
+		} else if d.String() != s {
+			t.Errorf("Expecting '%s', got '%s'", s, d.String())
+		}
+	}
+	for _, s := range []string{"", "b", "0", "x3", "3b"} {
+		if _, err := ParseDegree(s); err == nil {
+			t.Errorf("Expecting error for '%s'", s)
+		}
+	}
+}
+
+func Test_Degree_OnScale(t *testing.T) {
+	degrees, err := ParseDegrees([]interface{}{1, "3", 5, "b7", 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []int{C4, E4, G4, C4 + 10, C5}
+	for i, d := range degrees {
+		if got := d.OnScale(C4, "ionian"); got != expected[i] {
+			t.Errorf("Expecting %d for degree %s, got %d", expected[i], d, got)
+		}
+	}
+}
+
+func Test_ParseKey(t *testing.T) {
+	cases := map[interface{}]int{"C": C4, "F#": 66, "Bb3": 58, 50: 50}
+	for k, e := range cases {
+		got, err := ParseKey(k)
+		if err != nil {
+			t.Errorf("Failed to parse '%v': %s", k, err.Error())
+		} else if got != e {
+			t.Errorf("Expecting %d for '%v', got %d", e, k, got)
+		}
+	}
+}
@@ -90,6 +90,6 @@ func (n *Note) Diminish() {
 func (n *Note) Pitch() float64 {
 	return NoteToPitch[n.Int()]
 }
-func (n *Note) Chord(chord string) Notes {
+func (n *Note) Chord(chord string) (Notes, error) {
 	return ChordOnNote(n, chord)
 }
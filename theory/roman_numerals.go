@@ -0,0 +1,154 @@
+package theory
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RomanNumeral is a chord relative to a key, e.g. "ii7", "V7", "bVII" or
+// "viiø7". Upper case numerals are major and lower case numerals minor
+// chords, unless they're followed by "°"/"o" (diminished), "ø"
+// (half-diminished) or "+" (augmented). The rest of the numeral is parsed as
+// a chord quality relative to that; e.g. "7" makes "V7" a dominant and "ii7"
+// a minor seventh chord.
+//
+// Secondary chords are written with a slash, e.g. "V/V" or "viio7/ii": the
+// chord on the left is taken from the major or minor key (depending on the
+// chord) on the degree on the right. Figured bass inversions like "V65" and
+// borrowed chords written as "iv/-" aren't supported.
+type RomanNumeral struct {
+	Numeral   string
+	Degree    Degree
+	Intervals []int
+	// The chord whose key this is a secondary chord of, or nil.
+	Of *RomanNumeral
+}
+
+var romanNumerals = []struct {
+	numeral string
+	degree  int
+}{
+	{"vii", 7}, {"vi", 6}, {"iv", 4}, {"v", 5}, {"iii", 3}, {"ii", 2}, {"i", 1},
+}
+
+func ParseRomanNumeral(numeral string) (*RomanNumeral, error) {
+	s := strings.TrimSpace(numeral)
+	if ix := strings.Index(s, "/"); ix >= 0 {
+		if of, err := ParseRomanNumeral(s[ix+1:]); err == nil {
+			r, err := ParseRomanNumeral(s[:ix])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid roman numeral '%s': %s", numeral, err.Error())
+			}
+			r.Numeral = numeral
+			r.Of = of
+			return r, nil
+		}
+	}
+	accidentals := 0
+	for len(s) > 0 && (s[0] == 'b' || s[0] == '#') {
+		if s[0] == 'b' {
+			accidentals--
+		} else {
+			accidentals++
+		}
+		s = s[1:]
+	}
+	degree := 0
+	for _, r := range romanNumerals {
+		if len(s) >= len(r.numeral) && strings.ToLower(s[:len(r.numeral)]) == r.numeral {
+			upper := strings.ToUpper(r.numeral)
+			if s[:len(r.numeral)] != r.numeral && s[:len(r.numeral)] != upper {
+				return nil, fmt.Errorf("Invalid roman numeral '%s': mixed case", numeral)
+			}
+			degree = r.degree
+			major := s[:len(r.numeral)] == upper
+			s = s[len(r.numeral):]
+			quality := romanNumeralQuality(major, s)
+			intervals, err := ParseChordQuality(quality)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid roman numeral '%s': %s", numeral, err.Error())
+			}
+			return &RomanNumeral{
+				Numeral:   numeral,
+				Degree:    Degree{Degree: degree, Accidentals: accidentals},
+				Intervals: intervals,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("Invalid roman numeral '%s'", numeral)
+}
+
+// romanNumeralQuality turns the suffix of a roman numeral into a chord
+// quality, taking the case of the numeral into account.
+func romanNumeralQuality(major bool, suffix string) string {
+	for _, prefix := range []string{"°", "o"} {
+		if strings.HasPrefix(suffix, prefix) {
+			return "dim" + suffix[len(prefix):]
+		}
+	}
+	if strings.HasPrefix(suffix, "ø") || strings.HasPrefix(suffix, "+") {
+		return suffix
+	}
+	if major {
+		return suffix
+	}
+	if strings.HasPrefix(suffix, "maj") {
+		return "mMaj" + suffix[3:]
+	}
+	return "m" + suffix
+}
+
+// OnKey returns the notes of the chord in the given key (a tonic note) and
+// scale.
+func (r *RomanNumeral) OnKey(key int, scale string) []int {
+	root := r.root(key, scale)
+	result := make([]int, len(r.Intervals))
+	for i, interval := range r.Intervals {
+		result[i] = root + interval
+	}
+	return result
+}
+
+func (r *RomanNumeral) root(key int, scale string) int {
+	if r.Of != nil {
+		key = r.Of.root(key, scale)
+		scale = r.Of.key()
+	}
+	return r.Degree.OnScale(key, scale)
+}
+
+// key returns the scale of the key that this chord is the tonic of.
+func (r *RomanNumeral) key() string {
+	for _, interval := range r.Intervals {
+		if interval == 4 {
+			return "ionian"
+		}
+	}
+	return "aeolian"
+}
+
+func ParseRomanNumerals(numerals []string) ([]*RomanNumeral, error) {
+	result := make([]*RomanNumeral, len(numerals))
+	for i, n := range numerals {
+		r, err := ParseRomanNumeral(n)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = r
+	}
+	return result, nil
+}
+
+// ProgressionOnKey returns the chords for the roman numerals in the given
+// key and scale.
+func ProgressionOnKey(numerals []string, key int, scale string) ([][]int, error) {
+	parsed, err := ParseRomanNumerals(numerals)
+	if err != nil {
+		return nil, err
+	}
+	result := make([][]int, len(parsed))
+	for i, r := range parsed {
+		result[i] = r.OnKey(key, scale)
+	}
+	return result, nil
+}
@@ -72,16 +72,78 @@ var HarmonicMajor = NewScale(
 	MustNotesFromString("B Ab G F E D C"),
 )
 
+var NaturalMinor = Aeolian
+
+var HarmonicMinor = NewScale(
+	MustNotesFromString("C D Eb F G Ab B"),
+	nil,
+)
+
+// The modes of the harmonic minor scale, in the key of C.
+var LocrianSharp6 = NewScale(MustNotesFromString("C Db Eb F Gb A Bb"), nil)
+var IonianSharp5 = NewScale(MustNotesFromString("C D E F G# A B"), nil)
+var DorianSharp4 = NewScale(MustNotesFromString("C D Eb F# G A Bb"), nil)
+var PhrygianDominant = NewScale(MustNotesFromString("C Db E F G Ab Bb"), nil)
+var LydianSharp2 = NewScale(MustNotesFromString("C D# E F# G A B"), nil)
+var Ultralocrian = NewScale(MustNotesFromString("C Db Eb Fb Gb Ab Bbb"), nil)
+
+// The ascending melodic minor scale. It descends like the natural minor.
+var MelodicMinor = NewScale(
+	MustNotesFromString("C D Eb F G A B"),
+	MustNotesFromString("Bb Ab G F Eb D C"),
+)
+
+// The modes of the (ascending) melodic minor scale, in the key of C.
+var DorianFlat2 = NewScale(MustNotesFromString("C Db Eb F G A Bb"), nil)
+var LydianAugmented = NewScale(MustNotesFromString("C D E F# G# A B"), nil)
+var LydianDominant = NewScale(MustNotesFromString("C D E F# G A Bb"), nil)
+var MixolydianFlat6 = NewScale(MustNotesFromString("C D E F G Ab Bb"), nil)
+var LocrianSharp2 = NewScale(MustNotesFromString("C D Eb F Gb Ab Bb"), nil)
+var Altered = NewScale(MustNotesFromString("C Db Eb Fb Gb Ab Bb"), nil)
+
+var MajorPentatonic = NewScale(MustNotesFromString("C D E G A"), nil)
+var MinorPentatonic = NewScale(MustNotesFromString("C Eb F G Bb"), nil)
+var Blues = NewScale(MustNotesFromString("C Eb F Gb G Bb"), nil)
+var WholeTone = NewScale(MustNotesFromString("C D E F# G# A#"), nil)
+
+// The diminished (octatonic) scales, starting with a whole or a half step.
+var DiminishedWholeHalf = NewScale(MustNotesFromString("C D Eb F Gb Ab A B"), nil)
+var DiminishedHalfWhole = NewScale(MustNotesFromString("C Db D# E F# G A Bb"), nil)
+
 var Scales = map[string]*Scale{
-	"diatonic":       Diatonic,
-	"ionian":         Ionian,
-	"dorian":         Dorian,
-	"phrygian":       Phrygian,
-	"lydian":         Lydian,
-	"mixolydian":     Mixolydian,
-	"aeolian":        Aeolian,
-	"locrian":        Locrian,
-	"harmonic major": HarmonicMajor,
+	"diatonic":              Diatonic,
+	"ionian":                Ionian,
+	"dorian":                Dorian,
+	"phrygian":              Phrygian,
+	"lydian":                Lydian,
+	"mixolydian":            Mixolydian,
+	"aeolian":               Aeolian,
+	"locrian":               Locrian,
+	"harmonic major":        HarmonicMajor,
+	"major":                 MajorScale,
+	"natural minor":         NaturalMinor,
+	"minor":                 NaturalMinor,
+	"harmonic minor":        HarmonicMinor,
+	"locrian #6":            LocrianSharp6,
+	"ionian #5":             IonianSharp5,
+	"dorian #4":             DorianSharp4,
+	"phrygian dominant":     PhrygianDominant,
+	"lydian #2":             LydianSharp2,
+	"ultralocrian":          Ultralocrian,
+	"melodic minor":         MelodicMinor,
+	"dorian b2":             DorianFlat2,
+	"lydian augmented":      LydianAugmented,
+	"lydian dominant":       LydianDominant,
+	"mixolydian b6":         MixolydianFlat6,
+	"locrian #2":            LocrianSharp2,
+	"altered":               Altered,
+	"major pentatonic":      MajorPentatonic,
+	"minor pentatonic":      MinorPentatonic,
+	"blues":                 Blues,
+	"whole tone":            WholeTone,
+	"diminished":            DiminishedWholeHalf,
+	"diminished whole half": DiminishedWholeHalf,
+	"diminished half whole": DiminishedHalfWhole,
 }
 
 func ScaleOnNoteInt(note int, scale string) []int {
@@ -93,3 +155,34 @@ func ScaleOnNoteInt(note int, scale string) []int {
 	}
 	return result
 }
+
+// ScaleSteps returns the semitones between the tonic and each note of the
+// scale, so that the first step is always 0. Unlike ScaleOnNoteInt this
+// treats the modes as scales of their own; e.g. the steps for "dorian" start
+// 0, 2, 3.
+func ScaleSteps(scale string) []int {
+	notes := ScaleOnNoteInt(0, scale)
+	result := make([]int, len(notes))
+	for i, n := range notes {
+		result[i] = n - notes[0]
+	}
+	return result
+}
+
+// ScaleDegreeToNoteInt returns the note for the given degree of the scale
+// with note as its tonic. Degree 0 is the tonic; degrees outside of the scale
+// wrap around into the next or previous octaves.
+func ScaleDegreeToNoteInt(note int, scale string, degree int) int {
+	steps := ScaleSteps(scale)
+	if len(steps) == 0 {
+		return note
+	}
+	l := len(steps)
+	octave := degree / l
+	ix := degree % l
+	if ix < 0 {
+		ix += l
+		octave--
+	}
+	return note + steps[ix] + 12*octave
+}
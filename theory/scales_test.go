@@ -34,3 +34,21 @@ func Test_ScaleOnNoteInt(t *testing.T) {
 		}
 	}
 }
+
+func Test_ScaleDegreeToNoteInt(t *testing.T) {
+	cases := map[int]int{0: C4, 2: C4 + 4, 7: C4 + 12, 9: C4 + 16, -1: C4 - 1, -7: C4 - 12, -8: C4 - 13}
+	for degree, e := range cases {
+		if got := ScaleDegreeToNoteInt(C4, "diatonic", degree); got != e {
+			t.Errorf("Expecting %d for degree %d, got %d", e, degree, got)
+		}
+	}
+}
+
+func Test_ScaleDegreeToNoteInt_modes_start_on_tonic(t *testing.T) {
+	expected := []int{D4, D4 + 2, D4 + 3, D4 + 5, D4 + 7, D4 + 9, D4 + 10, D4 + 12}
+	for degree, e := range expected {
+		if got := ScaleDegreeToNoteInt(D4, "dorian", degree); got != e {
+			t.Errorf("Expecting %d for degree %d, got %d", e, degree, got)
+		}
+	}
+}
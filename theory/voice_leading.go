@@ -0,0 +1,104 @@
+package theory
+
+import "sort"
+
+// VoiceLead returns the inversion of chord, moved by whole octaves, that is
+// closest to the previous chord, so that the voices move as little as
+// possible. The chord is assumed to be in close position.
+func VoiceLead(previous, chord []int) []int {
+	if len(previous) == 0 || len(chord) == 0 {
+		return chord
+	}
+	sorted := make([]int, len(chord))
+	copy(sorted, chord)
+	sort.Ints(sorted)
+
+	var best []int
+	bestCost := -1
+	for inv := 0; inv < len(sorted); inv++ {
+		voicing := inversion(sorted, inv)
+		for octave := -3; octave <= 3; octave++ {
+			candidate := make([]int, len(voicing))
+			valid := true
+			for i, n := range voicing {
+				candidate[i] = n + 12*octave
+				if candidate[i] < 0 || candidate[i] > 127 {
+					valid = false
+				}
+			}
+			if !valid {
+				continue
+			}
+			cost := voiceLeadingCost(previous, candidate)
+			if bestCost < 0 || cost < bestCost {
+				best, bestCost = candidate, cost
+			}
+		}
+	}
+	if best == nil {
+		return chord
+	}
+	return best
+}
+
+// VoiceLeadProgression voices every chord as close as possible to the chord
+// before it. The first chord is left as is.
+func VoiceLeadProgression(chords [][]int) [][]int {
+	result := make([][]int, len(chords))
+	for i, chord := range chords {
+		if i == 0 {
+			result[i] = chord
+		} else {
+			result[i] = VoiceLead(result[i-1], chord)
+		}
+	}
+	return result
+}
+
+// inversion moves the lowest n notes of the (sorted) chord up an octave.
+func inversion(chord []int, n int) []int {
+	result := make([]int, 0, len(chord))
+	result = append(result, chord[n:]...)
+	for _, note := range chord[:n] {
+		result = append(result, note+12)
+	}
+	return result
+}
+
+// voiceLeadingCost is the total number of semitones the voices move. When
+// the chords have a different number of notes, every note is compared with
+// the nearest note in the other chord.
+func voiceLeadingCost(a, b []int) int {
+	if len(a) == len(b) {
+		sa := make([]int, len(a))
+		copy(sa, a)
+		sort.Ints(sa)
+		cost := 0
+		for i := range sa {
+			cost += abs(sa[i] - b[i])
+		}
+		return cost
+	}
+	return nearestCost(a, b) + nearestCost(b, a)
+}
+
+func nearestCost(from, to []int) int {
+	cost := 0
+	for _, f := range from {
+		nearest := -1
+		for _, t := range to {
+			if d := abs(f - t); nearest < 0 || d < nearest {
+				nearest = d
+			}
+		}
+		cost += nearest
+	}
+	return cost
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
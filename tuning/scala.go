@@ -0,0 +1,212 @@
+package tuning
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Scale is a Scala (.scl) scale. Cents holds the pitch of every degree
+// relative to the tonic, starting with degree 1; the last degree is the
+// period (usually the octave).
+type Scale struct {
+	Description string
+	Cents       []float64
+}
+
+// KeyboardMapping is a Scala (.kbm) keyboard mapping. Mapping holds the
+// scale degree for every key in the pattern; -1 means the key is unmapped.
+// An empty mapping maps every key to the next degree.
+type KeyboardMapping struct {
+	First              int
+	Last               int
+	Middle             int
+	Reference          int
+	ReferenceFrequency float64
+	OctaveDegree       int
+	Mapping            []int
+}
+
+// DefaultKeyboardMapping is used when no .kbm file is given: a linear
+// mapping with the tonic on middle C (MIDI note 60) at 261.6256Hz.
+func DefaultKeyboardMapping() *KeyboardMapping {
+	return &KeyboardMapping{
+		First:              0,
+		Last:               127,
+		Middle:             60,
+		Reference:          60,
+		ReferenceFrequency: 261.625565,
+	}
+}
+
+// scalaLines returns the lines of a Scala file, skipping comments.
+func scalaLines(r io.Reader) ([]string, error) {
+	result := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result, scanner.Err()
+}
+
+// firstField returns the first whitespace separated field of the line;
+// anything after it is a comment.
+func firstField(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+func ParseScale(r io.Reader) (*Scale, error) {
+	lines, err := scalaLines(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("Expecting a description and the number of notes")
+	}
+	count, err := strconv.Atoi(firstField(lines[1]))
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("Invalid number of notes '%s'", lines[1])
+	}
+	scale := &Scale{
+		Description: strings.TrimSpace(lines[0]),
+		Cents:       []float64{},
+	}
+	for _, line := range lines[2:] {
+		if len(scale.Cents) == count {
+			break
+		}
+		value := firstField(line)
+		if value == "" {
+			continue
+		}
+		cents, err := parsePitch(value)
+		if err != nil {
+			return nil, err
+		}
+		scale.Cents = append(scale.Cents, cents)
+	}
+	if len(scale.Cents) != count {
+		return nil, fmt.Errorf("Expecting %d notes, got %d", count, len(scale.Cents))
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("Expecting at least one note")
+	}
+	return scale, nil
+}
+
+// parsePitch parses a pitch in cents (if it contains a period) or as a
+// ratio, and returns it in cents.
+func parsePitch(value string) (float64, error) {
+	if strings.Contains(value, ".") {
+		cents, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid pitch '%s'", value)
+		}
+		return cents, nil
+	}
+	parts := strings.SplitN(value, "/", 2)
+	num, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid ratio '%s'", value)
+	}
+	den := 1.0
+	if len(parts) == 2 {
+		den, err = strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid ratio '%s'", value)
+		}
+	}
+	if num <= 0 || den <= 0 {
+		return 0, fmt.Errorf("Invalid ratio '%s'", value)
+	}
+	return 1200 * math.Log2(num/den), nil
+}
+
+func ParseKeyboardMapping(r io.Reader) (*KeyboardMapping, error) {
+	lines, err := scalaLines(r)
+	if err != nil {
+		return nil, err
+	}
+	values := []string{}
+	for _, line := range lines {
+		if v := firstField(line); v != "" {
+			values = append(values, v)
+		}
+	}
+	if len(values) < 7 {
+		return nil, fmt.Errorf("Expecting at least 7 values in keyboard mapping, got %d", len(values))
+	}
+	ints := make([]int, 7)
+	for i, ix := range []int{0, 1, 2, 3, 4, 6} {
+		v, err := strconv.Atoi(values[ix])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value '%s' in keyboard mapping", values[ix])
+		}
+		ints[i] = v
+	}
+	freq, err := strconv.ParseFloat(values[5], 64)
+	if err != nil || freq <= 0 {
+		return nil, fmt.Errorf("Invalid reference frequency '%s'", values[5])
+	}
+	size := ints[0]
+	kbm := &KeyboardMapping{
+		First:              ints[1],
+		Last:               ints[2],
+		Middle:             ints[3],
+		Reference:          ints[4],
+		ReferenceFrequency: freq,
+		OctaveDegree:       ints[5],
+		Mapping:            []int{},
+	}
+	for _, v := range values[7:] {
+		if len(kbm.Mapping) == size {
+			break
+		}
+		if v == "x" || v == "X" {
+			kbm.Mapping = append(kbm.Mapping, -1)
+			continue
+		}
+		degree, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid mapping '%s'", v)
+		}
+		kbm.Mapping = append(kbm.Mapping, degree)
+	}
+	if len(kbm.Mapping) < size {
+		// Missing entries are unmapped.
+		for len(kbm.Mapping) < size {
+			kbm.Mapping = append(kbm.Mapping, -1)
+		}
+	}
+	return kbm, nil
+}
+
+func LoadScale(file string) (*Scale, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseScale(f)
+}
+
+func LoadKeyboardMapping(file string) (*KeyboardMapping, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseKeyboardMapping(f)
+}
@@ -0,0 +1,135 @@
+package tuning
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const equalTemperament = `! 12tet.scl
+!
+12 tone equal temperament
+ 12
+!
+ 100.0
+ 200.
+ 300.0
+ 400.0
+ 500.0
+ 600.0
+ 700.0
+ 800.0
+ 900.0
+ 1000.0
+ 1100.0
+ 2/1
+`
+
+const justIntonation = `! just.scl
+Just intonation major
+7
+9/8
+5/4
+4/3
+3/2 perfect fifth
+5/3
+15/8
+2
+`
+
+// A seven note mapping that leaves the black keys unmapped.
+const whiteKeys = `! white.kbm
+12
+0
+127
+60
+69
+440.0
+7
+! mapping
+0
+x
+1
+x
+2
+3
+x
+4
+x
+5
+x
+6
+`
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+func Test_ParseScale(t *testing.T) {
+	scale, err := ParseScale(strings.NewReader(equalTemperament))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scale.Description != "12 tone equal temperament" {
+		t.Errorf("Unexpected description '%s'", scale.Description)
+	}
+	if len(scale.Cents) != 12 {
+		t.Fatalf("Expecting 12 pitches, got %d", len(scale.Cents))
+	}
+	if !almostEqual(scale.Cents[11], 1200.0) {
+		t.Errorf("Expecting ratio 2/1 to be 1200 cents, got %f", scale.Cents[11])
+	}
+	if _, err := ParseScale(strings.NewReader("desc\n3\n100.0\n")); err == nil {
+		t.Errorf("Expecting error for scale with missing pitches")
+	}
+}
+
+func Test_NewTuning_equal_temperament(t *testing.T) {
+	scale, err := ParseScale(strings.NewReader(equalTemperament))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuning, err := NewTuning(scale, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var et *Tuning
+	for note := 0; note < 128; note++ {
+		if !almostEqual(tuning.Pitch(note), et.Pitch(note)) {
+			t.Errorf("Expecting %f for note %d, got %f", et.Pitch(note), note, tuning.Pitch(note))
+		}
+	}
+	if !almostEqual(tuning.Pitch(69), 440.0) {
+		t.Errorf("Expecting 440.0 for note 69, got %f", tuning.Pitch(69))
+	}
+}
+
+func Test_NewTuning_keyboard_mapping(t *testing.T) {
+	scale, err := ParseScale(strings.NewReader(justIntonation))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kbm, err := ParseKeyboardMapping(strings.NewReader(whiteKeys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuning, err := NewTuning(scale, kbm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[int]float64{
+		69: 440.0,
+		60: 264.0,
+		64: 330.0,
+		67: 396.0,
+		72: 528.0,
+		57: 220.0,
+		61: 0.0,
+		70: 0.0,
+	}
+	for note, expected := range cases {
+		if !almostEqual(tuning.Pitch(note), expected) {
+			t.Errorf("Expecting %f for note %d, got %f", expected, note, tuning.Pitch(note))
+		}
+	}
+}
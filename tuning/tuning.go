@@ -0,0 +1,118 @@
+// Package tuning maps MIDI notes to frequencies. By default notes are tuned
+// in twelve tone equal temperament, but tunings can also be loaded from
+// Scala scale (.scl) and keyboard mapping (.kbm) files.
+package tuning
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bspaans/bleep/midi/notes"
+)
+
+type Tuning struct {
+	Name    string
+	Pitches []float64
+}
+
+// Pitch returns the frequency for the note, or 0.0 if the note is unmapped
+// and shouldn't sound. A nil Tuning is twelve tone equal temperament.
+func (t *Tuning) Pitch(note int) float64 {
+	if note < 0 || note > 127 {
+		return 0.0
+	}
+	if t == nil {
+		return notes.NoteToPitch[note]
+	}
+	return t.Pitches[note]
+}
+
+// NewTuning creates the tuning for a scale and keyboard mapping. If the
+// mapping is nil, DefaultKeyboardMapping is used.
+func NewTuning(scale *Scale, kbm *KeyboardMapping) (*Tuning, error) {
+	if kbm == nil {
+		kbm = DefaultKeyboardMapping()
+	}
+	refDegree, ok := kbm.degree(kbm.Reference)
+	if !ok {
+		return nil, fmt.Errorf("The reference note %d is not mapped", kbm.Reference)
+	}
+	refCents := scale.cents(refDegree)
+	pitches := make([]float64, 128)
+	for note := 0; note < 128; note++ {
+		if note < kbm.First || note > kbm.Last {
+			continue
+		}
+		degree, ok := kbm.degree(note)
+		if !ok {
+			continue
+		}
+		cents := scale.cents(degree) - refCents
+		pitches[note] = kbm.ReferenceFrequency * math.Pow(2, cents/1200)
+	}
+	return &Tuning{
+		Name:    scale.Description,
+		Pitches: pitches,
+	}, nil
+}
+
+// LoadTuning loads a tuning from a .scl file and an optional .kbm file.
+func LoadTuning(sclFile, kbmFile string) (*Tuning, error) {
+	scale, err := LoadScale(sclFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s > %s", sclFile, err.Error())
+	}
+	var kbm *KeyboardMapping
+	if kbmFile != "" {
+		kbm, err = LoadKeyboardMapping(kbmFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s > %s", kbmFile, err.Error())
+		}
+	}
+	return NewTuning(scale, kbm)
+}
+
+// degree returns the scale degree (relative to the middle note) for the
+// note, counting in steps of the mapping's formal octave.
+func (k *KeyboardMapping) degree(note int) (int, bool) {
+	offset := note - k.Middle
+	if len(k.Mapping) == 0 {
+		return offset, true
+	}
+	size := len(k.Mapping)
+	pattern := floorDiv(offset, size)
+	ix := offset - pattern*size
+	if k.Mapping[ix] < 0 {
+		return 0, false
+	}
+	return pattern*k.octaveDegree() + k.Mapping[ix], true
+}
+
+func (k *KeyboardMapping) octaveDegree() int {
+	if k.OctaveDegree == 0 {
+		return len(k.Mapping)
+	}
+	return k.OctaveDegree
+}
+
+// cents returns the pitch of a degree relative to the tonic. Degrees beyond
+// the scale repeat the scale one period up or down.
+func (s *Scale) cents(degree int) float64 {
+	n := len(s.Cents)
+	period := s.Cents[n-1]
+	periods := floorDiv(degree, n)
+	ix := degree - periods*n
+	result := float64(periods) * period
+	if ix > 0 {
+		result += s.Cents[ix-1]
+	}
+	return result
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
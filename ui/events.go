@@ -5,12 +5,14 @@ type UIEventType int
 const (
 	ChannelsOutputEvent UIEventType = iota
 	ForceReloadEvent    UIEventType = iota
+	MIDILearnEvent      UIEventType = iota
 )
 
 type UIEvent struct {
 	Type   UIEventType
 	Value  string
 	Values []float64
+	Meters *Meters
 }
 
 func NewUIEvent(ty UIEventType) *UIEvent {
@@ -0,0 +1,40 @@
+package ui
+
+import "math"
+
+// The lowest level in decibels that's reported; anything quieter is silence.
+const MinDecibels = -100.0
+
+// Levels are measured since the previous meters update. The peak and RMS are
+// linear amplitudes, where 1.0 is full scale.
+type Levels struct {
+	Peak    float64 `json:"peak"`
+	RMS     float64 `json:"rms"`
+	Clipped bool    `json:"clipped"`
+}
+
+func (l Levels) PeakDecibels() float64 {
+	return ToDecibels(l.Peak)
+}
+
+func (l Levels) RMSDecibels() float64 {
+	return ToDecibels(l.RMS)
+}
+
+// Meters are published at a fixed rate in a ChannelsOutputEvent. The
+// spectrum is only set when it's enabled in the audio config; it holds the
+// level in dBFS of every frequency band.
+type Meters struct {
+	Channels            []Levels  `json:"channels"`
+	Master              Levels    `json:"master"`
+	Spectrum            []float64 `json:"spectrum,omitempty"`
+	SpectrumFrequencies []float64 `json:"spectrum_frequencies,omitempty"`
+}
+
+// Convert a linear amplitude to decibels relative to full scale.
+func ToDecibels(v float64) float64 {
+	if v <= 0 {
+		return MinDecibels
+	}
+	return math.Max(MinDecibels, 20*math.Log10(v))
+}
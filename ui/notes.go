@@ -0,0 +1,18 @@
+package ui
+
+import "fmt"
+
+// ValidateNote checks the channel, note and velocity of a note sent by a
+// client before it's passed on to the controller.
+func ValidateNote(ch, note, velocity int) error {
+	if ch < 0 || ch > 15 {
+		return fmt.Errorf("invalid channel %d", ch)
+	}
+	if note < 0 || note > 127 {
+		return fmt.Errorf("invalid note %d", note)
+	}
+	if velocity < 0 || velocity > 127 {
+		return fmt.Errorf("invalid velocity %d", velocity)
+	}
+	return nil
+}
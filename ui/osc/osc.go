@@ -0,0 +1,278 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Message is an OSC message. The arguments are int32, int64, float32,
+// float64, string, []byte, bool or nil values.
+type Message struct {
+	Address   string
+	Arguments []interface{}
+}
+
+func NewMessage(address string, args ...interface{}) *Message {
+	return &Message{
+		Address:   address,
+		Arguments: args,
+	}
+}
+
+// ParsePacket parses an OSC packet, which is either a message or a bundle.
+// The messages in (nested) bundles are returned in order; time tags are
+// ignored and everything is handled immediately.
+func ParsePacket(data []byte) ([]*Message, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty packet")
+	}
+	if data[0] == '/' {
+		msg, err := ParseMessage(data)
+		if err != nil {
+			return nil, err
+		}
+		return []*Message{msg}, nil
+	}
+	r := &reader{data: data}
+	tag, err := r.String()
+	if err != nil {
+		return nil, err
+	}
+	if tag != "#bundle" {
+		return nil, fmt.Errorf("expecting a message or a bundle")
+	}
+	if _, err := r.Bytes(8); err != nil {
+		return nil, fmt.Errorf("missing time tag")
+	}
+	result := []*Message{}
+	for !r.Done() {
+		size, err := r.Int32()
+		if err != nil {
+			return nil, err
+		}
+		element, err := r.Bytes(int(size))
+		if err != nil {
+			return nil, err
+		}
+		messages, err := ParsePacket(element)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, messages...)
+	}
+	return result, nil
+}
+
+func ParseMessage(data []byte) (*Message, error) {
+	r := &reader{data: data}
+	address, err := r.String()
+	if err != nil {
+		return nil, err
+	}
+	if len(address) == 0 || address[0] != '/' {
+		return nil, fmt.Errorf("invalid address '%s'", address)
+	}
+	msg := NewMessage(address)
+	if r.Done() {
+		// Very old implementations leave out the type tags
+		return msg, nil
+	}
+	tags, err := r.String()
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 || tags[0] != ',' {
+		return nil, fmt.Errorf("invalid type tags '%s'", tags)
+	}
+	for _, tag := range tags[1:] {
+		var arg interface{}
+		switch tag {
+		case 'i':
+			arg, err = r.Int32()
+		case 'h':
+			arg, err = r.Int64()
+		case 'f':
+			var i int32
+			i, err = r.Int32()
+			arg = math.Float32frombits(uint32(i))
+		case 'd':
+			var i int64
+			i, err = r.Int64()
+			arg = math.Float64frombits(uint64(i))
+		case 's', 'S':
+			arg, err = r.String()
+		case 'b':
+			arg, err = r.Blob()
+		case 'T':
+			arg = true
+		case 'F':
+			arg = false
+		case 'N', 'I':
+			arg = nil
+		default:
+			return nil, fmt.Errorf("unsupported type tag '%c'", tag)
+		}
+		if err != nil {
+			return nil, err
+		}
+		msg.Arguments = append(msg.Arguments, arg)
+	}
+	return msg, nil
+}
+
+// MarshalBinary encodes the message.
+func (m *Message) MarshalBinary() ([]byte, error) {
+	tags := ","
+	args := &bytes.Buffer{}
+	for _, arg := range m.Arguments {
+		switch v := arg.(type) {
+		case int32:
+			tags += "i"
+			binary.Write(args, binary.BigEndian, v)
+		case int:
+			tags += "i"
+			binary.Write(args, binary.BigEndian, int32(v))
+		case int64:
+			tags += "h"
+			binary.Write(args, binary.BigEndian, v)
+		case float32:
+			tags += "f"
+			binary.Write(args, binary.BigEndian, v)
+		case float64:
+			tags += "d"
+			binary.Write(args, binary.BigEndian, v)
+		case string:
+			tags += "s"
+			writeString(args, v)
+		case []byte:
+			tags += "b"
+			binary.Write(args, binary.BigEndian, int32(len(v)))
+			args.Write(v)
+			args.Write(make([]byte, padding(len(v))))
+		case bool:
+			if v {
+				tags += "T"
+			} else {
+				tags += "F"
+			}
+		case nil:
+			tags += "N"
+		default:
+			return nil, fmt.Errorf("unsupported argument type %T", arg)
+		}
+	}
+	result := &bytes.Buffer{}
+	writeString(result, m.Address)
+	writeString(result, tags)
+	result.Write(args.Bytes())
+	return result.Bytes(), nil
+}
+
+// Float returns the i-th argument as a float. Integer arguments are
+// converted.
+func (m *Message) Float(i int) (float64, error) {
+	if i >= len(m.Arguments) {
+		return 0.0, fmt.Errorf("%s: missing argument %d", m.Address, i+1)
+	}
+	switch v := m.Arguments[i].(type) {
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case bool:
+		if v {
+			return 1.0, nil
+		}
+		return 0.0, nil
+	}
+	return 0.0, fmt.Errorf("%s: expecting a number for argument %d", m.Address, i+1)
+}
+
+// Int returns the i-th argument as an int. Float arguments are rounded; NaN,
+// infinity and floats outside of the int32 range are rejected.
+func (m *Message) Int(i int) (int, error) {
+	f, err := m.Float(i)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(f) || math.Abs(f) > math.MaxInt32 {
+		return 0, fmt.Errorf("%s: expecting an integer for argument %d", m.Address, i+1)
+	}
+	return int(math.Round(f)), nil
+}
+
+func padding(n int) int {
+	return (4 - n%4) % 4
+}
+
+// Strings are null terminated and padded to a multiple of four bytes.
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	buf.Write(make([]byte, 4-len(s)%4))
+}
+
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) Done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *reader) Bytes(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, fmt.Errorf("unexpected end of packet")
+	}
+	result := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return result, nil
+}
+
+func (r *reader) Int32() (int32, error) {
+	b, err := r.Bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *reader) Int64() (int64, error) {
+	b, err := r.Bytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *reader) String() (string, error) {
+	end := bytes.IndexByte(r.data[r.pos:], 0)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string")
+	}
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1 + padding(end+1)
+	if r.pos > len(r.data) {
+		r.pos = len(r.data)
+	}
+	return s, nil
+}
+
+func (r *reader) Blob() ([]byte, error) {
+	size, err := r.Int32()
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.Bytes(int(size))
+	if err != nil {
+		return nil, err
+	}
+	r.pos += padding(int(size))
+	return b, nil
+}
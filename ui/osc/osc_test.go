@@ -0,0 +1,95 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func Test_Message_roundtrip(t *testing.T) {
+	msg := NewMessage("/bleep/ch/3/lpf", int32(1200), float32(0.5), "abc", []byte{1, 2, 3, 4, 5}, true, false, nil, int64(7), 2.5)
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data)%4 != 0 {
+		t.Errorf("Expecting the packet to be padded to four bytes, got %d", len(data))
+	}
+	messages, err := ParsePacket(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 || !reflect.DeepEqual(messages[0], msg) {
+		t.Errorf("Expecting %v, got %v", msg, messages)
+	}
+}
+
+func Test_ParseMessage_spec_example(t *testing.T) {
+	// From the OSC 1.0 specification
+	data := []byte("/foo\x00\x00\x00\x00,iisff\x00\x00\x00\x00\x03\xe8\xff\xff\xff\xffhello\x00\x00\x00\x3f\x9d\xf3\xb6\x40\xb5\xb2\x2d")
+	msg, err := ParseMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Address != "/foo" || len(msg.Arguments) != 5 {
+		t.Fatalf("Unexpected message %v", msg)
+	}
+	if msg.Arguments[0] != int32(1000) || msg.Arguments[1] != int32(-1) || msg.Arguments[2] != "hello" {
+		t.Errorf("Unexpected arguments %v", msg.Arguments)
+	}
+	if f, _ := msg.Float(3); f < 1.233 || f > 1.235 {
+		t.Errorf("Expecting 1.234, got %f", f)
+	}
+	if i, _ := msg.Int(4); i != 6 {
+		t.Errorf("Expecting 5.678 to round to 6, got %d", i)
+	}
+	if _, err := msg.Float(5); err == nil {
+		t.Errorf("Expecting an error for a missing argument")
+	}
+	if _, err := msg.Float(2); err == nil {
+		t.Errorf("Expecting an error for a string argument")
+	}
+}
+
+func Test_ParsePacket_bundle(t *testing.T) {
+	first, _ := NewMessage("/a", int32(1)).MarshalBinary()
+	second, _ := NewMessage("/b", float32(2)).MarshalBinary()
+	nested := &bytes.Buffer{}
+	writeString(nested, "#bundle")
+	nested.Write(make([]byte, 8))
+	binary.Write(nested, binary.BigEndian, int32(len(second)))
+	nested.Write(second)
+
+	bundle := &bytes.Buffer{}
+	writeString(bundle, "#bundle")
+	bundle.Write([]byte{0, 0, 0, 0, 0, 0, 0, 1})
+	binary.Write(bundle, binary.BigEndian, int32(len(first)))
+	bundle.Write(first)
+	binary.Write(bundle, binary.BigEndian, int32(nested.Len()))
+	bundle.Write(nested.Bytes())
+
+	messages, err := ParsePacket(bundle.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].Address != "/a" || messages[1].Address != "/b" {
+		t.Errorf("Expecting the messages in the bundle, got %v", messages)
+	}
+	if _, err := ParsePacket(bundle.Bytes()[:bundle.Len()-2]); err == nil {
+		t.Errorf("Expecting an error for a truncated bundle")
+	}
+}
+
+func Test_ParseMessage_errors(t *testing.T) {
+	for _, data := range [][]byte{
+		[]byte("/foo"),
+		[]byte("/foo\x00\x00\x00\x00,i\x00\x00"),
+		[]byte("/foo\x00\x00\x00\x00,x\x00\x00"),
+		[]byte("foo\x00"),
+	} {
+		if _, err := ParsePacket(data); err == nil {
+			t.Errorf("Expecting an error for %q", data)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package osc
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/bspaans/bleep/controller"
+	"github.com/bspaans/bleep/synth"
+	"github.com/bspaans/bleep/ui"
+)
+
+// The Server listens for OSC messages on a UDP port, so that the synthesizer
+// and sequencer can be controlled by e.g. TouchOSC or SuperCollider. These
+// addresses are supported:
+//
+//	/bleep/ch/<channel>/note_on <note> [<velocity>]
+//	/bleep/ch/<channel>/note_off <note>
+//	/bleep/ch/<channel>/<parameter> <value>    see synth.ChannelParameters
+//	/bleep/ch/<channel>/pitchbend <-1.0 to 1.0>
+//	/bleep/ch/<channel>/silence
+//	/bleep/ch/<channel>/solo
+//	/bleep/master_gain <gain>
+//	/bleep/seq/play, stop, pause, rewind, forward, backward, reload
+//	/bleep/seq/goto <beat>
+//	/bleep/seq/bpm <bpm>
+//	/bleep/seq/register/<register> <int>
+//	/bleep/seq/float_register/<register> <float>
+//	/bleep/bank/reload
+//
+// Messages without arguments are triggers. Buttons that send 1 when they're
+// pressed and 0 when they're released only trigger on the 1.
+type Server struct {
+	Addr       string
+	Controller *controller.Controller
+	Conn       net.PacketConn
+}
+
+func NewServer(addr string) *Server {
+	return &Server{
+		Addr: addr,
+	}
+}
+
+func (s *Server) Start(ctrl *controller.Controller) (*Server, error) {
+	conn, err := net.ListenPacket("udp", s.Addr)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println("Starting OSC server on", conn.LocalAddr())
+	s.Controller = ctrl
+	s.Conn = conn
+	go s.listen()
+	return s, nil
+}
+
+func (s *Server) Close() error {
+	return s.Conn.Close()
+}
+
+func (s *Server) listen() {
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := s.Conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		messages, err := ParsePacket(buf[:n])
+		if err != nil {
+			fmt.Println("Invalid OSC packet:", err.Error())
+			continue
+		}
+		for _, msg := range messages {
+			if err := s.Handle(msg); err != nil {
+				fmt.Println("Invalid OSC message:", err.Error())
+			}
+		}
+	}
+}
+
+// Handle a single OSC message.
+func (s *Server) Handle(msg *Message) error {
+	path := strings.Split(strings.TrimPrefix(msg.Address, "/"), "/")
+	if len(path) < 2 || path[0] != "bleep" {
+		return fmt.Errorf("unknown address %s", msg.Address)
+	}
+	if path[1] == "ch" && len(path) == 4 {
+		ch, err := strconv.Atoi(path[2])
+		if err != nil || ch < 0 || ch > 15 {
+			return fmt.Errorf("%s: invalid channel '%s'", msg.Address, path[2])
+		}
+		return s.handleChannel(msg, ch, path[3])
+	} else if path[1] == "seq" && len(path) >= 3 {
+		return s.handleSequencer(msg, path[2:])
+	} else if path[1] == "master_gain" && len(path) == 2 {
+		gain, err := msg.Float(0)
+		if err != nil {
+			return err
+		}
+		s.Controller.Synth.SetMasterGain(gain)
+		return nil
+	} else if path[1] == "bank" && len(path) == 3 && path[2] == "reload" {
+		if !isTriggered(msg) {
+			return nil
+		}
+		if err := s.Controller.ReloadInstrumentBank(); err != nil {
+			return err
+		}
+		return s.Controller.ReloadPercussionBank()
+	}
+	return fmt.Errorf("unknown address %s", msg.Address)
+}
+
+func (s *Server) handleChannel(msg *Message, ch int, param string) error {
+	if param == "note_on" {
+		note, err := msg.Int(0)
+		if err != nil {
+			return err
+		}
+		velocity := 100
+		if len(msg.Arguments) > 1 {
+			if velocity, err = msg.Int(1); err != nil {
+				return err
+			}
+		}
+		if err := ui.ValidateNote(ch, note, velocity); err != nil {
+			return fmt.Errorf("%s: %s", msg.Address, err.Error())
+		}
+		if velocity == 0 {
+			s.Controller.HandleLiveEvent(synth.NewEvent(synth.NoteOff, ch, []int{note}))
+		} else {
+			s.Controller.HandleLiveEvent(synth.NewEvent(synth.NoteOn, ch, []int{note, velocity}))
+		}
+		return nil
+	} else if param == "note_off" {
+		note, err := msg.Int(0)
+		if err != nil {
+			return err
+		}
+		if err := ui.ValidateNote(ch, note, 0); err != nil {
+			return fmt.Errorf("%s: %s", msg.Address, err.Error())
+		}
+		s.Controller.HandleLiveEvent(synth.NewEvent(synth.NoteOff, ch, []int{note}))
+		return nil
+	} else if param == "pitchbend" {
+		f, err := msg.Float(0)
+		if err != nil {
+			return err
+		}
+		// Convert to a 14 bit MIDI pitch bend
+		v := int((f + 1.0) * 8192)
+		if v < 0 {
+			v = 0
+		} else if v > 16383 {
+			v = 16383
+		}
+		s.Controller.HandleLiveEvent(synth.NewEvent(synth.PitchBend, ch, []int{v >> 7, v & 0x7F}))
+		return nil
+	} else if param == "silence" {
+		if isTriggered(msg) {
+			s.Controller.SendSynthEvent(synth.NewEvent(synth.SilenceChannel, ch, nil))
+		}
+		return nil
+	} else if param == "solo" {
+		if isTriggered(msg) {
+			s.Controller.ToggleSoloChannel(ch)
+		}
+		return nil
+	}
+	value, err := msg.Float(0)
+	if err != nil {
+		return err
+	}
+	ev, err := synth.NewChannelParameterEvent(param, ch, value)
+	if err != nil {
+		return fmt.Errorf("%s: %s", msg.Address, err.Error())
+	}
+	s.Controller.SendSynthEvent(ev)
+	return nil
+}
+
+func (s *Server) handleSequencer(msg *Message, path []string) error {
+	seq := s.Controller.Sequencer
+	if seq == nil {
+		return fmt.Errorf("%s: no sequencer", msg.Address)
+	}
+	if len(path) == 2 && (path[0] == "register" || path[0] == "float_register") {
+		register, err := strconv.Atoi(path[1])
+		if err != nil || register < 0 || register > 127 {
+			return fmt.Errorf("%s: invalid register '%s'", msg.Address, path[1])
+		}
+		if path[0] == "register" {
+			v, err := msg.Int(0)
+			if err != nil {
+				return err
+			}
+			seq.SetRegister(register, v)
+		} else {
+			v, err := msg.Float(0)
+			if err != nil {
+				return err
+			}
+			seq.SetFloatRegister(register, v)
+		}
+		return nil
+	} else if len(path) != 1 {
+		return fmt.Errorf("unknown address %s", msg.Address)
+	}
+	cmd := path[0]
+	if cmd == "bpm" {
+		bpm, err := msg.Float(0)
+		if err != nil {
+			return err
+		}
+		seq.SetBPM(bpm)
+		return nil
+	} else if cmd == "goto" {
+		beat, err := msg.Float(0)
+		if err != nil {
+			return err
+		}
+		if beat < 0 {
+			beat = 0
+		}
+		seq.GoToTime(uint(beat * float64(seq.Granularity)))
+		return nil
+	}
+	triggers := map[string]func(){
+		"play":     seq.StartPlaying,
+		"stop":     seq.StopPlaying,
+		"pause":    seq.PausePlaying,
+		"rewind":   seq.Rewind,
+		"forward":  seq.MoveForward,
+		"backward": seq.MoveBackward,
+		"reload":   seq.Reload,
+	}
+	f, ok := triggers[cmd]
+	if !ok {
+		return fmt.Errorf("unknown address %s", msg.Address)
+	}
+	if isTriggered(msg) {
+		f()
+	}
+	return nil
+}
+
+// isTriggered returns false for button releases.
+func isTriggered(msg *Message) bool {
+	if len(msg.Arguments) == 0 {
+		return true
+	}
+	f, err := msg.Float(0)
+	return err != nil || f != 0.0
+}
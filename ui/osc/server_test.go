@@ -0,0 +1,138 @@
+package osc
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/controller"
+	"github.com/bspaans/bleep/sequencer"
+	"github.com/bspaans/bleep/synth"
+)
+
+func newTestServer(t *testing.T) (*Server, net.Conn) {
+	ctrl := controller.NewController(audio.NewAudioConfig())
+	ctrl.Sequencer = sequencer.NewSequencer(120, 64)
+	s, err := NewServer("127.0.0.1:0").Start(ctrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("udp", s.Conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, conn
+}
+
+func send(t *testing.T, conn net.Conn, msg *Message) {
+	data, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receiveSynthEvent(t *testing.T, s *Server) *synth.Event {
+	select {
+	case ev := <-s.Controller.Synth.Inputs:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for synth event")
+	}
+	return nil
+}
+
+func receiveSequencerEvent(t *testing.T, s *Server) *sequencer.SequencerEvent {
+	select {
+	case ev := <-s.Controller.Sequencer.Inputs:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for sequencer event")
+	}
+	return nil
+}
+
+func Test_Server_channel_messages(t *testing.T) {
+	s, conn := newTestServer(t)
+	defer s.Close()
+	defer conn.Close()
+
+	send(t, conn, NewMessage("/bleep/ch/3/lpf", float32(1200)))
+	if ev := receiveSynthEvent(t, s); ev.Type != synth.SetLPFCutoff || ev.Channel != 3 || ev.Values[0] != 1200 {
+		t.Errorf("Expecting a low pass filter event, got %v", ev)
+	}
+	send(t, conn, NewMessage("/bleep/ch/2/grain_speed", int32(2)))
+	if ev := receiveSynthEvent(t, s); ev.Type != synth.SetGrainSpeed || ev.Channel != 2 || ev.FloatValues[0] != 2.0 {
+		t.Errorf("Expecting a grain speed event, got %v", ev)
+	}
+	send(t, conn, NewMessage("/bleep/master_gain", float32(0.5)))
+	if ev := receiveSynthEvent(t, s); ev.Type != synth.SetMasterGain || ev.FloatValues[0] != 0.5 {
+		t.Errorf("Expecting a master gain event, got %v", ev)
+	}
+
+	// Live notes go through the sequencer's input processors
+	send(t, conn, NewMessage("/bleep/ch/1/note_on", int32(60), int32(90)))
+	ev := receiveSequencerEvent(t, s)
+	if ev.Type != sequencer.LiveEvent || ev.Event.Type != synth.NoteOn || ev.Event.Values[0] != 60 || ev.Event.Values[1] != 90 {
+		t.Errorf("Expecting a live note on, got %v", ev)
+	}
+	send(t, conn, NewMessage("/bleep/ch/1/pitchbend", float32(0.0)))
+	ev = receiveSequencerEvent(t, s)
+	if ev.Event.Type != synth.PitchBend || ev.Event.Values[0] != 64 || ev.Event.Values[1] != 0 {
+		t.Errorf("Expecting a centered pitch bend, got %v", ev.Event)
+	}
+}
+
+func Test_Server_sequencer_messages(t *testing.T) {
+	s, conn := newTestServer(t)
+	defer s.Close()
+	defer conn.Close()
+
+	send(t, conn, NewMessage("/bleep/seq/register/1", int32(74)))
+	if ev := receiveSequencerEvent(t, s); ev.Type != sequencer.SetRegister {
+		t.Errorf("Expecting a register event, got %v", ev)
+	}
+	send(t, conn, NewMessage("/bleep/seq/bpm", float32(90)))
+	if ev := receiveSequencerEvent(t, s); ev.Type != sequencer.SetBPM || ev.Value.(float64) != 90.0 {
+		t.Errorf("Expecting a bpm event, got %v", ev)
+	}
+	// Button releases are ignored
+	send(t, conn, NewMessage("/bleep/seq/play", float32(0)))
+	send(t, conn, NewMessage("/bleep/seq/play", float32(1)))
+	if ev := receiveSequencerEvent(t, s); ev.Type != sequencer.StartPlaying {
+		t.Errorf("Expecting a play event, got %v", ev)
+	}
+	send(t, conn, NewMessage("/bleep/seq/goto", int32(2)))
+	if ev := receiveSequencerEvent(t, s); ev.Type != sequencer.GoToTime || ev.Value.(uint) != 128 {
+		t.Errorf("Expecting a goto event, got %v", ev)
+	}
+}
+
+func Test_Server_Handle_errors(t *testing.T) {
+	s := NewServer("")
+	s.Controller = controller.NewController(audio.NewAudioConfig())
+	for _, msg := range []*Message{
+		NewMessage("/other/ch/1/lpf", int32(1)),
+		NewMessage("/bleep/ch/16/lpf", int32(1)),
+		NewMessage("/bleep/ch/1/unknown", int32(1)),
+		NewMessage("/bleep/ch/1/lpf", "high"),
+		NewMessage("/bleep/ch/1/note_on"),
+		NewMessage("/bleep/ch/1/note_on", int32(128)),
+		NewMessage("/bleep/ch/1/note_on", int32(60), int32(200)),
+		NewMessage("/bleep/ch/1/note_on", float32(math.NaN())),
+		NewMessage("/bleep/ch/1/note_on", float32(1e30)),
+		NewMessage("/bleep/ch/1/note_off", int32(-1)),
+		NewMessage("/bleep/seq/play"),
+	} {
+		if err := s.Handle(msg); err == nil {
+			t.Errorf("Expecting an error for %v", msg)
+		}
+	}
+	if len(s.Controller.Synth.Inputs) != 0 {
+		t.Errorf("Expecting invalid messages not to reach the synth")
+	}
+}
@@ -3,122 +3,353 @@ package server
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 
 	"github.com/bspaans/bleep/controller"
+	"github.com/bspaans/bleep/midi/mapping"
 	"github.com/bspaans/bleep/sequencer/definitions"
-	"github.com/gorilla/websocket"
+	"github.com/bspaans/bleep/synth"
+	"github.com/bspaans/bleep/ui"
 )
 
+// ProtocolVersion is the version of the websocket protocol. Requests without
+// a version are handled as the current version; requests for a newer version
+// get an error reply.
+//
+// Every request is a JSON object with a type, an optional id and optional
+// data:
+//
+//	{"version": 1, "id": 7, "type": "set_channel_param", "data": {"channel": 1, "param": "lpf", "value": 1200}}
+//
+// Every request gets a reply with the same type and id. The data holds the
+// result (null for commands), unless the request failed, in which case the
+// error is set:
+//
+//	{"version": 1, "id": 7, "type": "set_channel_param", "data": null, "error": "unknown channel parameter 'lfp'"}
+//
+// The server also pushes messages without an id: status and meters updates
+// (see ui.Meters), force_reload after the sequencer has been reloaded, and
+// midi_learned.
+const ProtocolVersion = 1
+
 type MessageType string
 
 const (
-	Test            MessageType = "test"
-	Status          MessageType = "status"
-	ChannelDef      MessageType = "channel_def"
-	SequencerDef    MessageType = "sequencer_def"
-	SetSequencerDef MessageType = "set_sequencer_def"
-	Play            MessageType = "play"
-	Stop            MessageType = "stop"
-	Pause           MessageType = "pause"
-	Rewind          MessageType = "rewind"
-	GoToTime        MessageType = "goto"
-	Load            MessageType = "load"
-	Save            MessageType = "save"
-	ForceReload     MessageType = "force_reload"
-	SetMasterGain   MessageType = "set_master_gain"
+	Hello            MessageType = "hello"
+	Test             MessageType = "test"
+	Status           MessageType = "status"
+	ChannelDef       MessageType = "channel_def"
+	SequencerDef     MessageType = "sequencer_def"
+	SetSequencerDef  MessageType = "set_sequencer_def"
+	Play             MessageType = "play"
+	Stop             MessageType = "stop"
+	Pause            MessageType = "pause"
+	Rewind           MessageType = "rewind"
+	GoToTime         MessageType = "goto"
+	SetBPM           MessageType = "set_bpm"
+	Load             MessageType = "load"
+	Save             MessageType = "save"
+	SetMasterGain    MessageType = "set_master_gain"
+	NoteOn           MessageType = "note_on"
+	NoteOff          MessageType = "note_off"
+	SetChannelParam  MessageType = "set_channel_param"
+	SilenceChannel   MessageType = "silence_channel"
+	ToggleSolo       MessageType = "toggle_solo"
+	ToggleMute       MessageType = "toggle_mute"
+	SetRegister      MessageType = "set_register"
+	SetFloatRegister MessageType = "set_float_register"
+	LoadBank         MessageType = "load_bank"
+	ReloadBanks      MessageType = "reload_banks"
+	MIDILearn        MessageType = "midi_learn"
+	CancelMIDILearn  MessageType = "cancel_midi_learn"
+
+	// Pushed by the server
+	ForceReload MessageType = "force_reload"
+	MIDILearned MessageType = "midi_learned"
+	Meters      MessageType = "meters"
 )
 
+// The request types that are handled; returned in the hello reply.
+var MessageTypes = []MessageType{
+	Hello, Test, Status, ChannelDef, SequencerDef, SetSequencerDef,
+	Play, Stop, Pause, Rewind, GoToTime, SetBPM, Load, Save,
+	SetMasterGain, NoteOn, NoteOff, SetChannelParam, SilenceChannel, ToggleSolo, ToggleMute,
+	SetRegister, SetFloatRegister, LoadBank, ReloadBanks, MIDILearn, CancelMIDILearn,
+}
+
 type ResponseMessage struct {
-	Type MessageType `json:"type"`
-	Data interface{} `json:"data"`
+	Version int         `json:"version"`
+	ID      interface{} `json:"id,omitempty"`
+	Type    MessageType `json:"type"`
+	Data    interface{} `json:"data"`
+	Error   string      `json:"error,omitempty"`
+}
+
+type HelloResponse struct {
+	Version      int           `json:"version"`
+	MessageTypes []MessageType `json:"message_types"`
 }
 
 type StatusResponse struct {
+	Sequencer   bool    `json:"sequencer"`
 	BPM         float64 `json:"bpm"`
 	Playing     bool    `json:"playing"`
 	Granularity int     `json:"granularity"`
 	Time        uint    `json:"time"`
 	MasterGain  float64 `json:"master_gain"`
+	Solo        []bool  `json:"solo"`
+	SoloSafe    []bool  `json:"solo_safe"`
+	Mute        []bool  `json:"mute"`
+}
+
+type NoteRequest struct {
+	Channel  int `json:"channel"`
+	Note     int `json:"note"`
+	Velocity int `json:"velocity"`
+}
+
+type ChannelParamRequest struct {
+	Channel int     `json:"channel"`
+	Param   string  `json:"param"`
+	Value   float64 `json:"value"`
+}
+
+type ChannelRequest struct {
+	Channel int `json:"channel"`
+}
+
+type RegisterRequest struct {
+	Register int     `json:"register"`
+	Value    float64 `json:"value"`
+}
+
+type LoadBankRequest struct {
+	File       string `json:"file"`
+	Percussion bool   `json:"percussion"`
 }
 
 type Message struct {
-	Type MessageType `json:"type"`
-	Data interface{} `json:"data"`
+	Version int             `json:"version,omitempty"`
+	ID      interface{}     `json:"id,omitempty"`
+	Type    MessageType     `json:"type"`
+	Data    json.RawMessage `json:"data,omitempty"`
 }
 
-func NewMessage(ty MessageType, data interface{}) *Message {
-	return &Message{
-		Type: ty,
-		Data: data,
+// Reply handles the request and returns the reply.
+func (m *Message) Reply(ctrl *controller.Controller) *ResponseMessage {
+	result := &ResponseMessage{
+		Version: ProtocolVersion,
+		ID:      m.ID,
+		Type:    m.Type,
+	}
+	if m.Version > ProtocolVersion {
+		result.Error = fmt.Sprintf("unsupported protocol version %d; expecting %d or lower", m.Version, ProtocolVersion)
+		return result
+	}
+	data, err := m.Handle(ctrl)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Data = data
 	}
+	return result
 }
 
-func (m *Message) Handle(ctrl *controller.Controller, conn *websocket.Conn) {
-	if m.Type == Test {
-		m.send(conn, m.Type, "Test")
+// Handle the request and return the data for the reply.
+func (m *Message) Handle(ctrl *controller.Controller) (interface{}, error) {
+	if m.Type == Hello {
+		return &HelloResponse{ProtocolVersion, MessageTypes}, nil
+	} else if m.Type == Test {
+		return "Test", nil
+	} else if m.Type == Status {
+		return NewStatusResponse(ctrl), nil
+	} else if m.Type == SetMasterGain {
+		var gain float64
+		if err := m.decode(&gain); err != nil {
+			return nil, err
+		}
+		ctrl.Synth.SetMasterGain(gain)
+	} else if m.Type == NoteOn {
+		req := NoteRequest{Velocity: 100}
+		if err := m.decode(&req); err != nil {
+			return nil, err
+		}
+		if err := ui.ValidateNote(req.Channel, req.Note, req.Velocity); err != nil {
+			return nil, err
+		}
+		if req.Velocity == 0 {
+			ctrl.HandleLiveEvent(synth.NewEvent(synth.NoteOff, req.Channel, []int{req.Note}))
+		} else {
+			ctrl.HandleLiveEvent(synth.NewEvent(synth.NoteOn, req.Channel, []int{req.Note, req.Velocity}))
+		}
+	} else if m.Type == NoteOff {
+		req := NoteRequest{}
+		if err := m.decode(&req); err != nil {
+			return nil, err
+		}
+		if err := ui.ValidateNote(req.Channel, req.Note, 0); err != nil {
+			return nil, err
+		}
+		ctrl.HandleLiveEvent(synth.NewEvent(synth.NoteOff, req.Channel, []int{req.Note}))
+	} else if m.Type == SetChannelParam {
+		req := ChannelParamRequest{}
+		if err := m.decode(&req); err != nil {
+			return nil, err
+		}
+		ev, err := synth.NewChannelParameterEvent(req.Param, req.Channel, req.Value)
+		if err != nil {
+			return nil, err
+		}
+		ctrl.SendSynthEvent(ev)
+	} else if m.Type == SilenceChannel || m.Type == ToggleSolo || m.Type == ToggleMute {
+		req := ChannelRequest{}
+		if err := m.decode(&req); err != nil {
+			return nil, err
+		}
+		if req.Channel < 0 || req.Channel > 15 {
+			return nil, fmt.Errorf("invalid channel %d", req.Channel)
+		}
+		if m.Type == SilenceChannel {
+			ctrl.SendSynthEvent(synth.NewEvent(synth.SilenceChannel, req.Channel, nil))
+		} else if m.Type == ToggleSolo {
+			ctrl.ToggleSoloChannel(req.Channel)
+		} else {
+			ctrl.ToggleMuteChannel(req.Channel)
+		}
+	} else if m.Type == LoadBank {
+		req := LoadBankRequest{}
+		if err := m.decode(&req); err != nil {
+			return nil, err
+		}
+		if req.Percussion {
+			return nil, ctrl.LoadPercussionBank(req.File)
+		}
+		return nil, ctrl.LoadInstrumentBank(req.File)
+	} else if m.Type == ReloadBanks {
+		if err := ctrl.ReloadInstrumentBank(); err != nil {
+			return nil, err
+		}
+		return nil, ctrl.ReloadPercussionBank()
+	} else if m.Type == MIDILearn {
+		// The data is a mapping definition without the cc, e.g.
+		// {"target": "lpf_cutoff", "channel": 1}
+		def := mapping.MappingDef{}
+		if err := m.decode(&def); err != nil {
+			return nil, err
+		}
+		return nil, ctrl.StartMIDILearn(&def)
+	} else if m.Type == CancelMIDILearn {
+		ctrl.CancelMIDILearn()
+	} else {
+		return m.handleSequencer(ctrl)
 	}
+	return nil, nil
+}
 
-	if ctrl.Sequencer == nil {
-		log.Println("No sequencer.")
-		return
+func (m *Message) handleSequencer(ctrl *controller.Controller) (interface{}, error) {
+	known := false
+	for _, ty := range MessageTypes {
+		known = known || ty == m.Type
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown message type '%s'", m.Type)
+	}
+	seq := ctrl.Sequencer
+	if seq == nil {
+		return nil, fmt.Errorf("no sequencer loaded")
 	}
-	if m.Type == Status {
-		m.send(conn, m.Type, &StatusResponse{
-			BPM:         ctrl.Sequencer.Status.BPM,
-			Playing:     ctrl.Sequencer.Status.Playing,
-			Granularity: ctrl.Sequencer.Status.Granularity,
-			Time:        ctrl.Sequencer.Status.Time,
-			MasterGain:  ctrl.Synth.Mixer.MasterGain,
-		})
-	} else if m.Type == ChannelDef {
-		m.send(conn, m.Type, ctrl.Sequencer.InitialChannelSetup)
+	if m.Type == ChannelDef {
+		return seq.InitialChannelSetup, nil
+	} else if m.Type == SequencerDef {
+		return seq.SequencerDef, nil
 	} else if m.Type == Play {
-		ctrl.Sequencer.StartPlaying()
+		seq.StartPlaying()
 	} else if m.Type == Pause {
-		ctrl.Sequencer.PausePlaying()
+		seq.PausePlaying()
 	} else if m.Type == Stop {
-		ctrl.Sequencer.StopPlaying()
+		seq.StopPlaying()
 	} else if m.Type == Rewind {
-		ctrl.Sequencer.Rewind()
+		seq.Rewind()
 	} else if m.Type == GoToTime {
-		v := m.Data.(float64)
-		ctrl.Sequencer.GoToTime(uint(v * float64(ctrl.Sequencer.Status.Granularity)))
-	} else if m.Type == Load {
-		ctrl.Sequencer.LoadFile(m.Data.(string))
-	} else if m.Type == Save {
-		ctrl.Sequencer.SaveFile(m.Data.(string))
-	} else if m.Type == SetMasterGain {
-		ctrl.Synth.SetMasterGain(m.Data.(float64))
-	} else if m.Type == SequencerDef {
-		if ctrl.Sequencer.SequencerDef != nil {
-			m.send(conn, m.Type, ctrl.Sequencer.SequencerDef)
+		var beat float64
+		if err := m.decode(&beat); err != nil {
+			return nil, err
+		}
+		if beat < 0 {
+			return nil, fmt.Errorf("can't go to a negative time")
+		}
+		seq.GoToTime(uint(beat * float64(seq.Status.Granularity)))
+	} else if m.Type == SetBPM {
+		var bpm float64
+		if err := m.decode(&bpm); err != nil {
+			return nil, err
+		}
+		if bpm <= 0 {
+			return nil, fmt.Errorf("the bpm should be positive")
+		}
+		seq.SetBPM(bpm)
+	} else if m.Type == Load || m.Type == Save {
+		var file string
+		if err := m.decode(&file); err != nil {
+			return nil, err
+		}
+		if m.Type == Load {
+			seq.LoadFile(file)
 		} else {
-			m.send(conn, m.Type, nil)
+			seq.SaveFile(file)
 		}
 	} else if m.Type == SetSequencerDef {
+		// The definition can be sent as an object, or as a string
+		// containing JSON.
+		data := m.Data
+		var s string
+		if err := json.Unmarshal(m.Data, &s); err == nil {
+			data = []byte(s)
+		}
 		def := definitions.SequencerDef{}
-		if err := json.Unmarshal([]byte(m.Data.(string)), &def); err != nil {
-			fmt.Println("Invalid sequencer_def:", err.Error())
+		if err := json.Unmarshal(data, &def); err != nil {
+			return nil, fmt.Errorf("invalid sequencer_def: %s", err.Error())
+		}
+		seq.SetSequencerDef(&def)
+	} else if m.Type == SetRegister || m.Type == SetFloatRegister {
+		req := RegisterRequest{}
+		if err := m.decode(&req); err != nil {
+			return nil, err
+		}
+		if req.Register < 0 || req.Register > 127 {
+			return nil, fmt.Errorf("invalid register %d", req.Register)
+		}
+		if m.Type == SetRegister {
+			seq.SetRegister(req.Register, int(req.Value))
+		} else {
+			seq.SetFloatRegister(req.Register, req.Value)
 		}
-		fmt.Println("Received sequencer def:")
-		fmt.Println(def.YAML())
-		ctrl.Sequencer.SetSequencerDef(&def)
-	} else {
-		log.Println("Unknown message", m.Type, "message")
 	}
+	return nil, nil
 }
 
-func (m *Message) send(conn *websocket.Conn, typ MessageType, v interface{}) {
-	msg, err := json.Marshal(&ResponseMessage{
-		Type: typ,
-		Data: v,
-	})
-	if err == nil {
-		conn.WriteMessage(websocket.TextMessage, msg)
+func (m *Message) decode(v interface{}) error {
+	if len(m.Data) == 0 {
+		return fmt.Errorf("missing data for %s", m.Type)
 	}
+	if err := json.Unmarshal(m.Data, v); err != nil {
+		return fmt.Errorf("invalid data for %s: %s", m.Type, err.Error())
+	}
+	return nil
 }
 
-func (m *Message) Send(conn *websocket.Conn) {
-	m.send(conn, m.Type, m.Data)
+func NewStatusResponse(ctrl *controller.Controller) *StatusResponse {
+	result := &StatusResponse{
+		MasterGain: ctrl.Synth.Mixer.MasterGain,
+		Solo:       append([]bool{}, ctrl.Synth.Mixer.Solo...),
+		SoloSafe:   append([]bool{}, ctrl.Synth.Mixer.SoloSafe...),
+		Mute:       append([]bool{}, ctrl.Synth.Mixer.Mute...),
+	}
+	if ctrl.Sequencer != nil {
+		result.Sequencer = true
+		result.BPM = ctrl.Sequencer.Status.BPM
+		result.Playing = ctrl.Sequencer.Status.Playing
+		result.Granularity = ctrl.Sequencer.Status.Granularity
+		result.Time = ctrl.Sequencer.Status.Time
+	}
+	return result
 }
@@ -0,0 +1,285 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bspaans/bleep/instruments"
+	"github.com/bspaans/bleep/sequencer/definitions"
+	"github.com/bspaans/bleep/synth"
+)
+
+// The maximum number of beats that can be rendered in one request.
+const maxRenderBeats = 4096
+
+// RegisterAPI adds the REST endpoints to mux:
+//
+//	GET  /api/status
+//	POST /api/sequencer/validate
+//	POST /api/sequencer/load
+//	POST /api/bank/validate[?percussion=true]
+//	POST /api/bank/load[?percussion=true]
+//	POST /api/transport/play, stop, pause, rewind
+//	POST /api/transport/seek?beat=<beat>
+//	POST /api/channels/<channel>    {"lpf": 1200, "reverb": 40}
+//	POST /api/render[?beats=16]     returns audio/wav
+//
+// Definitions are posted as YAML in the request body, or read from disk with
+// ?file=<path>. Files referenced by a posted definition are resolved relative
+// to the working directory. Errors are returned as {"error": "..."}.
+//
+// POST requests need a JSON or YAML Content-Type, and are rejected when they
+// come from a web page on another origin. Browsers can't send those types
+// cross-origin without asking first, so other pages can't use the API.
+func (s *Server) RegisterAPI(mux *http.ServeMux) {
+	mux.HandleFunc("/api/status", s.apiStatus)
+	mux.HandleFunc("/api/sequencer/validate", s.post(s.apiSequencer(false)))
+	mux.HandleFunc("/api/sequencer/load", s.post(s.apiSequencer(true)))
+	mux.HandleFunc("/api/bank/validate", s.post(s.apiBank(false)))
+	mux.HandleFunc("/api/bank/load", s.post(s.apiBank(true)))
+	mux.HandleFunc("/api/transport/", s.post(s.apiTransport))
+	mux.HandleFunc("/api/channels/", s.post(s.apiChannel))
+	mux.HandleFunc("/api/render", s.post(s.apiRender))
+}
+
+type apiError struct {
+	Status int
+	Err    error
+}
+
+func (e *apiError) Error() string {
+	return e.Err.Error()
+}
+
+func badRequest(err error) error {
+	return &apiError{http.StatusBadRequest, err}
+}
+
+type apiHandler func(w http.ResponseWriter, r *http.Request) error
+
+// The content types accepted in POST requests. These can't be sent by a form
+// or a simple cross-origin request.
+var apiContentTypes = map[string]bool{
+	"application/json":   true,
+	"application/yaml":   true,
+	"application/x-yaml": true,
+}
+
+// post only accepts same-origin POST requests, and writes the JSON error
+// replies.
+func (s *Server) post(f apiHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "expecting a POST request"})
+			return
+		}
+		if !sameOrigin(r) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "cross-origin requests are not allowed"})
+			return
+		}
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || !apiContentTypes[mediaType] {
+			writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "expecting an application/json or application/yaml Content-Type"})
+			return
+		}
+		if err := f(w, r); err != nil {
+			status := http.StatusInternalServerError
+			if e, ok := err.(*apiError); ok {
+				status = e.Status
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
+		}
+	}
+}
+
+// sameOrigin returns false when the request comes from a page on another
+// host. Requests without an Origin don't come from a browser.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeOK(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (s *Server) apiStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "expecting a GET request"})
+		return
+	}
+	writeJSON(w, http.StatusOK, NewStatusResponse(s.Controller))
+}
+
+// readDefinition returns the posted YAML, or the contents of the file
+// parameter, and the file it came from.
+func readDefinition(r *http.Request) ([]byte, string, error) {
+	if file := r.URL.Query().Get("file"); file != "" {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, "", badRequest(err)
+		}
+		return contents, file, nil
+	}
+	contents, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", badRequest(err)
+	}
+	if len(contents) == 0 {
+		return nil, "", badRequest(fmt.Errorf("expecting a YAML definition in the request body or a file parameter"))
+	}
+	return contents, "", nil
+}
+
+func readSequencerDef(r *http.Request) (*definitions.SequencerDef, error) {
+	contents, file, err := readDefinition(r)
+	if err != nil {
+		return nil, err
+	}
+	def, err := definitions.NewSequencerDefFromYaml(contents, file)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+	if err := def.Validate(); err != nil {
+		return nil, badRequest(err)
+	}
+	return def, nil
+}
+
+func (s *Server) apiSequencer(load bool) apiHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		def, err := readSequencerDef(r)
+		if err != nil {
+			return err
+		}
+		if load {
+			s.Controller.LoadSequencerDef(def)
+		}
+		writeOK(w)
+		return nil
+	}
+}
+
+func (s *Server) apiBank(load bool) apiHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		contents, file, err := readDefinition(r)
+		if err != nil {
+			return err
+		}
+		def, err := instruments.NewBankFromYaml(contents, file)
+		if err != nil {
+			return badRequest(err)
+		}
+		if err := def.Validate(s.Controller.Config); err != nil {
+			return badRequest(err)
+		}
+		if load && r.URL.Query().Get("percussion") == "true" {
+			err = s.Controller.LoadPercussionBankDef(def)
+		} else if load {
+			err = s.Controller.LoadInstrumentBankDef(def)
+		}
+		if err != nil {
+			return err
+		}
+		writeOK(w)
+		return nil
+	}
+}
+
+func (s *Server) apiTransport(w http.ResponseWriter, r *http.Request) error {
+	seq := s.Controller.Sequencer
+	if seq == nil {
+		return &apiError{http.StatusConflict, fmt.Errorf("no sequencer loaded")}
+	}
+	cmd := strings.TrimPrefix(r.URL.Path, "/api/transport/")
+	if cmd == "play" {
+		seq.StartPlaying()
+	} else if cmd == "stop" {
+		seq.StopPlaying()
+	} else if cmd == "pause" {
+		seq.PausePlaying()
+	} else if cmd == "rewind" {
+		seq.Rewind()
+	} else if cmd == "seek" {
+		beat, err := strconv.ParseFloat(r.URL.Query().Get("beat"), 64)
+		if err != nil || beat < 0 {
+			return badRequest(fmt.Errorf("expecting a beat parameter >= 0"))
+		}
+		seq.GoToTime(uint(beat * float64(seq.Status.Granularity)))
+	} else {
+		return &apiError{http.StatusNotFound, fmt.Errorf("unknown transport command '%s'", cmd)}
+	}
+	writeOK(w)
+	return nil
+}
+
+func (s *Server) apiChannel(w http.ResponseWriter, r *http.Request) error {
+	ch, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/channels/"))
+	if err != nil {
+		return &apiError{http.StatusNotFound, fmt.Errorf("invalid channel")}
+	}
+	params := map[string]float64{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		return badRequest(fmt.Errorf("expecting a JSON object with parameter values: %s", err.Error()))
+	}
+	// Validate everything before changing anything
+	events := []*synth.Event{}
+	for param, value := range params {
+		ev, err := synth.NewChannelParameterEvent(param, ch, value)
+		if err != nil {
+			return badRequest(err)
+		}
+		events = append(events, ev)
+	}
+	for _, ev := range events {
+		s.Controller.SendSynthEvent(ev)
+	}
+	writeOK(w)
+	return nil
+}
+
+func (s *Server) apiRender(w http.ResponseWriter, r *http.Request) error {
+	def, err := readSequencerDef(r)
+	if err != nil {
+		return err
+	}
+	beats := 16.0
+	if b := r.URL.Query().Get("beats"); b != "" {
+		if beats, err = strconv.ParseFloat(b, 64); err != nil || beats <= 0 || beats > maxRenderBeats {
+			return badRequest(fmt.Errorf("expecting a number of beats between 0 and %d", maxRenderBeats))
+		}
+	}
+	tmp, err := ioutil.TempFile("", "bleep-render-*.wav")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+	if err := s.Controller.Render(def, beats, tmp.Name()); err != nil {
+		return err
+	}
+	contents, err := ioutil.ReadFile(tmp.Name())
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Content-Length", strconv.Itoa(len(contents)))
+	w.Write(contents)
+	return nil
+}
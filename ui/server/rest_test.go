@@ -0,0 +1,195 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/controller"
+	"github.com/bspaans/bleep/sequencer"
+	"github.com/bspaans/bleep/synth"
+)
+
+const testSequencerDef = `
+bpm: 120
+granularity: 16
+channels:
+- channel: 1
+  instrument: 0
+  volume: 100
+sequences:
+- play_note:
+    every: Quarter
+    channel: 1
+    note: 60
+    duration: Eight
+    velocity: 100
+`
+
+func newTestAPI(t *testing.T) (*controller.Controller, *httptest.Server) {
+	cfg := audio.NewAudioConfig()
+	cfg.SampleRate = 8000
+	ctrl := controller.NewController(cfg)
+	s := NewServer()
+	s.Controller = ctrl
+	mux := http.NewServeMux()
+	s.RegisterAPI(mux)
+	return ctrl, httptest.NewServer(mux)
+}
+
+func postAPI(t *testing.T, server *httptest.Server, path, body string) (int, []byte) {
+	resp, err := http.Post(server.URL+path, "application/x-yaml", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, contents
+}
+
+func Test_API_status(t *testing.T) {
+	_, server := newTestAPI(t)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/api/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	status := StatusResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || status.Sequencer || status.MasterGain != 1.0 {
+		t.Errorf("Unexpected status %d %v", resp.StatusCode, status)
+	}
+	if code, _ := postAPI(t, server, "/api/status", ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expecting POST to be rejected, got %d", code)
+	}
+}
+
+func Test_API_sequencer_validate(t *testing.T) {
+	ctrl, server := newTestAPI(t)
+	defer server.Close()
+	if code, body := postAPI(t, server, "/api/sequencer/validate", testSequencerDef); code != http.StatusOK {
+		t.Errorf("Expecting the definition to be valid, got %d %s", code, body)
+	}
+	for _, def := range []string{
+		"",
+		"bpm: [",
+		"bpm: 120\ngranularity: 16\n",
+		testSequencerDef + "- unknown_sequence: {}\n",
+	} {
+		code, body := postAPI(t, server, "/api/sequencer/validate", def)
+		if code != http.StatusBadRequest || !bytes.Contains(body, []byte("error")) {
+			t.Errorf("Expecting an error for %q, got %d %s", def, code, body)
+		}
+	}
+	if ctrl.Sequencer != nil {
+		t.Errorf("Expecting validation not to load the sequencer")
+	}
+	if code, _ := postAPI(t, server, "/api/transport/play", ""); code != http.StatusConflict {
+		t.Errorf("Expecting transport to fail without a sequencer, got %d", code)
+	}
+}
+
+func Test_API_transport_and_channels(t *testing.T) {
+	ctrl, server := newTestAPI(t)
+	defer server.Close()
+	ctrl.Sequencer = sequencer.NewSequencer(120, 64)
+
+	if code, _ := postAPI(t, server, "/api/transport/seek?beat=2", ""); code != http.StatusOK {
+		t.Errorf("Expecting seek to succeed, got %d", code)
+	}
+	if ev := <-ctrl.Sequencer.Inputs; ev.Type != sequencer.GoToTime || ev.Value.(uint) != 128 {
+		t.Errorf("Expecting a goto event, got %v", ev)
+	}
+	if code, _ := postAPI(t, server, "/api/transport/seek?beat=soon", ""); code != http.StatusBadRequest {
+		t.Errorf("Expecting an invalid seek to fail, got %d", code)
+	}
+	if code, _ := postAPI(t, server, "/api/transport/jump", ""); code != http.StatusNotFound {
+		t.Errorf("Expecting an unknown command to fail, got %d", code)
+	}
+
+	if code, body := postAPI(t, server, "/api/channels/3", `{"lpf": 1200}`); code != http.StatusOK {
+		t.Errorf("Expecting the parameter to be set, got %d %s", code, body)
+	}
+	if ev := <-ctrl.Synth.Inputs; ev.Type != synth.SetLPFCutoff || ev.Channel != 3 || ev.Values[0] != 1200 {
+		t.Errorf("Expecting a low pass filter event, got %v", ev)
+	}
+	if code, _ := postAPI(t, server, "/api/channels/3", `{"lpf": 1200, "lfp": 1}`); code != http.StatusBadRequest {
+		t.Errorf("Expecting unknown parameters to fail, got %d", code)
+	}
+	if len(ctrl.Synth.Inputs) != 0 {
+		t.Errorf("Expecting nothing to be set when a parameter is invalid")
+	}
+}
+
+func Test_API_rejects_cross_origin_requests(t *testing.T) {
+	ctrl, server := newTestAPI(t)
+	defer server.Close()
+	ctrl.Sequencer = sequencer.NewSequencer(120, 64)
+	post := func(origin, contentType string) int {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/transport/play", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := post("http://example.com", "application/json"); code != http.StatusForbidden {
+		t.Errorf("Expecting other origins to be rejected, got %d", code)
+	}
+	if code := post("", "text/plain"); code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expecting text/plain to be rejected, got %d", code)
+	}
+	if code := post("", ""); code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expecting a missing Content-Type to be rejected, got %d", code)
+	}
+	if len(ctrl.Sequencer.Inputs) != 0 {
+		t.Errorf("Expecting rejected requests not to reach the sequencer")
+	}
+	if code := post(server.URL, "application/json; charset=utf-8"); code != http.StatusOK {
+		t.Errorf("Expecting same-origin requests to be accepted, got %d", code)
+	}
+}
+
+func Test_API_render(t *testing.T) {
+	_, server := newTestAPI(t)
+	defer server.Close()
+	code, body := postAPI(t, server, "/api/render?beats=2", testSequencerDef)
+	if code != http.StatusOK {
+		t.Fatalf("Expecting the definition to render, got %d %s", code, body)
+	}
+	if !bytes.HasPrefix(body, []byte("RIFF")) || !bytes.Contains(body[:16], []byte("WAVE")) {
+		t.Fatalf("Expecting a wav file")
+	}
+	// One second of 16 bit stereo audio at 8000Hz, plus the header
+	if len(body) < 32000 || len(body) > 32100 {
+		t.Errorf("Expecting two beats at 120bpm, got %d bytes", len(body))
+	}
+	silent := true
+	for _, b := range body[44:] {
+		silent = silent && b == 0
+	}
+	if silent {
+		t.Errorf("Expecting the notes to be audible")
+	}
+	if code, _ := postAPI(t, server, "/api/render?beats=-1", testSequencerDef); code != http.StatusBadRequest {
+		t.Errorf("Expecting an invalid number of beats to fail, got %d", code)
+	}
+}
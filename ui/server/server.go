@@ -6,6 +6,8 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/bspaans/bleep/controller"
 	"github.com/bspaans/bleep/ui"
@@ -14,11 +16,30 @@ import (
 
 type Server struct {
 	Controller *controller.Controller
-	Clients    []*websocket.Conn
+	Clients    []*Client
+	// How often the status is pushed to the clients.
+	StatusInterval time.Duration
+
+	mux sync.Mutex
+}
+
+// Client is a websocket connection. The connection doesn't support
+// concurrent writes, so replies and pushed messages are serialized.
+type Client struct {
+	Conn *websocket.Conn
+	mux  sync.Mutex
+}
+
+func (c *Client) Send(msg *ResponseMessage) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.Conn.WriteJSON(msg)
 }
 
 func NewServer() *Server {
-	return &Server{}
+	return &Server{
+		StatusInterval: 500 * time.Millisecond,
+	}
 }
 
 func (s *Server) Start(ctrl *controller.Controller) *Server {
@@ -26,62 +47,94 @@ func (s *Server) Start(ctrl *controller.Controller) *Server {
 	fmt.Println("Starting web server on", addr)
 	s.Controller = ctrl
 	http.HandleFunc("/ws", s.Websocket)
+	s.RegisterAPI(http.DefaultServeMux)
 	http.HandleFunc("/bleep.js", s.serveFile("ui/web/bleep.js", "text/javascript"))
 	http.HandleFunc("/", s.serveFile("ui/web/index.html", "text/html"))
 	go http.ListenAndServe(addr, nil)
+	go s.pushStatus()
 	return s
 }
 
 func (s *Server) Websocket(w http.ResponseWriter, r *http.Request) {
-	var upgrader = websocket.Upgrader{} // use default options
-	upgrader.CheckOrigin = func(r *http.Request) bool {
-		fmt.Println(r)
-		return true
+	var upgrader = websocket.Upgrader{
+		CheckOrigin: sameOrigin,
 	}
 	c, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Print("upgrade:", err)
 		return
 	}
-	s.Clients = append(s.Clients, c)
+	client := &Client{Conn: c}
+	s.mux.Lock()
+	s.Clients = append(s.Clients, client)
+	s.mux.Unlock()
 	defer func() {
-		// Remove from clients
-		ix := -1
-		for i, client := range s.Clients {
-			if client == c {
-				ix = i
+		s.mux.Lock()
+		for i, other := range s.Clients {
+			if other == client {
+				s.Clients = append(s.Clients[:i], s.Clients[i+1:]...)
+				break
 			}
 		}
-		s.Clients[ix] = s.Clients[len(s.Clients)-1]
-		s.Clients = s.Clients[:len(s.Clients)-1]
+		s.mux.Unlock()
 		c.Close()
 	}()
 	for {
 		_, message, err := c.ReadMessage()
-		if err == nil {
-			var msg Message
-			if err := json.Unmarshal(message, &msg); err == nil {
-				msg.Handle(s.Controller, c)
-			} else {
-				log.Println("unmarshal error", err)
+		if err != nil {
+			log.Println("read error:", err)
+			break
+		}
+		var msg Message
+		var reply *ResponseMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			reply = &ResponseMessage{
+				Version: ProtocolVersion,
+				Error:   "invalid message: " + err.Error(),
 			}
 		} else {
-			log.Println("read error:", err)
+			reply = msg.Reply(s.Controller)
+		}
+		if err := client.Send(reply); err != nil {
+			log.Println("write error:", err)
 			break
 		}
 	}
 }
 
-func (s *Server) Broadcast(msg *Message) {
-	for _, client := range s.Clients {
-		msg.Send(client)
+// Broadcast pushes a message to all the clients.
+func (s *Server) Broadcast(ty MessageType, data interface{}) {
+	msg := &ResponseMessage{
+		Version: ProtocolVersion,
+		Type:    ty,
+		Data:    data,
+	}
+	s.mux.Lock()
+	clients := append([]*Client{}, s.Clients...)
+	s.mux.Unlock()
+	for _, client := range clients {
+		client.Send(msg)
+	}
+}
+
+func (s *Server) pushStatus() {
+	for range time.Tick(s.StatusInterval) {
+		s.mux.Lock()
+		hasClients := len(s.Clients) > 0
+		s.mux.Unlock()
+		if hasClients {
+			s.Broadcast(Status, NewStatusResponse(s.Controller))
+		}
 	}
 }
 
 func (s *Server) HandleEvent(ev *ui.UIEvent) {
 	if ev.Type == ui.ForceReloadEvent {
-		msg := NewMessage(ForceReload, "")
-		s.Broadcast(msg)
+		s.Broadcast(ForceReload, "")
+	} else if ev.Type == ui.MIDILearnEvent {
+		s.Broadcast(MIDILearned, ev.Value)
+	} else if ev.Type == ui.ChannelsOutputEvent {
+		s.Broadcast(Meters, ev.Meters)
 	}
 }
 
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bspaans/bleep/audio"
+	"github.com/bspaans/bleep/controller"
+	"github.com/bspaans/bleep/sequencer"
+	"github.com/bspaans/bleep/synth"
+	"github.com/bspaans/bleep/ui"
+	"github.com/gorilla/websocket"
+)
+
+func newTestClient(t *testing.T, ctrl *controller.Controller) (*Server, *websocket.Conn, func()) {
+	s := NewServer()
+	s.Controller = ctrl
+	httpServer := httptest.NewServer(http.HandlerFunc(s.Websocket))
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, conn, func() {
+		conn.Close()
+		httpServer.Close()
+	}
+}
+
+func request(t *testing.T, conn *websocket.Conn, msg string) map[string]interface{} {
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	reply := map[string]interface{}{}
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func receiveSynthEvent(t *testing.T, ctrl *controller.Controller) *synth.Event {
+	select {
+	case ev := <-ctrl.Synth.Inputs:
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for synth event")
+	}
+	return nil
+}
+
+func Test_Websocket_rejects_other_origins(t *testing.T) {
+	s := NewServer()
+	s.Controller = controller.NewController(audio.NewAudioConfig())
+	httpServer := httptest.NewServer(http.HandlerFunc(s.Websocket))
+	defer httpServer.Close()
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http")
+	header := http.Header{"Origin": []string{"http://example.com"}}
+	if conn, _, err := websocket.DefaultDialer.Dial(url, header); err == nil {
+		conn.Close()
+		t.Errorf("Expecting a connection from another origin to be rejected")
+	}
+	header = http.Header{"Origin": []string{httpServer.URL}}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func Test_Websocket_requests(t *testing.T) {
+	ctrl := controller.NewController(audio.NewAudioConfig())
+	_, conn, closer := newTestClient(t, ctrl)
+	defer closer()
+
+	reply := request(t, conn, `{"version": 1, "id": 1, "type": "hello"}`)
+	if reply["id"] != 1.0 || reply["type"] != "hello" || reply["error"] != nil {
+		t.Errorf("Unexpected hello reply %v", reply)
+	}
+	if data := reply["data"].(map[string]interface{}); data["version"] != float64(ProtocolVersion) {
+		t.Errorf("Expecting the protocol version, got %v", data)
+	}
+
+	reply = request(t, conn, `{"id": "a", "type": "set_channel_param", "data": {"channel": 3, "param": "lpf", "value": 1200}}`)
+	if reply["id"] != "a" || reply["error"] != nil {
+		t.Errorf("Unexpected reply %v", reply)
+	}
+	if ev := receiveSynthEvent(t, ctrl); ev.Type != synth.SetLPFCutoff || ev.Channel != 3 || ev.Values[0] != 1200 {
+		t.Errorf("Expecting a low pass filter event, got %v", ev)
+	}
+
+	request(t, conn, `{"id": 2, "type": "note_on", "data": {"channel": 1, "note": 60}}`)
+	if ev := receiveSynthEvent(t, ctrl); ev.Type != synth.NoteOn || ev.Values[0] != 60 || ev.Values[1] != 100 {
+		t.Errorf("Expecting a note on with the default velocity, got %v", ev)
+	}
+
+	request(t, conn, `{"id": 3, "type": "toggle_mute", "data": {"channel": 5}}`)
+	if ev := receiveSynthEvent(t, ctrl); ev.Type != synth.ToggleMuteChannel || ev.Channel != 5 {
+		t.Errorf("Expecting a toggle mute event, got %v", ev)
+	}
+
+	// Status works without a sequencer
+	reply = request(t, conn, `{"id": 4, "type": "status"}`)
+	data := reply["data"].(map[string]interface{})
+	if data["sequencer"] != false || data["master_gain"] != 1.0 || len(data["mute"].([]interface{})) != 16 {
+		t.Errorf("Unexpected status %v", reply)
+	}
+}
+
+func Test_Websocket_errors(t *testing.T) {
+	ctrl := controller.NewController(audio.NewAudioConfig())
+	_, conn, closer := newTestClient(t, ctrl)
+	defer closer()
+
+	for _, msg := range []string{
+		`{"id": 1, "type": "unknown"}`,
+		`{"id": 1, "type": "set_master_gain", "data": "loud"}`,
+		`{"id": 1, "type": "set_master_gain"}`,
+		`{"id": 1, "type": "set_channel_param", "data": {"channel": 1, "param": "lfp", "value": 1}}`,
+		`{"id": 1, "type": "note_on", "data": {"channel": 16, "note": 60}}`,
+		`{"id": 1, "type": "play"}`,
+		`{"id": 1, "version": 2, "type": "hello"}`,
+	} {
+		reply := request(t, conn, msg)
+		if reply["id"] != 1.0 || reply["error"] == nil || reply["error"] == "" {
+			t.Errorf("Expecting an error reply for %s, got %v", msg, reply)
+		}
+	}
+	reply := request(t, conn, `not json`)
+	if reply["error"] == nil {
+		t.Errorf("Expecting an error reply for invalid JSON, got %v", reply)
+	}
+}
+
+func Test_Websocket_sequencer(t *testing.T) {
+	ctrl := controller.NewController(audio.NewAudioConfig())
+	ctrl.Sequencer = sequencer.NewSequencer(120, 64)
+	_, conn, closer := newTestClient(t, ctrl)
+	defer closer()
+
+	reply := request(t, conn, `{"id": 1, "type": "set_register", "data": {"register": 1, "value": 74}}`)
+	if reply["error"] != nil {
+		t.Errorf("Unexpected error %v", reply)
+	}
+	ev := <-ctrl.Sequencer.Inputs
+	if ev.Type != sequencer.SetRegister {
+		t.Errorf("Expecting a register event, got %v", ev)
+	}
+	request(t, conn, `{"id": 2, "type": "goto", "data": 2}`)
+	ev = <-ctrl.Sequencer.Inputs
+	if ev.Type != sequencer.GoToTime || ev.Value.(uint) != 128 {
+		t.Errorf("Expecting a goto event, got %v", ev)
+	}
+}
+
+func Test_Websocket_pushed_messages(t *testing.T) {
+	ctrl := controller.NewController(audio.NewAudioConfig())
+	s, conn, closer := newTestClient(t, ctrl)
+	defer closer()
+	// Make sure the client is registered before broadcasting.
+	request(t, conn, `{"id": 1, "type": "test"}`)
+
+	ev := ui.NewUIEvent(ui.ChannelsOutputEvent)
+	ev.Meters = &ui.Meters{
+		Channels: []ui.Levels{{Peak: 0.5, RMS: 0.25}, {}},
+		Master:   ui.Levels{Peak: 1.5, RMS: 0.5, Clipped: true},
+	}
+	s.HandleEvent(ev)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	msg := map[string]interface{}{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg["type"] != "meters" || msg["id"] != nil {
+		t.Fatalf("Expecting a meters message, got %v", msg)
+	}
+	data := msg["data"].(map[string]interface{})
+	master := data["master"].(map[string]interface{})
+	if len(data["channels"].([]interface{})) != 2 || master["clipped"] != true || master["peak"] != 1.5 {
+		t.Errorf("Expecting the channel and master levels, got %v", data)
+	}
+}